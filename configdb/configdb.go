@@ -0,0 +1,31 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package configdb holds the CONFIG_DB client shared by the server packages
+package configdb
+
+import "github.com/go-redis/redis/v7"
+
+// Client is the single CONFIG_DB client, go-redis pools its connections
+var Client = redis.NewClient(&redis.Options{
+	Network:  "unix",
+	Addr:     "/var/run/redis/redis.sock",
+	Password: "",
+	DB:       4,
+})
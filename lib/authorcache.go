@@ -0,0 +1,121 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"sync"
+	"time"
+)
+
+// Lifetime of the cached authorization decisions of a session, 0 disables the cache
+var AuthorizationCacheTTL = 30 * time.Second
+
+// Lifetime of the cached denials, 0 disables negative caching
+var AuthorizationNegativeCacheTTL = 10 * time.Second
+
+// Decisions kept per session, expired entries are swept when it is reached
+// and the entry closest to expiry is then dropped
+const authorCacheMaxEntries = 1024
+
+type authorCacheEntry struct {
+	allowed bool
+	expires time.Time
+}
+
+// Authorization decisions of a session, by operation and exact path. A path
+// prefix cannot be used as key: a server may permit a container and deny one
+// of its list entries, or the reverse, so a decision only holds for the
+// arguments it was given for
+type authorCache struct {
+	mutex   sync.Mutex
+	entries map[string]authorCacheEntry
+}
+
+func (c *authorCache) get(key string) (bool, bool) {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return false, false
+	}
+
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return false, false
+	}
+
+	return entry.allowed, true
+}
+
+func (c *authorCache) put(key string, allowed bool) {
+
+	ttl := AuthorizationCacheTTL
+	if !allowed {
+		ttl = AuthorizationNegativeCacheTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]authorCacheEntry{}
+	}
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= authorCacheMaxEntries {
+		c.sweep()
+	}
+
+	c.entries[key] = authorCacheEntry{allowed: allowed, expires: time.Now().Add(ttl)}
+}
+
+// sweep drops the expired entries, or the one expiring first when none has
+func (c *authorCache) sweep() {
+
+	now := time.Now()
+	oldest := ""
+
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+			oldest = key
+		}
+	}
+
+	if len(c.entries) >= authorCacheMaxEntries {
+		delete(c.entries, oldest)
+	}
+}
+
+func (c *authorCache) clear() {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries = nil
+}
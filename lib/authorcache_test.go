@@ -0,0 +1,164 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init authorcache_test +++++")
+}
+
+// setCacheTTL replaces the cache lifetimes, restored by the returned function
+func setCacheTTL(ttl time.Duration, negativeTTL time.Duration) func() {
+
+	savedTTL, savedNegativeTTL := AuthorizationCacheTTL, AuthorizationNegativeCacheTTL
+	AuthorizationCacheTTL, AuthorizationNegativeCacheTTL = ttl, negativeTTL
+
+	return func() { AuthorizationCacheTTL, AuthorizationNegativeCacheTTL = savedTTL, savedNegativeTTL }
+}
+
+func TestAuthorCacheTTL(t *testing.T) {
+
+	restore := setCacheTTL(200*time.Millisecond, 50*time.Millisecond)
+	defer restore()
+
+	cache := authorCache{}
+
+	if _, ok := cache.get("get\n/a"); ok {
+		t.Error("Empty cache returned a decision")
+	}
+
+	cache.put("get\n/a", true)
+	cache.put("edit-data\n/a", false)
+
+	if allowed, ok := cache.get("get\n/a"); !ok || !allowed {
+		t.Errorf("Permit: got %v %v", allowed, ok)
+	}
+	if allowed, ok := cache.get("edit-data\n/a"); !ok || allowed {
+		t.Errorf("Denial: got %v %v", allowed, ok)
+	}
+
+	// Keys are exact, a prefix gets no decision
+	if _, ok := cache.get("get\n/a/b"); ok {
+		t.Error("Decision of a prefix reused")
+	}
+
+	// Denials expire first
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok := cache.get("edit-data\n/a"); ok {
+		t.Error("Denial not expired")
+	}
+	if _, ok := cache.get("get\n/a"); !ok {
+		t.Error("Permit expired with the denial")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if _, ok := cache.get("get\n/a"); ok {
+		t.Error("Permit not expired")
+	}
+}
+
+func TestAuthorCacheDisabled(t *testing.T) {
+
+	restore := setCacheTTL(time.Minute, 0)
+	cache := authorCache{}
+	cache.put("edit-data\n/a", false)
+	cache.put("get\n/a", true)
+	restore()
+
+	if _, ok := cache.get("edit-data\n/a"); ok {
+		t.Error("Denial cached without negative TTL")
+	}
+	if _, ok := cache.get("get\n/a"); !ok {
+		t.Error("Permit not cached")
+	}
+
+	restore = setCacheTTL(0, time.Minute)
+	defer restore()
+
+	cache = authorCache{}
+	cache.put("get\n/a", true)
+
+	if _, ok := cache.get("get\n/a"); ok {
+		t.Error("Permit cached without TTL")
+	}
+}
+
+func TestAuthorCacheClear(t *testing.T) {
+
+	restore := setCacheTTL(time.Minute, time.Minute)
+	defer restore()
+
+	cache := authorCache{}
+	cache.put("get\n/a", true)
+	cache.put("edit-data\n/a", false)
+	cache.clear()
+
+	if _, ok := cache.get("get\n/a"); ok {
+		t.Error("Permit kept after clear")
+	}
+	if _, ok := cache.get("edit-data\n/a"); ok {
+		t.Error("Denial kept after clear")
+	}
+
+	// The cache is usable again
+	cache.put("get\n/a", true)
+	if _, ok := cache.get("get\n/a"); !ok {
+		t.Error("Decision not cached after clear")
+	}
+}
+
+func TestAuthorCacheBound(t *testing.T) {
+
+	restore := setCacheTTL(time.Minute, time.Second)
+	defer restore()
+
+	cache := authorCache{}
+
+	// The denial expires first, it is dropped to make room
+	cache.put("denied", false)
+	for i := 1; i < authorCacheMaxEntries; i++ {
+		cache.put(strconv.Itoa(i), true)
+	}
+	cache.put("new", true)
+
+	if len(cache.entries) != authorCacheMaxEntries {
+		t.Errorf("Expected %d entries, got %d", authorCacheMaxEntries, len(cache.entries))
+	}
+	if _, ok := cache.get("denied"); ok {
+		t.Error("Entry expiring first not dropped")
+	}
+	if _, ok := cache.get("new"); !ok {
+		t.Error("New entry not cached")
+	}
+
+	// Updating a cached decision drops nothing
+	cache.put("new", false)
+	if len(cache.entries) != authorCacheMaxEntries {
+		t.Errorf("Update changed the entry count to %d", len(cache.entries))
+	}
+}
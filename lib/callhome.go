@@ -0,0 +1,257 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// CONFIG_DB tables of the call home clients (RFC 8071), mirroring the
+// call-home container of ietf-netconf-server
+const (
+	CallHomeTable         = "NETCONF_CALL_HOME"          // NETCONF_CALL_HOME|<client>
+	CallHomeEndpointTable = "NETCONF_CALL_HOME_ENDPOINT" // NETCONF_CALL_HOME_ENDPOINT|<client>|<endpoint>
+)
+
+// Transports and connection types of a call home client
+const (
+	CallHomeSSH        = "ssh"
+	CallHomeTLS        = "tls"
+	CallHomePersistent = "persistent"
+	CallHomePeriodic   = "periodic"
+)
+
+// Reconnect strategy start-with values
+const (
+	StartWithFirstListed   = "first-listed"
+	StartWithLastConnected = "last-connected"
+	StartWithRandom        = "random-selection"
+)
+
+// IANA assigned call home ports
+const (
+	CallHomeSSHPort = 4334
+	CallHomeTLSPort = 4335
+)
+
+// Reconnection of persistent clients: the backoff starts at CallHomeMinBackoff
+// and is reset by a session that stayed up for CallHomeStableSession, shorter
+// sessions such as a rejected host key count as failed attempts
+const (
+	CallHomeMinBackoff    = time.Second
+	CallHomeStableSession = 30 * time.Second
+)
+
+type CallHomeEndpoint struct {
+	Name     string
+	Address  string
+	Port     int
+	Priority int // Lower values are tried first
+}
+
+type CallHomeClient struct {
+	Name           string
+	Transport      string
+	ConnectionType string
+	Period         int // Minutes between two periodic connections
+	IdleTimeout    int // Seconds without traffic before a periodic connection is dropped, 0 disables
+	StartWith      string
+	MaxAttempts    int // Attempts per endpoint before moving to the next one
+	MaxWait        int // Seconds, cap of the backoff between two rounds of endpoints
+	Endpoints      []CallHomeEndpoint
+}
+
+// GetCallHomeClients reads the call home clients and their endpoints, the
+// endpoints are sorted by priority then name
+func GetCallHomeClients() ([]CallHomeClient, error) {
+
+	clientEntries, err := getTableEntries(CallHomeTable)
+	if err != nil {
+		return nil, err
+	}
+
+	endpointEntries, err := getTableEntries(CallHomeEndpointTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return callHomeClients(clientEntries, endpointEntries), nil
+}
+
+// callHomeClients builds the clients from the entries of the call home tables
+func callHomeClients(clientEntries map[string]map[string]string, endpointEntries map[string]map[string]string) []CallHomeClient {
+
+	clients := []CallHomeClient{}
+
+	for name, data := range clientEntries {
+
+		client := CallHomeClient{
+			Name:           name,
+			Transport:      CallHomeSSH,
+			ConnectionType: CallHomePersistent,
+			Period:         60,
+			StartWith:      StartWithFirstListed,
+			MaxAttempts:    3,
+			MaxWait:        300,
+		}
+
+		if transport, ok := data["transport"]; ok {
+			client.Transport = transport
+		}
+
+		if connectionType, ok := data["connection_type"]; ok {
+			client.ConnectionType = connectionType
+		}
+
+		if startWith, ok := data["start_with"]; ok {
+			client.StartWith = startWith
+		}
+
+		readInt(data, "period", 1, &client.Period)
+		readInt(data, "idle_timeout", 0, &client.IdleTimeout)
+		readInt(data, "max_attempts", 1, &client.MaxAttempts)
+		readInt(data, "max_wait", 1, &client.MaxWait)
+
+		if client.Transport != CallHomeSSH && client.Transport != CallHomeTLS {
+			glog.Warningf("[CALLHOME] Client %s skipped, unsupported transport %s", name, client.Transport)
+			continue
+		}
+
+		if client.ConnectionType != CallHomePersistent && client.ConnectionType != CallHomePeriodic {
+			glog.Warningf("[CALLHOME] Client %s skipped, unsupported connection type %s", name, client.ConnectionType)
+			continue
+		}
+
+		defaultPort := CallHomeSSHPort
+		if client.Transport == CallHomeTLS {
+			defaultPort = CallHomeTLSPort
+		}
+
+		for key, endpointData := range endpointEntries {
+
+			keys := strings.SplitN(key, "|", 2)
+			if len(keys) != 2 || keys[0] != name {
+				continue
+			}
+
+			endpoint := CallHomeEndpoint{
+				Name:    keys[1],
+				Address: endpointData["address"],
+				Port:    defaultPort,
+			}
+
+			readInt(endpointData, "port", 1, &endpoint.Port)
+			readInt(endpointData, "priority", 0, &endpoint.Priority)
+
+			if endpoint.Address == "" {
+				glog.Warningf("[CALLHOME] Endpoint %s of client %s has no address", endpoint.Name, name)
+				continue
+			}
+
+			client.Endpoints = append(client.Endpoints, endpoint)
+		}
+
+		sort.Slice(client.Endpoints, func(i, j int) bool {
+			if client.Endpoints[i].Priority != client.Endpoints[j].Priority {
+				return client.Endpoints[i].Priority < client.Endpoints[j].Priority
+			}
+			return client.Endpoints[i].Name < client.Endpoints[j].Name
+		})
+
+		if len(client.Endpoints) == 0 {
+			glog.Warningf("[CALLHOME] Client %s skipped, no endpoint", name)
+			continue
+		}
+
+		clients = append(clients, client)
+	}
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].Name < clients[j].Name })
+
+	return clients
+}
+
+// readInt reads an integer field of at least min, invalid values are ignored
+func readInt(data map[string]string, field string, min int, value *int) {
+
+	text, ok := data[field]
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(text)
+	if err != nil || number < min {
+		glog.Warningf("[CALLHOME] Invalid %s value %s ignored", field, text)
+		return
+	}
+
+	*value = number
+}
+
+// EndpointOrder lists the endpoint indexes following the start-with strategy,
+// last is the index of the endpoint connected last
+func (c CallHomeClient) EndpointOrder(last int) []int {
+
+	count := len(c.Endpoints)
+
+	switch c.StartWith {
+	case StartWithRandom:
+		return rand.Perm(count)
+	case StartWithLastConnected:
+		order := make([]int, 0, count)
+		for i := 0; i < count; i++ {
+			order = append(order, (last+i)%count)
+		}
+		return order
+	default:
+		order := make([]int, 0, count)
+		for i := 0; i < count; i++ {
+			order = append(order, i)
+		}
+		return order
+	}
+}
+
+// ReconnectWait returns the wait before the next round of endpoints and the
+// backoff of the round after it. A periodic client waits for the rest of its
+// period, a persistent one reconnects at once only after a stable session
+func (c CallHomeClient) ReconnectWait(round time.Duration, session time.Duration, connected bool, backoff time.Duration) (time.Duration, time.Duration) {
+
+	if c.ConnectionType == CallHomePeriodic {
+		return time.Duration(c.Period)*time.Minute - round, backoff
+	}
+
+	if connected && session >= CallHomeStableSession {
+		return 0, CallHomeMinBackoff
+	}
+
+	next := backoff * 2
+	if maxWait := time.Duration(c.MaxWait) * time.Second; next > maxWait {
+		next = maxWait
+	}
+
+	return backoff, next
+}
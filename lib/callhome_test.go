@@ -0,0 +1,153 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init callhome_test +++++")
+}
+
+func TestCallHomeClients(t *testing.T) {
+
+	clientEntries := map[string]map[string]string{
+		"defaults": {},
+		"tls": {
+			"transport": "tls", "connection_type": "periodic", "period": "5", "idle_timeout": "0",
+			"start_with": "last-connected", "max_attempts": "2", "max_wait": "30",
+		},
+		"invalid-values": {"period": "0", "max_attempts": "0", "max_wait": "0", "idle_timeout": "-1"},
+		"bad-transport":  {"transport": "quic"},
+		"bad-type":       {"connection_type": "sometimes"},
+		"no-endpoint":    {},
+	}
+
+	endpointEntries := map[string]map[string]string{
+		"defaults|b":       {"address": "192.0.2.2", "priority": "5"},
+		"defaults|a":       {"address": "192.0.2.1", "priority": "5"},
+		"defaults|first":   {"address": "192.0.2.3", "priority": "0", "port": "830"},
+		"defaults|noaddr":  {"port": "830"},
+		"tls|collector":    {"address": "collector.example", "priority": "1", "port": "0"},
+		"invalid-values|a": {"address": "192.0.2.4"},
+		"bad-transport|a":  {"address": "192.0.2.5"},
+		"bad-type|a":       {"address": "192.0.2.6"},
+		"other|a":          {"address": "192.0.2.7"},
+	}
+
+	defaults := CallHomeClient{
+		Transport: CallHomeSSH, ConnectionType: CallHomePersistent, Period: 60,
+		StartWith: StartWithFirstListed, MaxAttempts: 3, MaxWait: 300,
+	}
+
+	expected := []CallHomeClient{defaults, defaults, defaults}
+
+	expected[0].Name = "defaults"
+	expected[0].Endpoints = []CallHomeEndpoint{
+		{"first", "192.0.2.3", 830, 0},
+		{"a", "192.0.2.1", CallHomeSSHPort, 5},
+		{"b", "192.0.2.2", CallHomeSSHPort, 5},
+	}
+
+	// Values below their minimum keep the defaults
+	expected[1].Name = "invalid-values"
+	expected[1].Endpoints = []CallHomeEndpoint{{"a", "192.0.2.4", CallHomeSSHPort, 0}}
+
+	expected[2] = CallHomeClient{
+		Name: "tls", Transport: CallHomeTLS, ConnectionType: CallHomePeriodic, Period: 5,
+		StartWith: StartWithLastConnected, MaxAttempts: 2, MaxWait: 30,
+		Endpoints: []CallHomeEndpoint{{"collector", "collector.example", CallHomeTLSPort, 1}},
+	}
+
+	clients := callHomeClients(clientEntries, endpointEntries)
+	if !reflect.DeepEqual(clients, expected) {
+		t.Errorf("got %+v\nwant: %+v", clients, expected)
+	}
+}
+
+func TestEndpointOrder(t *testing.T) {
+
+	client := CallHomeClient{Endpoints: make([]CallHomeEndpoint, 4)}
+
+	tests := []struct {
+		startWith string
+		last      int
+		order     []int
+	}{
+		{StartWithFirstListed, 2, []int{0, 1, 2, 3}},
+		{StartWithLastConnected, 0, []int{0, 1, 2, 3}},
+		{StartWithLastConnected, 2, []int{2, 3, 0, 1}},
+		{StartWithLastConnected, 3, []int{3, 0, 1, 2}},
+	}
+
+	for _, test := range tests {
+		client.StartWith = test.startWith
+		if order := client.EndpointOrder(test.last); !reflect.DeepEqual(order, test.order) {
+			t.Errorf("%s from %d: got %v, want: %v", test.startWith, test.last, order, test.order)
+		}
+	}
+
+	// A random order still tries every endpoint once
+	client.StartWith = StartWithRandom
+	order := client.EndpointOrder(1)
+	sort.Ints(order)
+	if !reflect.DeepEqual(order, []int{0, 1, 2, 3}) {
+		t.Errorf("random-selection: got %v", order)
+	}
+}
+
+func TestReconnectWait(t *testing.T) {
+
+	persistent := CallHomeClient{ConnectionType: CallHomePersistent, MaxWait: 5}
+	periodic := CallHomeClient{ConnectionType: CallHomePeriodic, Period: 2}
+
+	tests := []struct {
+		name      string
+		client    CallHomeClient
+		round     time.Duration
+		session   time.Duration
+		connected bool
+		backoff   time.Duration
+		wait      time.Duration
+		next      time.Duration
+	}{
+		{"stable session reconnects at once", persistent, time.Hour, time.Hour, true, 4 * time.Second, 0, CallHomeMinBackoff},
+		{"session just stable", persistent, time.Minute, CallHomeStableSession, true, 4 * time.Second, 0, CallHomeMinBackoff},
+		{"short session backs off", persistent, time.Second, time.Second, true, 2 * time.Second, 2 * time.Second, 4 * time.Second},
+		{"immediate close backs off", persistent, 0, 0, true, CallHomeMinBackoff, CallHomeMinBackoff, 2 * time.Second},
+		{"no endpoint backs off", persistent, time.Second, 0, false, 2 * time.Second, 2 * time.Second, 4 * time.Second},
+		{"backoff capped by max-wait", persistent, time.Second, 0, false, 4 * time.Second, 4 * time.Second, 5 * time.Second},
+		{"capped backoff stays", persistent, time.Second, 0, false, 5 * time.Second, 5 * time.Second, 5 * time.Second},
+		{"periodic waits the rest of the period", periodic, 30 * time.Second, 20 * time.Second, true, CallHomeMinBackoff, 90 * time.Second, CallHomeMinBackoff},
+		{"periodic round longer than the period", periodic, 3 * time.Minute, 0, false, CallHomeMinBackoff, -time.Minute, CallHomeMinBackoff},
+	}
+
+	for _, test := range tests {
+		wait, next := test.client.ReconnectWait(test.round, test.session, test.connected, test.backoff)
+		if wait != test.wait || next != test.next {
+			t.Errorf("%s: got wait %v next %v, want: %v %v", test.name, wait, next, test.wait, test.next)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+// CA keys allowed to sign user certificates, in authorized_keys format
+var TrustedUserCAKeysPath = "/etc/sonic/netconf/trusted_user_ca_keys"
+
+// Principal to username mapping, one "principal username" pair per line.
+// A principal equal to the username is always accepted
+var AuthorizedPrincipalsPath = "/etc/sonic/netconf/authorized_principals"
+
+// Revoked keys, certificates and CA keys. Lines hold a public key in
+// authorized_keys format, "serial: <number>" or "id: <key id>"
+var RevokedKeysPath = "/etc/sonic/netconf/revoked_keys"
+
+const (
+	certOptionSourceAddress = "source-address"
+	certOptionForceCommand  = "force-command"
+)
+
+// Only the netconf subsystem can be run, a forced command must name it
+const netconfSubsystem = "netconf"
+
+// Revocation list, reloaded on every login so that updates apply immediately
+type revokedKeys struct {
+	keys    [][]byte
+	serials map[uint64]bool
+	ids     map[string]bool
+}
+
+func loadRevokedKeys(path string) (*revokedKeys, error) {
+
+	revoked := &revokedKeys{serials: map[uint64]bool{}, ids: map[string]bool{}}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return revoked, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "serial:"):
+			serial, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "serial:")), 0, 64)
+			if err != nil {
+				return nil, errors.New("Invalid revoked serial " + line)
+			}
+			revoked.serials[serial] = true
+
+		case strings.HasPrefix(line, "id:"):
+			revoked.ids[strings.TrimSpace(strings.TrimPrefix(line, "id:"))] = true
+
+		default:
+			key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+			if err != nil {
+				return nil, errors.New("Invalid revoked key " + line)
+			}
+			revoked.keys = append(revoked.keys, key.Marshal())
+		}
+	}
+
+	return revoked, scanner.Err()
+}
+
+func (r *revokedKeys) isKeyRevoked(key ssh.PublicKey) bool {
+
+	marshaled := key.Marshal()
+	for _, revoked := range r.keys {
+		if bytes.Equal(revoked, marshaled) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *revokedKeys) isCertRevoked(cert *ssh.Certificate) bool {
+	return r.serials[cert.Serial] || r.ids[cert.KeyId] ||
+		r.isKeyRevoked(cert) || r.isKeyRevoked(cert.Key) || r.isKeyRevoked(cert.SignatureKey)
+}
+
+// IsRevokedKey checks a raw public key against the revocation list, an
+// unreadable list revokes every key
+func IsRevokedKey(key ssh.PublicKey) bool {
+
+	revoked, err := loadRevokedKeys(RevokedKeysPath)
+	if err != nil {
+		glog.Errorf("[SSH] Unable to read the revoked keys %s: %v", RevokedKeysPath, err)
+		return true
+	}
+
+	return revoked.isKeyRevoked(key)
+}
+
+// IsAuthorizedCertificate validates a user certificate signed by a trusted
+// CA: revocation, principal mapping, validity window and critical options
+func IsAuthorizedCertificate(username string, cert *ssh.Certificate, remoteAddress string) bool {
+
+	fingerprint := ssh.FingerprintSHA256(cert.Key)
+
+	if cert.CertType != ssh.UserCert {
+		glog.Infof("[SSH] Certificate %s of user=%s is not a user certificate", fingerprint, username)
+		return false
+	}
+
+	authorities, err := readAuthorizedKeys(TrustedUserCAKeysPath)
+	if err != nil {
+		glog.Infof("[SSH] No trusted user CA keys: %v", err)
+		return false
+	}
+
+	revoked, err := loadRevokedKeys(RevokedKeysPath)
+	if err != nil {
+		glog.Errorf("[SSH] Unable to read the revoked keys %s: %v", RevokedKeysPath, err)
+		return false
+	}
+
+	principal, ok := certificatePrincipal(username, cert)
+	if !ok {
+		glog.Infof("[SSH] Certificate %s id=%s has no principal mapped to user=%s", fingerprint, cert.KeyId, username)
+		return false
+	}
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			marshaled := auth.Marshal()
+			for _, authority := range authorities {
+				if bytes.Equal(authority, marshaled) {
+					return true
+				}
+			}
+			return false
+		},
+		IsRevoked:                revoked.isCertRevoked,
+		SupportedCriticalOptions: []string{certOptionForceCommand},
+	}
+
+	if !checker.IsUserAuthority(cert.SignatureKey) || revoked.isKeyRevoked(cert.SignatureKey) {
+		glog.Infof("[SSH] Certificate %s id=%s not signed by a trusted CA", fingerprint, cert.KeyId)
+		return false
+	}
+
+	// Signature, revocation, validity window and unknown critical options
+	if err := checker.CheckCert(principal, cert); err != nil {
+		glog.Infof("[SSH] Certificate %s id=%s rejected for user=%s: %v", fingerprint, cert.KeyId, username, err)
+		return false
+	}
+
+	if addresses, ok := cert.CriticalOptions[certOptionSourceAddress]; ok && !MatchAddressList(addresses, remoteAddress) {
+		glog.Infof("[SSH] Certificate %s id=%s not allowed from %s", fingerprint, cert.KeyId, remoteAddress)
+		return false
+	}
+
+	if command, ok := cert.CriticalOptions[certOptionForceCommand]; ok && command != netconfSubsystem {
+		glog.Infof("[SSH] Certificate %s id=%s forces command %q", fingerprint, cert.KeyId, command)
+		return false
+	}
+
+	glog.Infof("[SSH] Certificate %s id=%s serial=%d accepted for user=%s principal=%s", fingerprint, cert.KeyId, cert.Serial, username, principal)
+	return true
+}
+
+// certificatePrincipal returns the certificate principal granting the login as username
+func certificatePrincipal(username string, cert *ssh.Certificate) (string, bool) {
+
+	mapping := map[string]string{}
+
+	if data, err := ioutil.ReadFile(AuthorizedPrincipalsPath); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			mapping[fields[0]] = fields[1]
+		}
+	}
+
+	for _, principal := range cert.ValidPrincipals {
+		if principal == username || mapping[principal] == username {
+			return principal, true
+		}
+	}
+
+	return "", false
+}
+
+// readAuthorizedKeys returns the marshaled keys of an authorized_keys format file
+func readAuthorizedKeys(path string) ([][]byte, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := [][]byte{}
+	for len(data) > 0 {
+		key, _, _, rest, err := ssh.ParseAuthorizedKey(data)
+		if err != nil {
+			break
+		}
+		keys = append(keys, key.Marshal())
+		data = rest
+	}
+
+	return keys, nil
+}
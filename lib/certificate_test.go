@@ -0,0 +1,192 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	fmt.Println("+++++ init certificate_test +++++")
+}
+
+func testSigner(t *testing.T) ssh.Signer {
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+// testCertificate signs a user certificate for alice, valid for an hour,
+// after applying the changes of edit
+func testCertificate(t *testing.T, ca ssh.Signer, key ssh.PublicKey, edit func(*ssh.Certificate)) *ssh.Certificate {
+
+	cert := &ssh.Certificate{
+		Key:             key,
+		CertType:        ssh.UserCert,
+		KeyId:           "alice@example",
+		Serial:          42,
+		ValidPrincipals: []string{"alice"},
+		ValidAfter:      uint64(time.Now().Add(-time.Hour).Unix()),
+		ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()),
+		Permissions:     ssh.Permissions{CriticalOptions: map[string]string{}},
+	}
+
+	if edit != nil {
+		edit(cert)
+	}
+
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+// setCertificateFiles writes the trusted CA, principals and revocation
+// files, the paths are restored by the returned function
+func setCertificateFiles(t *testing.T, trusted []ssh.PublicKey, principals string, revoked string) func() {
+
+	dir, err := ioutil.TempDir("", "certificate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedCA, savedPrincipals, savedRevoked := TrustedUserCAKeysPath, AuthorizedPrincipalsPath, RevokedKeysPath
+
+	TrustedUserCAKeysPath = filepath.Join(dir, "trusted_user_ca_keys")
+	AuthorizedPrincipalsPath = filepath.Join(dir, "authorized_principals")
+	RevokedKeysPath = filepath.Join(dir, "revoked_keys")
+
+	if trusted != nil {
+		data := []byte{}
+		for _, key := range trusted {
+			data = append(data, ssh.MarshalAuthorizedKey(key)...)
+		}
+		ioutil.WriteFile(TrustedUserCAKeysPath, data, 0644)
+	}
+	ioutil.WriteFile(AuthorizedPrincipalsPath, []byte(principals), 0644)
+	ioutil.WriteFile(RevokedKeysPath, []byte(revoked), 0644)
+
+	return func() {
+		TrustedUserCAKeysPath, AuthorizedPrincipalsPath, RevokedKeysPath = savedCA, savedPrincipals, savedRevoked
+		os.RemoveAll(dir)
+	}
+}
+
+func TestIsAuthorizedCertificate(t *testing.T) {
+
+	ca, revokedCA, untrustedCA := testSigner(t), testSigner(t), testSigner(t)
+	user, revokedUser := testSigner(t), testSigner(t)
+
+	revoked := "# revoked\nserial: 7\nid: stolen@example\n" +
+		string(ssh.MarshalAuthorizedKey(revokedUser.PublicKey())) +
+		string(ssh.MarshalAuthorizedKey(revokedCA.PublicKey()))
+
+	restore := setCertificateFiles(t, []ssh.PublicKey{ca.PublicKey(), revokedCA.PublicKey()}, "# principal user\nops-team alice\n", revoked)
+	defer restore()
+
+	tests := []struct {
+		name     string
+		ca       ssh.Signer
+		key      ssh.PublicKey
+		edit     func(*ssh.Certificate)
+		username string
+		address  string
+		pass     bool
+	}{
+		{"valid", ca, user.PublicKey(), nil, "alice", "192.0.2.1", true},
+		{"other user", ca, user.PublicKey(), nil, "bob", "192.0.2.1", false},
+		{"mapped principal", ca, user.PublicKey(), func(c *ssh.Certificate) { c.ValidPrincipals = []string{"ops-team"} }, "alice", "192.0.2.1", true},
+		{"mapped principal of another user", ca, user.PublicKey(), func(c *ssh.Certificate) { c.ValidPrincipals = []string{"ops-team"} }, "bob", "192.0.2.1", false},
+		{"no principal", ca, user.PublicKey(), func(c *ssh.Certificate) { c.ValidPrincipals = nil }, "alice", "192.0.2.1", false},
+		{"expired", ca, user.PublicKey(), func(c *ssh.Certificate) { c.ValidBefore = uint64(time.Now().Add(-time.Minute).Unix()) }, "alice", "192.0.2.1", false},
+		{"not yet valid", ca, user.PublicKey(), func(c *ssh.Certificate) { c.ValidAfter = uint64(time.Now().Add(time.Minute).Unix()) }, "alice", "192.0.2.1", false},
+		{"forever", ca, user.PublicKey(), func(c *ssh.Certificate) { c.ValidAfter, c.ValidBefore = 0, ssh.CertTimeInfinity }, "alice", "192.0.2.1", true},
+		{"host certificate", ca, user.PublicKey(), func(c *ssh.Certificate) { c.CertType = ssh.HostCert }, "alice", "192.0.2.1", false},
+		{"untrusted CA", untrustedCA, user.PublicKey(), nil, "alice", "192.0.2.1", false},
+		{"revoked CA", revokedCA, user.PublicKey(), nil, "alice", "192.0.2.1", false},
+		{"revoked serial", ca, user.PublicKey(), func(c *ssh.Certificate) { c.Serial = 7 }, "alice", "192.0.2.1", false},
+		{"revoked key id", ca, user.PublicKey(), func(c *ssh.Certificate) { c.KeyId = "stolen@example" }, "alice", "192.0.2.1", false},
+		{"revoked key", ca, revokedUser.PublicKey(), nil, "alice", "192.0.2.1", false},
+		{"source address", ca, user.PublicKey(), func(c *ssh.Certificate) { c.CriticalOptions["source-address"] = "10.0.0.1,192.0.2.0/24" }, "alice", "192.0.2.1", true},
+		{"other source address", ca, user.PublicKey(), func(c *ssh.Certificate) { c.CriticalOptions["source-address"] = "10.0.0.0/8" }, "alice", "192.0.2.1", false},
+		{"force netconf", ca, user.PublicKey(), func(c *ssh.Certificate) { c.CriticalOptions["force-command"] = "netconf" }, "alice", "192.0.2.1", true},
+		{"force shell", ca, user.PublicKey(), func(c *ssh.Certificate) { c.CriticalOptions["force-command"] = "/bin/sh" }, "alice", "192.0.2.1", false},
+		{"unknown critical option", ca, user.PublicKey(), func(c *ssh.Certificate) { c.CriticalOptions["verify-required"] = "" }, "alice", "192.0.2.1", false},
+	}
+
+	for _, test := range tests {
+		cert := testCertificate(t, test.ca, test.key, test.edit)
+		if IsAuthorizedCertificate(test.username, cert, test.address) != test.pass {
+			t.Errorf("%s: expected %v", test.name, test.pass)
+		}
+	}
+
+	// Fields changed after signing invalidate the signature
+	cert := testCertificate(t, ca, user.PublicKey(), func(c *ssh.Certificate) { c.ValidPrincipals = []string{"bob"} })
+	cert.ValidPrincipals = []string{"alice"}
+	if IsAuthorizedCertificate("alice", cert, "192.0.2.1") {
+		t.Error("tampered certificate accepted")
+	}
+}
+
+func TestCertificateFiles(t *testing.T) {
+
+	ca, user := testSigner(t), testSigner(t)
+	cert := testCertificate(t, ca, user.PublicKey(), nil)
+
+	// No trusted CA
+	restore := setCertificateFiles(t, nil, "", "")
+	if IsAuthorizedCertificate("alice", cert, "192.0.2.1") {
+		t.Error("certificate accepted without trusted CA keys")
+	}
+	restore()
+
+	// An invalid revocation list rejects everything
+	restore = setCertificateFiles(t, []ssh.PublicKey{ca.PublicKey()}, "", "serial: many\n")
+	if IsAuthorizedCertificate("alice", cert, "192.0.2.1") || !IsRevokedKey(user.PublicKey()) {
+		t.Error("invalid revocation list not failing closed")
+	}
+	restore()
+
+	restore = setCertificateFiles(t, []ssh.PublicKey{ca.PublicKey()}, "", string(ssh.MarshalAuthorizedKey(user.PublicKey())))
+	if !IsRevokedKey(user.PublicKey()) || IsRevokedKey(ca.PublicKey()) {
+		t.Error("raw key revocation not applied")
+	}
+	restore()
+}
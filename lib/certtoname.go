@@ -0,0 +1,214 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// Map types of the ietf-x509-cert-to-name cert-to-name list (RFC 7407)
+const (
+	MapSpecified     = "specified"
+	MapSANRFC822Name = "san-rfc822-name"
+	MapSANDNSName    = "san-dns-name"
+	MapSANIPAddress  = "san-ip-address"
+	MapSANAny        = "san-any"
+	MapCommonName    = "common-name"
+)
+
+// CONFIG_DB table of the cert-to-name entries, keyed by id
+const CertToNameTable = "NETCONF_CERT_TO_NAME"
+
+type CertToName struct {
+	ID          int
+	Fingerprint string // tls-fingerprint, hash algorithm octet then the hash, colon separated hex
+	MapType     string
+	Name        string // Username of the specified map type
+}
+
+// GetCertToName reads the cert-to-name list from CONFIG_DB, ordered by id
+func GetCertToName() ([]CertToName, error) {
+
+	entries, err := getTableEntries(CertToNameTable)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []CertToName{}
+
+	for _, id := range sortedNumericKeys(entries) {
+
+		data := entries[strconv.Itoa(id)]
+
+		list = append(list, CertToName{
+			ID:          id,
+			Fingerprint: data["fingerprint"],
+			MapType:     data["map_type"],
+			Name:        data["name"],
+		})
+	}
+
+	return list, nil
+}
+
+// MapCertificateToName derives the username of a TLS client (RFC 7589 section 7).
+// The chain starts with the client certificate, the entries are tried in order
+// and the first one matching a certificate of the chain and yielding a name wins
+func MapCertificateToName(chain []*x509.Certificate, list []CertToName) (string, error) {
+
+	if len(chain) == 0 {
+		return "", errors.New("No client certificate")
+	}
+
+	client := chain[0]
+
+	for _, entry := range list {
+
+		matched := false
+		for _, cert := range chain {
+			if matchFingerprint(entry.Fingerprint, cert) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			continue
+		}
+
+		name := certificateName(entry, client)
+		if name == "" {
+			glog.Infof("[TLS] cert-to-name %d (%s) yields no name for %s", entry.ID, entry.MapType, client.Subject)
+			continue
+		}
+
+		glog.Infof("[TLS] cert-to-name %d maps %s to user=%s", entry.ID, client.Subject, name)
+		return name, nil
+	}
+
+	return "", errors.New("No cert-to-name entry matches the client certificate")
+}
+
+func certificateName(entry CertToName, cert *x509.Certificate) string {
+
+	switch entry.MapType {
+	case MapSpecified:
+		return entry.Name
+	case MapSANRFC822Name:
+		return sanRFC822Name(cert)
+	case MapSANDNSName:
+		return sanDNSName(cert)
+	case MapSANIPAddress:
+		return sanIPAddress(cert)
+	case MapSANAny:
+		for _, name := range []string{sanRFC822Name(cert), sanDNSName(cert), sanIPAddress(cert)} {
+			if name != "" {
+				return name
+			}
+		}
+	case MapCommonName:
+		return cert.Subject.CommonName
+	default:
+		glog.Warningf("[TLS] Unsupported cert-to-name map type %s", entry.MapType)
+	}
+
+	return ""
+}
+
+// The host part of an rfc822Name is case insensitive and converted to lowercase
+func sanRFC822Name(cert *x509.Certificate) string {
+
+	if len(cert.EmailAddresses) == 0 {
+		return ""
+	}
+
+	address := cert.EmailAddresses[0]
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		address = address[:i] + strings.ToLower(address[i:])
+	}
+
+	return address
+}
+
+func sanDNSName(cert *x509.Certificate) string {
+
+	if len(cert.DNSNames) == 0 {
+		return ""
+	}
+
+	return strings.ToLower(cert.DNSNames[0])
+}
+
+// IPv4 in dotted quad and IPv6 in the RFC 5952 text form
+func sanIPAddress(cert *x509.Certificate) string {
+
+	if len(cert.IPAddresses) == 0 {
+		return ""
+	}
+
+	return cert.IPAddresses[0].String()
+}
+
+// matchFingerprint compares a tls-fingerprint with the hash of a certificate,
+// the first octet is the TLS HashAlgorithm identifier (RFC 5246)
+func matchFingerprint(fingerprint string, cert *x509.Certificate) bool {
+
+	raw, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+	if err != nil || len(raw) < 2 {
+		return false
+	}
+
+	var digest []byte
+
+	switch raw[0] {
+	case 1:
+		sum := md5.Sum(cert.Raw)
+		digest = sum[:]
+	case 2:
+		sum := sha1.Sum(cert.Raw)
+		digest = sum[:]
+	case 3:
+		sum := sha256.Sum224(cert.Raw)
+		digest = sum[:]
+	case 4:
+		sum := sha256.Sum256(cert.Raw)
+		digest = sum[:]
+	case 5:
+		sum := sha512.Sum384(cert.Raw)
+		digest = sum[:]
+	case 6:
+		sum := sha512.Sum512(cert.Raw)
+		digest = sum[:]
+	default:
+		return false
+	}
+
+	return hex.EncodeToString(raw[1:]) == hex.EncodeToString(digest)
+}
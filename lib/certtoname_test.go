@@ -0,0 +1,198 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init certtoname_test +++++")
+}
+
+// testX509Chain returns a client certificate and the CA that signed it
+func testX509Chain(t *testing.T, client *x509.Certificate) []*x509.Certificate {
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.SerialNumber = big.NewInt(2)
+	client.NotBefore, client.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	clientDER, err := x509.CreateCertificate(rand.Reader, client, ca, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(clientDER)
+
+	return []*x509.Certificate{cert, ca}
+}
+
+// testFingerprint formats a tls-fingerprint of a certificate
+func testFingerprint(algorithm byte, cert *x509.Certificate) string {
+
+	var digest []byte
+
+	switch algorithm {
+	case 1:
+		sum := md5.Sum(cert.Raw)
+		digest = sum[:]
+	case 2:
+		sum := sha1.Sum(cert.Raw)
+		digest = sum[:]
+	case 3:
+		sum := sha256.Sum224(cert.Raw)
+		digest = sum[:]
+	case 4:
+		sum := sha256.Sum256(cert.Raw)
+		digest = sum[:]
+	case 5:
+		sum := sha512.Sum384(cert.Raw)
+		digest = sum[:]
+	case 6:
+		sum := sha512.Sum512(cert.Raw)
+		digest = sum[:]
+	}
+
+	octets := []string{fmt.Sprintf("%02x", algorithm)}
+	for _, b := range digest {
+		octets = append(octets, hex.EncodeToString([]byte{b}))
+	}
+
+	return strings.Join(octets, ":")
+}
+
+func TestMatchFingerprint(t *testing.T) {
+
+	chain := testX509Chain(t, &x509.Certificate{Subject: pkix.Name{CommonName: "alice"}})
+	client, ca := chain[0], chain[1]
+
+	for algorithm := byte(1); algorithm <= 6; algorithm++ {
+		fingerprint := testFingerprint(algorithm, client)
+		if !matchFingerprint(fingerprint, client) {
+			t.Errorf("Algorithm %d: fingerprint of the certificate not matched", algorithm)
+		}
+		if !matchFingerprint(strings.ToUpper(fingerprint), client) {
+			t.Errorf("Algorithm %d: upper case fingerprint not matched", algorithm)
+		}
+		if matchFingerprint(fingerprint, ca) {
+			t.Errorf("Algorithm %d: fingerprint matched another certificate", algorithm)
+		}
+	}
+
+	// The hash must be the one named by the algorithm octet
+	sha256Hash := strings.TrimPrefix(testFingerprint(4, client), "04")
+
+	for _, fingerprint := range []string{"", "04", "06" + sha256Hash, "07" + sha256Hash, "00" + sha256Hash, "04:zz", testFingerprint(4, client)[:20]} {
+		if matchFingerprint(fingerprint, client) {
+			t.Errorf("Fingerprint %q matched", fingerprint)
+		}
+	}
+}
+
+func TestMapCertificateToName(t *testing.T) {
+
+	chain := testX509Chain(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "alice"},
+		EmailAddresses: []string{"Alice.Smith@Example.COM"},
+		DNSNames:       []string{"Router1.Example.com"},
+		IPAddresses:    []net.IP{net.ParseIP("2001:db8:0:0:0:0:0:1")},
+	})
+	client, ca := chain[0], chain[1]
+
+	bare := testX509Chain(t, &x509.Certificate{Subject: pkix.Name{CommonName: "bob"}})
+
+	clientFingerprint, caFingerprint := testFingerprint(4, client), testFingerprint(2, ca)
+	otherFingerprint := testFingerprint(4, bare[1])
+
+	tests := []struct {
+		name  string
+		chain []*x509.Certificate
+		list  []CertToName
+		user  string // "" when no name is mapped
+	}{
+		{"specified", chain, []CertToName{{1, clientFingerprint, MapSpecified, "admin"}}, "admin"},
+		{"rfc822 host lowercased", chain, []CertToName{{1, clientFingerprint, MapSANRFC822Name, ""}}, "Alice.Smith@example.com"},
+		{"dns name lowercased", chain, []CertToName{{1, clientFingerprint, MapSANDNSName, ""}}, "router1.example.com"},
+		{"ipv6 text form", chain, []CertToName{{1, clientFingerprint, MapSANIPAddress, ""}}, "2001:db8::1"},
+		{"san-any takes rfc822 first", chain, []CertToName{{1, clientFingerprint, MapSANAny, ""}}, "Alice.Smith@example.com"},
+		{"common name", chain, []CertToName{{1, clientFingerprint, MapCommonName, ""}}, "alice"},
+		{"CA fingerprint", chain, []CertToName{{1, caFingerprint, MapCommonName, ""}}, "alice"},
+		{"first match wins", chain, []CertToName{{1, caFingerprint, MapSpecified, "first"}, {2, clientFingerprint, MapSpecified, "second"}}, "first"},
+		{"unmatched entry skipped", chain, []CertToName{{1, otherFingerprint, MapSpecified, "other"}, {2, clientFingerprint, MapSpecified, "admin"}}, "admin"},
+		{"entry without name skipped", bare, []CertToName{{1, testFingerprint(4, bare[0]), MapSANDNSName, ""}, {2, testFingerprint(4, bare[0]), MapCommonName, ""}}, "bob"},
+		{"unknown map type skipped", chain, []CertToName{{1, clientFingerprint, "san-uri", ""}}, ""},
+		{"no san", bare, []CertToName{{1, testFingerprint(4, bare[0]), MapSANAny, ""}}, ""},
+		{"no match", chain, []CertToName{{1, otherFingerprint, MapSpecified, "other"}}, ""},
+		{"empty list", chain, nil, ""},
+		{"no certificate", nil, []CertToName{{1, clientFingerprint, MapSpecified, "admin"}}, ""},
+	}
+
+	for _, test := range tests {
+		user, err := MapCertificateToName(test.chain, test.list)
+		if test.user == "" {
+			if err == nil {
+				t.Errorf("%s: got user %q, want: no mapping", test.name, user)
+			}
+			continue
+		}
+		if err != nil || user != test.user {
+			t.Errorf("%s: got %q %v, want: %q", test.name, user, err, test.user)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"orange/sonic-netconf-server/configdb"
+)
+
+// CONFIG_DB client for the NETCONF server tables
+var configDB = configdb.Client
+
+// getTableEntries returns the entries of a CONFIG_DB table indexed by key,
+// the table name prefix is stripped from the keys
+func getTableEntries(table string) (map[string]map[string]string, error) {
+
+	keys, err := configDB.Keys(table + "|*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := map[string]map[string]string{}
+
+	for _, key := range keys {
+
+		data, err := configDB.HGetAll(key).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		entries[strings.TrimPrefix(key, table+"|")] = data
+	}
+
+	return entries, nil
+}
+
+// sortedNumericKeys orders table keys holding list ids, non numeric keys are skipped
+func sortedNumericKeys(entries map[string]map[string]string) []int {
+
+	ids := []int{}
+	for key := range entries {
+		if id, err := strconv.Atoi(key); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+
+	return ids
+}
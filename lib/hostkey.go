@@ -0,0 +1,209 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+// Directory of the host keys generated by the server, named ssh_host_<type>_key
+var HostKeyDir = "/etc/sonic/netconf"
+
+// Comma separated host key types served, among ed25519, ecdsa and rsa
+var HostKeyTypes = "ed25519,ecdsa,rsa"
+
+// Comma separated private key files served in addition to the typed keys
+var HostKeyFiles = ""
+
+// Serve the keys of the system SSH server when they exist instead of
+// generating our own, clients then see the same host keys on both ports
+var UseSystemHostKeys = false
+
+var SystemHostKeyDir = "/etc/ssh"
+
+// Sizes of the generated and the accepted RSA host keys
+const (
+	RSAHostKeyBits    = 3072
+	MinRSAHostKeyBits = 2048
+)
+
+// LoadHostKeys returns the host keys of the configured types, missing ones
+// are generated, and the keys of HostKeyFiles
+func LoadHostKeys() ([]ssh.Signer, error) {
+
+	signers := []ssh.Signer{}
+
+	for _, keyType := range strings.Split(HostKeyTypes, ",") {
+
+		keyType = strings.TrimSpace(keyType)
+		if keyType == "" {
+			continue
+		}
+
+		if UseSystemHostKeys {
+			path := filepath.Join(SystemHostKeyDir, "ssh_host_"+keyType+"_key")
+			if signer, err := loadHostKey(path); err == nil {
+				glog.Infof("[SSH] Using system host key %s", path)
+				signers = append(signers, signer)
+				continue
+			} else if !os.IsNotExist(err) {
+				glog.Warningf("[SSH] Unable to use system host key %s: %v", path, err)
+			}
+		}
+
+		path := filepath.Join(HostKeyDir, "ssh_host_"+keyType+"_key")
+
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			glog.Infof("[SSH] Host key %s not found, generating it", path)
+			if err := GenerateHostKey(keyType, path); err != nil {
+				return nil, err
+			}
+		}
+
+		signer, err := loadHostKey(path)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	for _, path := range strings.Split(HostKeyFiles, ",") {
+
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		signer, err := loadHostKey(path)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+
+	if len(signers) == 0 {
+		return nil, errors.New("No host key configured")
+	}
+
+	return signers, nil
+}
+
+// GenerateHostKey writes a new private key of a type in PKCS#8 with mode
+// 0600 and its public key in path.pub with mode 0644
+func GenerateHostKey(keyType string, path string) error {
+
+	var privateKey crypto.Signer
+	var err error
+
+	switch keyType {
+	case "ed25519":
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case "ecdsa":
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		privateKey, err = rsa.GenerateKey(rand.Reader, RSAHostKeyBits)
+	default:
+		return errors.New("Unsupported host key type " + keyType)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := ssh.NewPublicKey(privateKey.Public())
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Never replace an existing key
+	privateKeyFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	err = pem.Encode(privateKeyFile, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if closeErr := privateKeyFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return ioutil.WriteFile(path+".pub", ssh.MarshalAuthorizedKey(publicKey), 0644)
+}
+
+// loadHostKey reads a private key, refusing short RSA keys. Keys readable
+// by others than the owner are restricted to 0600
+func loadHostKey(path string) (ssh.Signer, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		glog.Warningf("[SSH] Host key %s has mode %o, restricting it to 0600", path, info.Mode().Perm())
+		if err := os.Chmod(path, 0600); err != nil {
+			glog.Errorf("[SSH] Unable to restrict %s: %v", path, err)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ssh.ParseRawPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok && rsaKey.N.BitLen() < MinRSAHostKeyBits {
+		return nil, fmt.Errorf("%s: %d bit RSA key too short, %d bits needed", path, rsaKey.N.BitLen(), MinRSAHostKeyBits)
+	}
+
+	return ssh.NewSignerFromKey(key)
+}
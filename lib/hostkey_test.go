@@ -0,0 +1,171 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	fmt.Println("+++++ init hostkey_test +++++")
+}
+
+func setHostKeyConfig(t *testing.T, types string, useSystem bool) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "hostkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedDir, savedTypes, savedFiles, savedUseSystem, savedSystemDir := HostKeyDir, HostKeyTypes, HostKeyFiles, UseSystemHostKeys, SystemHostKeyDir
+
+	HostKeyDir = filepath.Join(dir, "netconf")
+	HostKeyTypes = types
+	HostKeyFiles = ""
+	UseSystemHostKeys = useSystem
+	SystemHostKeyDir = filepath.Join(dir, "ssh")
+
+	return dir, func() {
+		HostKeyDir, HostKeyTypes, HostKeyFiles, UseSystemHostKeys, SystemHostKeyDir = savedDir, savedTypes, savedFiles, savedUseSystem, savedSystemDir
+		os.RemoveAll(dir)
+	}
+}
+
+func TestGenerateHostKeys(t *testing.T) {
+
+	_, restore := setHostKeyConfig(t, "ed25519,ecdsa,rsa", false)
+	defer restore()
+
+	signers, err := LoadHostKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoRSA}
+	if len(signers) != len(expected) {
+		t.Fatalf("Expected %d host keys, got %d", len(expected), len(signers))
+	}
+
+	for i, signer := range signers {
+		if signer.PublicKey().Type() != expected[i] {
+			t.Errorf("Key %d: type %s, expected %s", i, signer.PublicKey().Type(), expected[i])
+		}
+	}
+
+	for _, keyType := range []string{"ed25519", "ecdsa", "rsa"} {
+
+		path := filepath.Join(HostKeyDir, "ssh_host_"+keyType+"_key")
+
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("%s: mode %v %v", path, info.Mode(), err)
+		}
+
+		info, err := os.Stat(path + ".pub")
+		if err != nil || info.Mode().Perm() != 0644 {
+			t.Errorf("%s.pub: mode %v %v", path, info.Mode(), err)
+		}
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(HostKeyDir, "ssh_host_rsa_key"))
+	if key, err := ssh.ParseRawPrivateKey(data); err != nil || key.(*rsa.PrivateKey).N.BitLen() != RSAHostKeyBits {
+		t.Errorf("Generated RSA key of unexpected size: %v", err)
+	}
+
+	// Existing keys are reused
+	again, err := LoadHostKeys()
+	if err != nil || string(again[0].PublicKey().Marshal()) != string(signers[0].PublicKey().Marshal()) {
+		t.Errorf("Host key regenerated: %v", err)
+	}
+}
+
+func TestWeakHostKeys(t *testing.T) {
+
+	dir, restore := setHostKeyConfig(t, "", false)
+	defer restore()
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	HostKeyFiles = filepath.Join(dir, "weak_key")
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(weak)})
+	if err := ioutil.WriteFile(HostKeyFiles, pemData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadHostKeys(); err == nil {
+		t.Error("1024 bit RSA host key accepted")
+	}
+
+	if info, err := os.Stat(HostKeyFiles); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("World readable host key not restricted: %v %v", info.Mode(), err)
+	}
+
+	HostKeyFiles = ""
+	if _, err := LoadHostKeys(); err == nil {
+		t.Error("No host key loaded without error")
+	}
+
+	HostKeyTypes = "dsa"
+	if _, err := LoadHostKeys(); err == nil {
+		t.Error("Unsupported host key type accepted")
+	}
+}
+
+func TestSystemHostKeys(t *testing.T) {
+
+	_, restore := setHostKeyConfig(t, "ed25519,ecdsa", true)
+	defer restore()
+
+	systemKey := filepath.Join(SystemHostKeyDir, "ssh_host_ed25519_key")
+	if err := GenerateHostKey("ed25519", systemKey); err != nil {
+		t.Fatal(err)
+	}
+
+	signers, err := LoadHostKeys()
+	if err != nil || len(signers) != 2 {
+		t.Fatalf("Expected 2 host keys: %v", err)
+	}
+
+	data, _ := ioutil.ReadFile(systemKey + ".pub")
+	systemPublicKey, _, _, _, _ := ssh.ParseAuthorizedKey(data)
+	if string(signers[0].PublicKey().Marshal()) != string(systemPublicKey.Marshal()) {
+		t.Error("System ed25519 host key not used")
+	}
+
+	// No system ecdsa key, ours is generated
+	if _, err := os.Stat(filepath.Join(HostKeyDir, "ssh_host_ecdsa_key")); err != nil {
+		t.Errorf("ecdsa host key not generated: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(HostKeyDir, "ssh_host_ed25519_key")); err == nil {
+		t.Error("ed25519 host key generated despite the system key")
+	}
+}
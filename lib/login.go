@@ -0,0 +1,210 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"errors"
+
+	"orange/sonic-netconf-server/radius"
+	"orange/sonic-netconf-server/tacplus"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	LoginTacacs = "tacacs+"
+	LoginRadius = "radius"
+	LoginLocal  = "local"
+)
+
+// Sources of the login configuration and of the AAA server pools, replaced in tests
+var (
+	getAAALogin   = tacplus.GetAAALogin
+	getTacacsPool = tacplus.GetPool
+	getRadiusPool = radius.GetPool
+)
+
+// Authenticators holding a connection, closed at the end of the session
+type Disconnecter interface {
+	Disconnect()
+}
+
+// Login authenticates a user following the AAA login order, it returns the
+// authenticator of the method that accepted the user and the method name
+func Login(ctx context.Context, protocol string, service string, username string, password string, remoteAddress string) (Authenticator, string, error) {
+
+	login := getAAALogin()
+
+	for _, method := range login.Methods {
+
+		switch method {
+		case LoginTacacs:
+
+			tacacsAuthenticator, err := NewTacacsAuthenticator(ctx, protocol, service, username, password, remoteAddress)
+
+			if err != nil {
+				glog.Warningf("[AAA] TACACS+ unavailable for user:(%s) %v", username, err)
+				if login.Fallback {
+					continue
+				}
+				return nil, method, err
+			}
+
+			if tacacsAuthenticator.Authenticate() {
+
+				// Privilege level and roles of the session
+				if tacacsAuthenticator.AuthorizeSession() {
+					return tacacsAuthenticator, method, nil
+				}
+
+				glog.Infof("[AAA] TACACS+ session authorization rejected user:(%s)", username)
+
+			} else {
+				glog.Infof("[AAA] TACACS+ authentication rejected user:(%s)", username)
+			}
+
+			tacacsAuthenticator.Disconnect()
+
+			if !login.Failthrough {
+				return nil, method, errors.New("TACACS+ authentication failed")
+			}
+
+		case LoginRadius:
+
+			radiusAuthenticator, err := NewRadiusAuthenticator(ctx, protocol, service, username, password, remoteAddress)
+
+			if err != nil {
+				glog.Warningf("[AAA] RADIUS unavailable for user:(%s) %v", username, err)
+				if login.Fallback {
+					continue
+				}
+				return nil, method, err
+			}
+
+			if radiusAuthenticator.Authenticate() {
+				return radiusAuthenticator, method, nil
+			}
+
+			glog.Infof("[AAA] RADIUS authentication rejected user:(%s)", username)
+
+			if !login.Failthrough {
+				return nil, method, errors.New("RADIUS authentication failed")
+			}
+
+		case LoginLocal:
+
+			pamAuthenticator := NewPAMAuthenticator(username, password, remoteAddress)
+
+			if pamAuthenticator.Authenticate() {
+				return localAuthenticator(pamAuthenticator), method, nil
+			}
+
+			glog.Infof("[AAA] Local authentication rejected user:(%s)", username)
+
+			if !login.Failthrough {
+				return nil, method, errors.New("Local authentication failed")
+			}
+
+		default:
+			glog.Warningf("[AAA] Unsupported login method %s skipped", method)
+		}
+	}
+
+	return nil, "", errors.New("Authentication failed for all login methods")
+}
+
+// CheckPublicKey checks that a public key is authorized for a user, or that a
+// certificate is signed by a trusted CA. It is called for keys the client only
+// offers, so it must not contact any AAA server: the session is authorized by
+// LoginVerified once the client has proven it holds the key
+func CheckPublicKey(username string, key ssh.PublicKey, remoteAddress string) error {
+
+	if cert, ok := key.(*ssh.Certificate); ok {
+		if !IsAuthorizedCertificate(username, cert, remoteAddress) {
+			return errors.New("Certificate not authorized")
+		}
+	} else if IsRevokedKey(key) || !IsAuthorizedKey(username, key, remoteAddress) {
+		return errors.New("Public key not authorized")
+	}
+
+	return nil
+}
+
+// LoginVerified authorizes the session of a user whose identity was already
+// proven by the transport, a public key or a client certificate
+func LoginVerified(ctx context.Context, protocol string, service string, username string, remoteAddress string) (Authenticator, string, error) {
+
+	login := getAAALogin()
+
+	for _, method := range login.Methods {
+
+		switch method {
+		case LoginTacacs:
+
+			tacacsAuthenticator, err := NewTacacsAuthenticator(ctx, protocol, service, username, "", remoteAddress)
+
+			if err != nil {
+				glog.Warningf("[AAA] TACACS+ unavailable for user:(%s) %v", username, err)
+				if login.Fallback {
+					continue
+				}
+				return nil, method, err
+			}
+
+			if tacacsAuthenticator.AuthorizeSession() {
+				return tacacsAuthenticator, method, nil
+			}
+
+			tacacsAuthenticator.Disconnect()
+
+			glog.Infof("[AAA] TACACS+ authorization rejected verified login user:(%s)", username)
+
+			if !login.Failthrough {
+				return nil, method, errors.New("TACACS+ authorization failed")
+			}
+
+		case LoginRadius:
+
+			// An Access-Request needs a password
+			glog.Infof("[AAA] RADIUS cannot authorize verified login user:(%s), skipped", username)
+
+		case LoginLocal:
+
+			pamAuthenticator := NewPAMAuthenticator(username, "", remoteAddress)
+
+			if pamAuthenticator.AuthenticateVerified() {
+				return localAuthenticator(pamAuthenticator), method, nil
+			}
+
+			glog.Infof("[AAA] Local account rejected verified login user:(%s)", username)
+
+			if !login.Failthrough {
+				return nil, method, errors.New("Local account check failed")
+			}
+
+		default:
+			glog.Warningf("[AAA] Unsupported login method %s skipped", method)
+		}
+	}
+
+	return nil, "", errors.New("Verified login failed for all login methods")
+}
@@ -0,0 +1,192 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"orange/sonic-netconf-server/radius"
+	"orange/sonic-netconf-server/radius/radiustest"
+	"orange/sonic-netconf-server/tacplus"
+	"orange/sonic-netconf-server/tacplus/tacplustest"
+)
+
+func init() {
+	fmt.Println("+++++ init login_test +++++")
+}
+
+// setLoginServers replaces the login configuration and the AAA pools, a nil
+// server makes its method unavailable
+func setLoginServers(login tacplus.AAALogin, tacacs *tacplustest.Server, radiusServer *radiustest.Server) func() {
+
+	savedLogin, savedTacacs, savedRadius := getAAALogin, getTacacsPool, getRadiusPool
+
+	getAAALogin = func() tacplus.AAALogin { return login }
+
+	getTacacsPool = func(ctx context.Context) (*tacplus.Pool, tacplus.TacacsInfo, error) {
+		if tacacs == nil {
+			return nil, tacplus.TacacsInfo{}, errors.New("no TACACS+ server")
+		}
+		info := tacacs.Info("pap")
+		return tacplus.NewPool([]tacplus.TacacsInfo{info}), info, nil
+	}
+
+	getRadiusPool = func() (*radius.Pool, radius.RadiusInfo, error) {
+		if radiusServer == nil {
+			return nil, radius.RadiusInfo{}, errors.New("no RADIUS server")
+		}
+		info := radiusServer.Info("pap")
+		return radius.NewPool([]radius.RadiusInfo{info}), info, nil
+	}
+
+	return func() { getAAALogin, getTacacsPool, getRadiusPool = savedLogin, savedTacacs, savedRadius }
+}
+
+func startLoginServers(t *testing.T) (*tacplustest.Server, *radiustest.Server) {
+
+	tacacs := tacplustest.NewServer("testing123")
+	tacacs.AddUser("tacuser", "tacpass", "priv-lvl=15")
+	tacacs.AddUser("shared", "tacshared", "priv-lvl=15")
+	if err := tacacs.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	radiusServer := radiustest.NewServer("testing123")
+	radiusServer.AddUser("raduser", "radpass").ServiceType = radius.ServiceTypeAdministrative
+	radiusServer.AddUser("shared", "radshared").ServiceType = radius.ServiceTypeAdministrative
+	if err := radiusServer.Start(); err != nil {
+		tacacs.Close()
+		t.Fatal(err)
+	}
+
+	return tacacs, radiusServer
+}
+
+func TestLogin(t *testing.T) {
+
+	tacacs, radiusServer := startLoginServers(t)
+	defer tacacs.Close()
+	defer radiusServer.Close()
+
+	both := []string{LoginTacacs, LoginRadius, LoginLocal}
+
+	tests := []struct {
+		name        string
+		methods     []string
+		failthrough bool
+		fallback    bool
+		tacacsDown  bool
+		username    string
+		password    string
+		method      string // Method that accepted the user, "" when rejected
+		lastMethod  string // Method reported on rejection
+	}{
+		{"first method accepts", both, false, false, false, "tacuser", "tacpass", LoginTacacs, ""},
+		{"order is followed", []string{LoginRadius, LoginTacacs}, false, false, false, "shared", "radshared", LoginRadius, ""},
+		{"rejection stops without failthrough", both, false, false, false, "shared", "radshared", "", LoginTacacs},
+		{"failthrough tries the next method", both, true, false, false, "shared", "radshared", LoginRadius, ""},
+		{"unknown user with failthrough", both, true, false, false, "nobody", "secret", "", ""},
+		{"unreachable server stops without fallback", both, true, false, true, "raduser", "radpass", "", LoginTacacs},
+		{"fallback skips an unreachable server", both, false, true, true, "raduser", "radpass", LoginRadius, ""},
+		{"fallback does not skip a rejection", both, false, true, false, "raduser", "radpass", "", LoginTacacs},
+		{"unsupported methods are skipped", []string{"ldap", LoginRadius}, false, false, false, "raduser", "radpass", LoginRadius, ""},
+	}
+
+	for _, test := range tests {
+
+		tacacsServer := tacacs
+		if test.tacacsDown {
+			tacacsServer = nil
+		}
+
+		restore := setLoginServers(tacplus.AAALogin{Methods: test.methods, Failthrough: test.failthrough, Fallback: test.fallback}, tacacsServer, radiusServer)
+
+		authenticator, method, err := Login(context.Background(), "ssh", "netconf", test.username, test.password, "192.0.2.1")
+
+		restore()
+
+		if test.method != "" {
+			if err != nil || authenticator == nil || method != test.method {
+				t.Errorf("%s: got method %q %v, want: %q", test.name, method, err, test.method)
+			}
+			if disconnecter, ok := authenticator.(Disconnecter); ok {
+				disconnecter.Disconnect()
+			}
+			continue
+		}
+
+		if err == nil || authenticator != nil || method != test.lastMethod {
+			t.Errorf("%s: got method %q %v, want: rejection by %q", test.name, method, err, test.lastMethod)
+		}
+	}
+}
+
+func TestLoginVerified(t *testing.T) {
+
+	tacacs, radiusServer := startLoginServers(t)
+	defer tacacs.Close()
+	defer radiusServer.Close()
+
+	tests := []struct {
+		name        string
+		methods     []string
+		failthrough bool
+		tacacsDown  bool
+		username    string
+		method      string
+	}{
+		{"session authorized by TACACS+", []string{LoginTacacs, LoginLocal}, false, false, "tacuser", LoginTacacs},
+		{"RADIUS is skipped", []string{LoginRadius, LoginTacacs}, false, false, "tacuser", LoginTacacs},
+		{"RADIUS alone cannot authorize", []string{LoginRadius}, false, false, "raduser", ""},
+		{"unknown user without failthrough", []string{LoginTacacs, LoginRadius}, false, false, "nobody", ""},
+		{"unreachable server without fallback", []string{LoginTacacs}, false, true, "tacuser", ""},
+	}
+
+	for _, test := range tests {
+
+		tacacsServer := tacacs
+		if test.tacacsDown {
+			tacacsServer = nil
+		}
+
+		restore := setLoginServers(tacplus.AAALogin{Methods: test.methods, Failthrough: test.failthrough}, tacacsServer, radiusServer)
+
+		authenticator, method, err := LoginVerified(context.Background(), "ssh", "netconf", test.username, "192.0.2.1")
+
+		restore()
+
+		if test.method != "" {
+			if err != nil || method != test.method {
+				t.Errorf("%s: got method %q %v, want: %q", test.name, method, err, test.method)
+			}
+			if disconnecter, ok := authenticator.(Disconnecter); ok {
+				disconnecter.Disconnect()
+			}
+			continue
+		}
+
+		if err == nil || authenticator != nil {
+			t.Errorf("%s: got method %q, want: rejection", test.name, method)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Brute force protection of the logins, each limit is disabled when 0
+var (
+	LoginMaxFailures   = 5                // Failed passwords of a source or user before a lockout
+	LoginLockoutTime   = 5 * time.Minute  // Duration of a lockout
+	LoginBackoffBase   = 1 * time.Second  // Answer delay of the first failure, doubled at each failure
+	LoginBackoffMax    = 30 * time.Second // Maximum answer delay
+	MaxUnauthenticated = 32               // Concurrent connections not yet authenticated
+	MaxSessionsPerUser = 0                // Concurrent NETCONF sessions of a user
+)
+
+// Number of tracked sources and users above which stale counters are dropped
+const loginCountersPrune = 1024
+
+// Login protection counters, exposed in the monitoring statistics
+type LoginStats struct {
+	SuccessfulLogins    uint64
+	FailedLogins        uint64
+	Lockouts            uint64
+	LockedOutLogins     uint64 // Attempts refused during a lockout
+	RejectedConnections uint64 // Over the unauthenticated connections limit
+	RejectedSessions    uint64 // Over the sessions per user limit
+	LockedSources       int
+	LockedUsers         int
+	Unauthenticated     int
+}
+
+type loginCounter struct {
+	failures    int
+	last        time.Time
+	lockedUntil time.Time
+}
+
+type loginGuard struct {
+	mutex           sync.Mutex
+	sources         map[string]*loginCounter
+	users           map[string]*loginCounter
+	sessions        map[string]int
+	unauthenticated int
+	stats           LoginStats
+}
+
+var guard = &loginGuard{
+	sources:  map[string]*loginCounter{},
+	users:    map[string]*loginCounter{},
+	sessions: map[string]int{},
+}
+
+// CheckLogin refuses the attempts of a locked out source or user
+func CheckLogin(username string, remoteAddress string) error {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	now := time.Now()
+
+	if counter, ok := guard.sources[remoteAddress]; ok && now.Before(counter.lockedUntil) {
+		guard.stats.LockedOutLogins++
+		return errors.New("Too many failed logins from " + remoteAddress)
+	}
+
+	if counter, ok := guard.users[username]; ok && now.Before(counter.lockedUntil) {
+		guard.stats.LockedOutLogins++
+		return errors.New("Too many failed logins of user " + username)
+	}
+
+	return nil
+}
+
+// LoginFailed counts a failed password of a user from a source, it returns
+// the delay to wait before answering the client
+func LoginFailed(username string, remoteAddress string) time.Duration {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	guard.stats.FailedLogins++
+
+	if len(guard.sources)+len(guard.users) > loginCountersPrune {
+		guard.prune()
+	}
+
+	sourceFailures := guard.fail(guard.sources, "source", remoteAddress)
+	userFailures := guard.fail(guard.users, "user", username)
+
+	failures := sourceFailures
+	if userFailures > failures {
+		failures = userFailures
+	}
+
+	delay := LoginBackoffBase
+	for i := 1; i < failures && delay < LoginBackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > LoginBackoffMax {
+		delay = LoginBackoffMax
+	}
+
+	return delay
+}
+
+// fail increments a counter, locking it out at the failure limit
+func (g *loginGuard) fail(counters map[string]*loginCounter, kind string, key string) int {
+
+	now := time.Now()
+
+	counter, ok := counters[key]
+	if !ok || now.Sub(counter.last) > LoginLockoutTime {
+		// Failures older than a lockout are forgotten
+		counter = &loginCounter{}
+		counters[key] = counter
+	}
+
+	counter.failures++
+	counter.last = now
+
+	if LoginMaxFailures > 0 && counter.failures >= LoginMaxFailures && now.After(counter.lockedUntil) {
+		glog.Warningf("[AAA] Locking out %s %s for %v after %d failed logins", kind, key, LoginLockoutTime, counter.failures)
+		counter.lockedUntil = now.Add(LoginLockoutTime)
+		counter.failures = 0
+		g.stats.Lockouts++
+	}
+
+	return counter.failures
+}
+
+// prune drops the counters neither locked nor updated during a lockout time
+func (g *loginGuard) prune() {
+
+	now := time.Now()
+
+	for _, counters := range []map[string]*loginCounter{g.sources, g.users} {
+		for key, counter := range counters {
+			if now.After(counter.lockedUntil) && now.Sub(counter.last) > LoginLockoutTime {
+				delete(counters, key)
+			}
+		}
+	}
+}
+
+// LoginSucceeded resets the failures of a user. Those of the source are
+// kept, a valid account must not clear the guessing of others from there
+func LoginSucceeded(username string, remoteAddress string) {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	guard.stats.SuccessfulLogins++
+
+	delete(guard.users, username)
+}
+
+// LoginSlot is held by a connection until it authenticates or closes
+type LoginSlot struct {
+	once sync.Once
+}
+
+// AcquireLoginSlot reserves one of the unauthenticated connections, nil
+// when the limit is reached
+func AcquireLoginSlot() *LoginSlot {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	if MaxUnauthenticated > 0 && guard.unauthenticated >= MaxUnauthenticated {
+		guard.stats.RejectedConnections++
+		return nil
+	}
+
+	guard.unauthenticated++
+
+	return &LoginSlot{}
+}
+
+// Release frees the slot, later calls do nothing
+func (s *LoginSlot) Release() {
+	s.once.Do(func() {
+		guard.mutex.Lock()
+		guard.unauthenticated--
+		guard.mutex.Unlock()
+	})
+}
+
+// AcquireSession reserves a session of a user, false when the limit is reached
+func AcquireSession(username string) bool {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	if MaxSessionsPerUser > 0 && guard.sessions[username] >= MaxSessionsPerUser {
+		guard.stats.RejectedSessions++
+		return false
+	}
+
+	guard.sessions[username]++
+
+	return true
+}
+
+func ReleaseSession(username string) {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	guard.sessions[username]--
+	if guard.sessions[username] <= 0 {
+		delete(guard.sessions, username)
+	}
+}
+
+// GetLoginStats returns a copy of the login protection counters
+func GetLoginStats() LoginStats {
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	stats := guard.stats
+	stats.Unauthenticated = guard.unauthenticated
+
+	now := time.Now()
+	for _, counter := range guard.sources {
+		if now.Before(counter.lockedUntil) {
+			stats.LockedSources++
+		}
+	}
+	for _, counter := range guard.users {
+		if now.Before(counter.lockedUntil) {
+			stats.LockedUsers++
+		}
+	}
+
+	return stats
+}
@@ -0,0 +1,242 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init loginguard_test +++++")
+}
+
+// setLoginGuard starts from empty counters with the given lockout limits,
+// the previous guard and limits are restored by the returned function
+func setLoginGuard(maxFailures int, lockout time.Duration, backoff time.Duration) func() {
+
+	savedGuard := guard
+	savedFailures, savedLockout, savedBackoff := LoginMaxFailures, LoginLockoutTime, LoginBackoffBase
+	savedUnauthenticated, savedSessions := MaxUnauthenticated, MaxSessionsPerUser
+
+	guard = &loginGuard{
+		sources:  map[string]*loginCounter{},
+		users:    map[string]*loginCounter{},
+		sessions: map[string]int{},
+	}
+	LoginMaxFailures, LoginLockoutTime, LoginBackoffBase = maxFailures, lockout, backoff
+
+	return func() {
+		guard = savedGuard
+		LoginMaxFailures, LoginLockoutTime, LoginBackoffBase = savedFailures, savedLockout, savedBackoff
+		MaxUnauthenticated, MaxSessionsPerUser = savedUnauthenticated, savedSessions
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		failures []string // user@source of each failed login
+		username string
+		source   string
+		locked   bool
+	}{
+		{"under the limit", []string{"alice@192.0.2.1", "alice@192.0.2.1"}, "alice", "192.0.2.1", false},
+		{"user locked from any source", []string{"alice@192.0.2.1", "alice@192.0.2.2", "alice@192.0.2.3"}, "alice", "192.0.2.4", true},
+		{"source locked for any user", []string{"alice@192.0.2.1", "bob@192.0.2.1", "carol@192.0.2.1"}, "dave", "192.0.2.1", true},
+		{"other user and source", []string{"alice@192.0.2.1", "alice@192.0.2.1", "alice@192.0.2.1"}, "bob", "192.0.2.2", false},
+	}
+
+	for _, test := range tests {
+		restore := setLoginGuard(3, time.Minute, time.Millisecond)
+
+		for _, failure := range test.failures {
+			parts := strings.SplitN(failure, "@", 2)
+			LoginFailed(parts[0], parts[1])
+		}
+
+		if err := CheckLogin(test.username, test.source); (err != nil) != test.locked {
+			t.Errorf("%s: CheckLogin returned %v, locked %v expected", test.name, err, test.locked)
+		}
+
+		restore()
+	}
+}
+
+func TestLoginLockoutExpiry(t *testing.T) {
+
+	restore := setLoginGuard(2, 50*time.Millisecond, time.Millisecond)
+	defer restore()
+
+	LoginFailed("alice", "192.0.2.1")
+	LoginFailed("alice", "192.0.2.1")
+
+	if CheckLogin("alice", "192.0.2.1") == nil {
+		t.Fatalf("Login not locked out after the failure limit")
+	}
+
+	stats := GetLoginStats()
+	if stats.Lockouts != 2 || stats.LockedUsers != 1 || stats.LockedSources != 1 || stats.LockedOutLogins != 1 {
+		t.Errorf("Unexpected counters after a lockout: %+v", stats)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if err := CheckLogin("alice", "192.0.2.1"); err != nil {
+		t.Errorf("Login still locked out after the lockout time: %v", err)
+	}
+}
+
+func TestLoginBackoff(t *testing.T) {
+
+	restore := setLoginGuard(0, time.Minute, 10*time.Millisecond)
+	defer restore()
+
+	savedMax := LoginBackoffMax
+	LoginBackoffMax = 50 * time.Millisecond
+	defer func() { LoginBackoffMax = savedMax }()
+
+	expected := []time.Duration{10, 20, 40, 50, 50}
+	for i, delay := range expected {
+		if got := LoginFailed("alice", "192.0.2.1"); got != delay*time.Millisecond {
+			t.Errorf("Failure %d: delay %v, want %v", i+1, got, delay*time.Millisecond)
+		}
+	}
+
+	if CheckLogin("alice", "192.0.2.1") != nil {
+		t.Errorf("Login locked out with the lockout disabled")
+	}
+}
+
+func TestLoginSucceeded(t *testing.T) {
+
+	restore := setLoginGuard(3, time.Minute, time.Millisecond)
+	defer restore()
+
+	// A valid account must not clear the failures of the source
+	LoginFailed("alice", "192.0.2.1")
+	LoginFailed("bob", "192.0.2.1")
+	LoginSucceeded("carol", "192.0.2.1")
+	LoginFailed("dave", "192.0.2.1")
+
+	if CheckLogin("erin", "192.0.2.1") == nil {
+		t.Errorf("Source failures reset by the login of another user")
+	}
+
+	LoginFailed("frank", "192.0.2.2")
+	LoginFailed("frank", "192.0.2.3")
+	LoginSucceeded("frank", "192.0.2.4")
+	LoginFailed("frank", "192.0.2.5")
+
+	if err := CheckLogin("frank", "192.0.2.6"); err != nil {
+		t.Errorf("User failures not reset by a successful login: %v", err)
+	}
+
+	if stats := GetLoginStats(); stats.SuccessfulLogins != 2 || stats.FailedLogins != 6 {
+		t.Errorf("Unexpected counters: %+v", stats)
+	}
+}
+
+func TestLoginPrune(t *testing.T) {
+
+	restore := setLoginGuard(2, time.Minute, time.Millisecond)
+	defer restore()
+
+	LoginFailed("locked", "192.0.2.1")
+	LoginFailed("locked", "192.0.2.1")
+
+	for i := 0; i <= loginCountersPrune; i++ {
+		LoginFailed("user"+strconv.Itoa(i), "198.51.100."+strconv.Itoa(i%256))
+	}
+
+	// Every unlocked counter is now older than the lockout time
+	LoginLockoutTime = 0
+	LoginFailed("last", "203.0.113.1")
+
+	if len(guard.users) != 2 {
+		t.Errorf("%d users kept after pruning, the locked and the last one expected", len(guard.users))
+	}
+	if _, ok := guard.users["locked"]; !ok {
+		t.Errorf("Locked out user pruned")
+	}
+}
+
+func TestLoginSlots(t *testing.T) {
+
+	restore := setLoginGuard(0, time.Minute, time.Millisecond)
+	defer restore()
+
+	MaxUnauthenticated = 2
+
+	first, second := AcquireLoginSlot(), AcquireLoginSlot()
+	if first == nil || second == nil {
+		t.Fatalf("Slots refused under the limit")
+	}
+
+	if AcquireLoginSlot() != nil {
+		t.Errorf("Slot granted over the limit")
+	}
+
+	first.Release()
+	first.Release()
+
+	if stats := GetLoginStats(); stats.Unauthenticated != 1 || stats.RejectedConnections != 1 {
+		t.Errorf("Unexpected counters: %+v", stats)
+	}
+
+	if AcquireLoginSlot() == nil {
+		t.Errorf("Released slot not available")
+	}
+}
+
+func TestSessionLimit(t *testing.T) {
+
+	restore := setLoginGuard(0, time.Minute, time.Millisecond)
+	defer restore()
+
+	MaxSessionsPerUser = 1
+
+	if !AcquireSession("alice") || !AcquireSession("bob") {
+		t.Fatalf("Sessions refused under the limit")
+	}
+
+	if AcquireSession("alice") {
+		t.Errorf("Session granted over the limit")
+	}
+
+	ReleaseSession("alice")
+
+	if !AcquireSession("alice") {
+		t.Errorf("Released session not available")
+	}
+
+	if stats := GetLoginStats(); stats.RejectedSessions != 1 {
+		t.Errorf("Unexpected counters: %+v", stats)
+	}
+
+	MaxSessionsPerUser = 0
+	if !AcquireSession("alice") {
+		t.Errorf("Session refused without limit")
+	}
+}
@@ -20,53 +20,171 @@
 package lib
 
 import (
+	"errors"
+	"os/user"
+
 	"github.com/golang/glog"
-	"golang.org/x/crypto/ssh"
+	"github.com/msteinert/pam/v2"
 )
 
+// PAM service used for local users, its stack is /etc/pam.d/<service>
+var PAMService = "sonic-netconf"
+
 type PAMAuthenticator struct {
-	username string
-	password string
+	username      string
+	password      string
+	remoteAddress string
+	groups        []string
 }
 
-func NewPAMAuthenticator(username string, password string) PAMAuthenticator {
-	return PAMAuthenticator{
-		username: username,
-		password: password,
+func NewPAMAuthenticator(username string, password string, remoteAddress string) *PAMAuthenticator {
+	return &PAMAuthenticator{
+		username:      username,
+		password:      password,
+		remoteAddress: remoteAddress,
 	}
 }
 
-func (p PAMAuthenticator) Authenticate() bool {
-	var rc struct{
-		ID string
+// conversation answers the password prompts, the client cannot be asked anything else
+func (p *PAMAuthenticator) conversation(style pam.Style, message string) (string, error) {
+	switch style {
+	case pam.PromptEchoOff:
+		return p.password, nil
+	case pam.PromptEchoOn:
+		glog.Infof("[PAM] Unexpected prompt for user=%s: %s", p.username, message)
+		return "", errors.New("unsupported prompt")
+	case pam.ErrorMsg:
+		glog.Warningf("[PAM] user=%s: %s", p.username, message)
+	case pam.TextInfo:
+		glog.Infof("[PAM] user=%s: %s", p.username, message)
 	}
+	return "", nil
+}
+
+func (p *PAMAuthenticator) Authenticate() bool {
 
-	rc.ID = p.username
+	glog.Infof("[PAM] Received user=%s service=%s", p.username, PAMService)
+
+	transaction, err := p.start()
+	if err != nil {
+		return false
+	}
+	defer transaction.End()
 
-	glog.Infof("[%s] Received user=%s", rc.ID, p.username)
+	if err := transaction.Authenticate(pam.DisallowNullAuthtok); err != nil {
+		glog.Infof("[PAM] Failed to authenticate user=%s: %v", p.username, err)
+		return false
+	}
 
-	//Use ssh for authentication.
-	config := &ssh.ClientConfig{
-		User: p.username,
-		Auth: []ssh.AuthMethod{
-			ssh.Password(p.password),
-		},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	if !p.checkAccount(transaction) {
+		return false
 	}
-	_, err := ssh.Dial("tcp", "127.0.0.1:22", config)
+
+	glog.Infof("[PAM] Authentication passed. user=%s groups=%v", p.username, p.groups)
+	return true
+}
+
+// AuthenticateVerified completes the login of a user already authenticated
+// by public key or client certificate, only the account is checked
+func (p *PAMAuthenticator) AuthenticateVerified() bool {
+
+	glog.Infof("[PAM] Received verified login user=%s service=%s", p.username, PAMService)
+
+	transaction, err := p.start()
+	if err != nil {
+		return false
+	}
+	defer transaction.End()
+
+	if !p.checkAccount(transaction) {
+		return false
+	}
+
+	glog.Infof("[PAM] Verified login passed. user=%s groups=%v", p.username, p.groups)
+	return true
+}
+
+func (p *PAMAuthenticator) start() (*pam.Transaction, error) {
+
+	transaction, err := pam.StartFunc(PAMService, p.username, p.conversation)
 	if err != nil {
-		glog.Infof("[%s] Failed to authenticate; %v", rc.ID, err)
+		glog.Errorf("[PAM] Unable to start service %s: %v", PAMService, err)
+		return nil, err
+	}
+
+	if p.remoteAddress != "" {
+		if err := transaction.SetItem(pam.Rhost, p.remoteAddress); err != nil {
+			glog.Warningf("[PAM] Unable to set the remote host %s: %v", p.remoteAddress, err)
+		}
+	}
+
+	return transaction, nil
+}
+
+// checkAccount runs the account expiry and password aging checks, then looks up the user groups
+func (p *PAMAuthenticator) checkAccount(transaction *pam.Transaction) bool {
+
+	if err := transaction.AcctMgmt(pam.DisallowNullAuthtok); err != nil {
+		switch {
+		case errors.Is(err, pam.ErrNewAuthtokReqd):
+			// A NETCONF client has no way to change the password
+			glog.Warningf("[PAM] Password change required for user=%s, login rejected", p.username)
+		case errors.Is(err, pam.ErrAcctExpired):
+			glog.Warningf("[PAM] Account expired for user=%s", p.username)
+		default:
+			glog.Infof("[PAM] Account check failed for user=%s: %v", p.username, err)
+		}
 		return false
 	}
 
-	glog.Infof("[%s] Authentication passed. user=%s ", rc.ID, p.username)
+	groups, err := lookupGroups(p.username)
+	if err != nil {
+		glog.Warningf("[PAM] Unable to get the groups of user=%s: %v", p.username, err)
+	}
+	p.groups = groups
+
 	return true
 }
 
-func (p PAMAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+func (p *PAMAuthenticator) Username() string {
+	return p.username
+}
+
+// Groups returns the local groups of the authenticated user, used for role mapping
+func (p *PAMAuthenticator) Groups() []string {
+	return p.groups
+}
+
+func (p *PAMAuthenticator) Authorize(cmd string, cmdArgs string) bool {
 	return true
 }
 
-func (p PAMAuthenticator) Account(cmd string, cmdArgs string) bool {
+func (p *PAMAuthenticator) Account(cmd string, cmdArgs string) bool {
 	return true
-}
\ No newline at end of file
+}
+
+// lookupGroups returns the names of the primary and supplementary groups of a user
+func lookupGroups(username string) ([]string, error) {
+
+	u, err := user.Lookup(username)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]string, 0, len(ids))
+	for _, id := range ids {
+		group, err := user.LookupGroupId(id)
+		if err != nil {
+			glog.Warningf("[PAM] Unknown group id %s of user=%s", id, username)
+			continue
+		}
+		groups = append(groups, group.Name)
+	}
+
+	return groups, nil
+}
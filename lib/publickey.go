@@ -0,0 +1,235 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+// Central directory of authorized keys, one file per user named after the user.
+// Keys found there are accepted in addition to ~/.ssh/authorized_keys
+var AuthorizedKeysDir = "/etc/sonic/netconf/authorized_keys"
+
+// authorized_keys options already satisfied by the server, it only serves
+// NETCONF sessions, without forwarding, X11, agent or user rc
+var satisfiedKeyOptions = []string{
+	"restrict", "no-agent-forwarding", "no-port-forwarding", "no-pty",
+	"no-user-rc", "no-x11-forwarding", "agent-forwarding", "port-forwarding",
+	"pty", "user-rc", "x11-forwarding", "no-touch-required",
+	"permitopen=", "permitlisten=", "environment=",
+}
+
+// IsAuthorizedKey checks a public key against the authorized keys files of
+// a user, honoring the from= and expiry-time= options of the matching entry.
+// Entries with options the server cannot enforce, such as command=, are refused
+func IsAuthorizedKey(username string, key ssh.PublicKey, remoteAddress string) bool {
+
+	files := []string{}
+
+	if u, err := user.Lookup(username); err == nil {
+		files = append(files, filepath.Join(u.HomeDir, ".ssh", "authorized_keys"))
+	}
+
+	if AuthorizedKeysDir != "" && filepath.Base(username) == username {
+		files = append(files, filepath.Join(AuthorizedKeysDir, username))
+	}
+
+	marshaled := key.Marshal()
+
+	for _, file := range files {
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		for len(data) > 0 {
+
+			authorized, _, options, rest, err := ssh.ParseAuthorizedKey(data)
+			if err != nil {
+				break
+			}
+			data = rest
+
+			if !bytes.Equal(authorized.Marshal(), marshaled) {
+				continue
+			}
+
+			if err := checkKeyOptions(options, time.Now()); err != nil {
+				glog.Infof("[SSH] Key of user=%s in %s refused: %v", username, file, err)
+				continue
+			}
+
+			if !matchFromOption(options, remoteAddress) {
+				glog.Infof("[SSH] Key of user=%s in %s not allowed from %s", username, file, remoteAddress)
+				continue
+			}
+
+			glog.Infof("[SSH] Key %s of user=%s found in %s", ssh.FingerprintSHA256(key), username, file)
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkKeyOptions refuses expired entries and the options that cannot be
+// enforced, from= is checked by matchFromOption
+func checkKeyOptions(options []string, now time.Time) error {
+
+	for _, option := range options {
+
+		name := strings.ToLower(option)
+		if i := strings.Index(name, "="); i >= 0 {
+			name = name[:i+1]
+		}
+
+		switch {
+		case name == "from=":
+		case name == "expiry-time=":
+			expiry, err := parseExpiryTime(strings.Trim(option[len("expiry-time="):], "\""))
+			if err != nil {
+				return err
+			}
+			if !now.Before(expiry) {
+				return errors.New("expired at " + expiry.Format(time.RFC3339))
+			}
+		case containsString(satisfiedKeyOptions, name):
+		default:
+			return errors.New("unsupported option " + option)
+		}
+	}
+
+	return nil
+}
+
+// parseExpiryTime parses the YYYYMMDD[HHMM[SS]] expiry-time format of
+// OpenSSH, in local time or in UTC with a Z suffix
+func parseExpiryTime(value string) (time.Time, error) {
+
+	location := time.Local
+	if strings.HasSuffix(value, "Z") || strings.HasSuffix(value, "z") {
+		location = time.UTC
+		value = value[:len(value)-1]
+	}
+
+	layouts := map[int]string{8: "20060102", 12: "200601021504", 14: "20060102150405"}
+
+	layout, ok := layouts[len(value)]
+	if !ok {
+		return time.Time{}, errors.New("invalid expiry-time " + value)
+	}
+
+	expiry, err := time.ParseInLocation(layout, value, location)
+	if err != nil {
+		return time.Time{}, errors.New("invalid expiry-time " + value)
+	}
+
+	return expiry, nil
+}
+
+// matchFromOption returns false when a from="pattern-list" option is present
+// and the remote address does not match it
+func matchFromOption(options []string, remoteAddress string) bool {
+
+	for _, option := range options {
+
+		if !strings.HasPrefix(strings.ToLower(option), "from=") {
+			continue
+		}
+
+		patterns := strings.Trim(option[len("from="):], "\"")
+		if !MatchAddressList(patterns, remoteAddress) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchAddressList matches an address against an OpenSSH comma separated
+// pattern list of hosts, wildcards and CIDR blocks. A negated pattern (!)
+// that matches rejects the address
+func MatchAddressList(patterns string, address string) bool {
+
+	ip := net.ParseIP(address)
+	matched := false
+
+	for _, pattern := range strings.Split(patterns, ",") {
+
+		pattern = strings.TrimSpace(pattern)
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		var match bool
+		if _, network, err := net.ParseCIDR(pattern); err == nil {
+			match = ip != nil && network.Contains(ip)
+		} else {
+			match = matchWildcard(pattern, address)
+		}
+
+		if match {
+			if negated {
+				return false
+			}
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+// matchWildcard implements the * and ? wildcards of OpenSSH patterns
+func matchWildcard(pattern string, s string) bool {
+
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := 0; i <= len(s); i++ {
+				if matchWildcard(pattern[1:], s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+
+	return len(s) == 0
+}
@@ -0,0 +1,211 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	fmt.Println("+++++ init publickey_test +++++")
+}
+
+func TestMatchWildcard(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		s       string
+		correct bool
+	}{
+		{"10.0.0.1", "10.0.0.1", true},
+		{"10.0.0.1", "10.0.0.10", false},
+		{"10.0.0.*", "10.0.0.10", true},
+		{"10.0.0.*", "10.0.1.10", false},
+		{"10.0.0.?", "10.0.0.7", true},
+		{"10.0.0.?", "10.0.0.17", false},
+		{"*", "", true},
+		{"?", "", false},
+		{"*.example.com", "host.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+	}
+
+	for _, test := range tests {
+		if result := matchWildcard(test.pattern, test.s); result != test.correct {
+			t.Errorf("Result was incorrect for %s %s, got: %v, want: %v.", test.pattern, test.s, result, test.correct)
+		}
+	}
+}
+
+func TestMatchAddressList(t *testing.T) {
+
+	tests := []struct {
+		patterns string
+		address  string
+		correct  bool
+	}{
+		{"10.0.0.1", "10.0.0.1", true},
+		{"10.0.0.1", "10.0.0.2", false},
+		{"10.0.0.0/24", "10.0.0.42", true},
+		{"10.0.0.0/24", "10.0.1.42", false},
+		{"2001:db8::/32", "2001:db8::1", true},
+		{"2001:db8::/32", "2001:db9::1", false},
+		{"10.0.0.0/24", "host.example.com", false},
+		{"192.168.1.1, 10.0.0.*", "10.0.0.5", true},
+		{"10.0.0.0/24,!10.0.0.5", "10.0.0.5", false},
+		{"!10.0.0.5,10.0.0.0/24", "10.0.0.6", true},
+		{"!10.0.0.0/8", "10.1.2.3", false},
+		{"!10.0.0.0/8", "192.168.1.1", false},
+		{"*,!10.*", "10.1.2.3", false},
+		{"*,!10.*", "192.168.1.1", true},
+		{"", "10.0.0.1", false},
+	}
+
+	for _, test := range tests {
+		if result := MatchAddressList(test.patterns, test.address); result != test.correct {
+			t.Errorf("Result was incorrect for %q %s, got: %v, want: %v.", test.patterns, test.address, result, test.correct)
+		}
+	}
+}
+
+func TestMatchFromOption(t *testing.T) {
+
+	tests := []struct {
+		options []string
+		address string
+		correct bool
+	}{
+		{nil, "10.0.0.1", true},
+		{[]string{"no-pty"}, "10.0.0.1", true},
+		{[]string{`from="10.0.0.0/24"`}, "10.0.0.1", true},
+		{[]string{`from="10.0.0.0/24"`}, "10.0.1.1", false},
+		{[]string{`FROM="10.0.0.*"`}, "10.0.0.1", true},
+		{[]string{`from="10.0.0.0/24,!10.0.0.1"`}, "10.0.0.1", false},
+		{[]string{`from="10.0.0.0/8"`, `from="10.0.0.0/24"`}, "10.1.0.1", false},
+	}
+
+	for _, test := range tests {
+		if result := matchFromOption(test.options, test.address); result != test.correct {
+			t.Errorf("Result was incorrect for %v %s, got: %v, want: %v.", test.options, test.address, result, test.correct)
+		}
+	}
+}
+
+func TestCheckKeyOptions(t *testing.T) {
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		options []string
+		correct bool
+	}{
+		{nil, true},
+		{[]string{"restrict"}, true},
+		{[]string{"no-port-forwarding", "no-agent-forwarding", "no-X11-forwarding", "no-pty", "no-user-rc"}, true},
+		{[]string{`permitopen="host:22"`, `environment="A=B"`}, true},
+		{[]string{`from="10.0.0.0/8"`}, true},
+		{[]string{`expiry-time="20261019Z"`}, true},
+		{[]string{`expiry-time="202610181201Z"`}, true},
+		{[]string{`expiry-time="20261018120000Z"`}, false},
+		{[]string{`expiry-time="20261017Z"`}, false},
+		{[]string{`expiry-time="2026101"`}, false},
+		{[]string{`expiry-time="20261318Z"`}, false},
+		{[]string{`command="/bin/true"`}, false},
+		{[]string{"restrict", `command="/bin/true"`}, false},
+		{[]string{`tunnel="0"`}, false},
+		{[]string{"cert-authority"}, false},
+		{[]string{`principals="alice"`}, false},
+		{[]string{"verify-required"}, false},
+		{[]string{"unknown-option"}, false},
+	}
+
+	for _, test := range tests {
+		if err := checkKeyOptions(test.options, now); (err == nil) != test.correct {
+			t.Errorf("Result was incorrect for %v, got: %v, want: %v.", test.options, err, test.correct)
+		}
+	}
+}
+
+func TestIsAuthorizedKey(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedDir := AuthorizedKeysDir
+	defer func() { AuthorizedKeysDir = savedDir }()
+	AuthorizedKeysDir = filepath.Join(dir, "keys")
+	os.Mkdir(AuthorizedKeysDir, 0755)
+
+	key := testSigner(t).PublicKey()
+	other := testSigner(t).PublicKey()
+	line := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key)))
+	expired := time.Now().Add(-time.Hour).UTC().Format("200601021504") + "Z"
+	valid := time.Now().Add(time.Hour).UTC().Format("200601021504") + "Z"
+
+	tests := []struct {
+		entries string
+		key     ssh.PublicKey
+		address string
+		correct bool
+	}{
+		{line, key, "10.0.0.1", true},
+		{line, other, "10.0.0.1", false},
+		{"# comment\n\n" + line + " alice@example", key, "10.0.0.1", true},
+		{`from="10.0.0.0/24" ` + line, key, "10.0.0.1", true},
+		{`from="10.0.0.0/24" ` + line, key, "10.0.1.1", false},
+		{`from="*,!10.0.0.1" ` + line, key, "10.0.0.1", false},
+		{`from="192.168.0.0/16" ` + line + "\n" + line, key, "10.0.0.1", true},
+		{"restrict,no-pty " + line, key, "10.0.0.1", true},
+		{`expiry-time="` + valid + `" ` + line, key, "10.0.0.1", true},
+		{`expiry-time="` + expired + `" ` + line, key, "10.0.0.1", false},
+		{`command="/bin/true" ` + line, key, "10.0.0.1", false},
+		{"cert-authority " + line, key, "10.0.0.1", false},
+	}
+
+	for _, test := range tests {
+
+		if err := ioutil.WriteFile(filepath.Join(AuthorizedKeysDir, "alice"), []byte(test.entries+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if result := IsAuthorizedKey("alice", test.key, test.address); result != test.correct {
+			t.Errorf("Result was incorrect for %q %s, got: %v, want: %v.", test.entries, test.address, result, test.correct)
+		}
+	}
+
+	// Usernames cannot leave the authorized keys directory
+	ioutil.WriteFile(filepath.Join(dir, "alice"), []byte(line+"\n"), 0644)
+
+	if IsAuthorizedKey("../alice", key, "10.0.0.1") {
+		t.Errorf("Result was incorrect, got: true, want: false.")
+	}
+}
@@ -0,0 +1,267 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"orange/sonic-netconf-server/radius"
+
+	"github.com/golang/glog"
+)
+
+// Prefix of the shell attributes in the Cisco-AVPair values
+const radiusShellPrefix = "shell:"
+
+// RadiusAuthenticator authenticates users with the RADIUS servers of
+// CONFIG_DB and accounts their sessions. The Access-Accept sets the
+// privilege level, roles and modules as the TACACS+ session authorization
+// does, commands are checked locally, RADIUS has no command authorization
+type RadiusAuthenticator struct {
+	pool          *radius.Pool
+	info          radius.RadiusInfo
+	context       context.Context
+	username      string
+	password      string
+	protocol      string
+	service       string
+	remoteAddress string
+
+	// Session authorization context, from the Access-Accept attributes
+	privLvl uint8
+	roles   []string
+	modules []string
+	policy  *RolePolicy
+	class   [][]byte
+
+	// Session accounting
+	mutex        sync.Mutex
+	sessionID    string
+	sessionStart time.Time
+}
+
+// Will use the server pool, requests go to the highest priority reachable server
+func NewRadiusAuthenticator(context context.Context, protocol string, service string, username string, password string, remoteAddress string) (*RadiusAuthenticator, error) {
+
+	pool, info, err := getRadiusPool()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newRadiusAuthenticator(context, pool, info, protocol, service, username, password, remoteAddress)
+}
+
+// newRadiusAuthenticator creates an authenticator sending its requests to
+// pool, info is the configuration of the preferred server
+func newRadiusAuthenticator(context context.Context, pool *radius.Pool, info radius.RadiusInfo, protocol string, service string, username string, password string, remoteAddress string) (*RadiusAuthenticator, error) {
+
+	if info.AuthType != "pap" && info.AuthType != "chap" {
+		return nil, errors.New("Unsupported RADIUS authentication type " + info.AuthType)
+	}
+
+	return &RadiusAuthenticator{
+		pool:          pool,
+		info:          info,
+		context:       context,
+		username:      username,
+		password:      password,
+		protocol:      protocol,
+		service:       service,
+		remoteAddress: remoteAddress,
+		privLvl:       TacacsPrivLvlUser,
+	}, nil
+}
+
+func (r *RadiusAuthenticator) Authenticate() bool {
+
+	request := r.newRequest(radius.CodeAccessRequest)
+	request.AddUint32(radius.AttrServiceType, radius.ServiceTypeLogin)
+
+	if r.info.AuthType == "chap" {
+		challenge := make([]byte, 17)
+		if _, err := rand.Read(challenge); err != nil {
+			glog.Errorf("[AAA] Unable to build the CHAP challenge of user %s: %v", r.username, err)
+			return false
+		}
+		request.Add(radius.AttrCHAPPassword, radius.CHAPPassword(challenge[0], []byte(r.password), challenge[1:]))
+		request.Add(radius.AttrCHAPChallenge, challenge[1:])
+	} else {
+		// Hidden by the client with the secret of each server
+		request.AddString(radius.AttrUserPassword, r.password)
+	}
+
+	response, err := r.pool.Authenticate(r.context, request)
+
+	if err != nil {
+		glog.Infof("RADIUS authentication of user %s failed: %v", r.username, err)
+		return false
+	}
+
+	switch response.Code {
+	case radius.CodeAccessAccept:
+	case radius.CodeAccessChallenge:
+		glog.Infof("RADIUS challenge not supported, user %s rejected", r.username)
+		return false
+	default:
+		glog.Infof("RADIUS authentication rejected user %s: %s", r.username, response.GetString(radius.AttrReplyMessage))
+		return false
+	}
+
+	r.setAttributes(response)
+
+	glog.Infof("Session authorized for user %s priv-lvl=%d roles=%v modules=%v", r.username, r.privLvl, r.roles, r.modules)
+
+	return true
+}
+
+// setAttributes maps the Service-Type and the shell Cisco-AVPair values of
+// an Access-Accept into the session authorization context
+func (r *RadiusAuthenticator) setAttributes(response *radius.Packet) {
+
+	if serviceType, ok := response.GetUint32(radius.AttrServiceType); ok {
+		switch serviceType {
+		case radius.ServiceTypeAdministrative:
+			r.privLvl = TacacsPrivLvlMax
+		case radius.ServiceTypeNASPrompt:
+			r.privLvl = TacacsPrivLvlUser
+		}
+	}
+
+	args := []string{}
+	for _, value := range response.Vendor(radius.VendorCisco, radius.CiscoAttrAVPair) {
+		if avPair := string(value); strings.HasPrefix(avPair, radiusShellPrefix) {
+			args = append(args, strings.TrimPrefix(avPair, radiusShellPrefix))
+		}
+	}
+
+	r.privLvl, r.roles, r.modules, r.policy = sessionAttributes(r.username, parseAVPairs(args), r.privLvl)
+
+	// Echoed in the accounting requests
+	r.class = nil
+	for _, attr := range response.Attributes {
+		if attr.Type == radius.AttrClass {
+			r.class = append(r.class, attr.Value)
+		}
+	}
+}
+
+// Authorize checks the modules and roles of the Access-Accept. Without role,
+// only administrators may write as NACM may be disabled
+func (r *RadiusAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+
+	if r.policy == nil && r.privLvl != TacacsPrivLvlMax && defaultOperationPermissions[cmd] == PermissionWrite {
+		glog.Infof("[ROLE] %s %s denied to user=%s priv-lvl=%d without role", cmd, cmdArgs, r.username, r.privLvl)
+		return false
+	}
+
+	return sessionAllows(r.username, r.roles, r.modules, r.policy, cmd, cmdArgs)
+}
+
+// Account does nothing, RADIUS accounts the sessions only
+func (r *RadiusAuthenticator) Account(cmd string, cmdArgs string) bool {
+	return true
+}
+
+// StartSession sends the accounting start of the session
+func (r *RadiusAuthenticator) StartSession() {
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	r.mutex.Lock()
+	r.sessionID = hex.EncodeToString(id)
+	r.sessionStart = time.Now()
+	r.mutex.Unlock()
+
+	r.sendAccounting(radius.AcctStatusStart)
+}
+
+// StopSession sends the accounting stop of the session
+func (r *RadiusAuthenticator) StopSession() {
+	r.sendAccounting(radius.AcctStatusStop)
+}
+
+// AccountCommand does nothing, RADIUS accounts the sessions only
+func (r *RadiusAuthenticator) AccountCommand(cmd string, start time.Time, err error) {
+}
+
+func (r *RadiusAuthenticator) sendAccounting(status uint32) bool {
+
+	r.mutex.Lock()
+	sessionID, sessionStart := r.sessionID, r.sessionStart
+	r.mutex.Unlock()
+
+	request := r.newRequest(radius.CodeAccountingRequest)
+	request.AddUint32(radius.AttrAcctStatusType, status)
+	request.AddString(radius.AttrAcctSessionID, sessionID)
+	request.AddUint32(radius.AttrAcctAuthentic, radius.AcctAuthenticRADIUS)
+
+	if status == radius.AcctStatusStop {
+		request.AddUint32(radius.AttrAcctSessionTime, uint32(time.Since(sessionStart)/time.Second))
+		request.AddUint32(radius.AttrAcctTerminateCause, radius.AcctTerminateUserRequest)
+	}
+
+	for _, class := range r.class {
+		request.Add(radius.AttrClass, class)
+	}
+
+	response, err := r.pool.Account(r.context, request)
+
+	if err != nil || response.Code != radius.CodeAccountingResponse {
+		glog.Warningf("Accounting record %d of user %s not accepted: %v", status, r.username, err)
+		return false
+	}
+
+	return true
+}
+
+// newRequest returns a request with the user and NAS attributes
+func (r *RadiusAuthenticator) newRequest(code uint8) *radius.Packet {
+
+	request := radius.NewPacket(code)
+	request.AddString(radius.AttrUserName, r.username)
+
+	if ip := net.ParseIP(r.info.NASIP).To4(); ip != nil {
+		request.Add(radius.AttrNASIPAddress, ip)
+	}
+
+	request.AddString(radius.AttrNASIdentifier, r.service)
+	request.AddUint32(radius.AttrNASPortType, radius.NASPortTypeVirtual)
+	request.AddString(radius.AttrCallingStationID, r.remoteAddress)
+
+	return request
+}
+
+func (r *RadiusAuthenticator) Username() string {
+	return r.username
+}
+
+// Groups returns the roles given by the Access-Accept
+func (r *RadiusAuthenticator) Groups() []string {
+	return r.roles
+}
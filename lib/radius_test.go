@@ -0,0 +1,228 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"orange/sonic-netconf-server/radius"
+	"orange/sonic-netconf-server/radius/radiustest"
+)
+
+func init() {
+	fmt.Println("+++++ init radius_test +++++")
+}
+
+func startRadiusServer(t *testing.T) *radiustest.Server {
+
+	server := radiustest.NewServer("testing123")
+	server.AddUser("admin", "adminpass").ServiceType = radius.ServiceTypeAdministrative
+	server.AddUser("operator", "oppass", "shell:netconf-role=operator", "shell:netconf-modules=sonic-vlan")
+	server.AddUser("viewer", "viewpass").ServiceType = radius.ServiceTypeNASPrompt
+
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return server
+}
+
+func testRadiusAuthenticator(t *testing.T, infos []radius.RadiusInfo, username string, password string) *RadiusAuthenticator {
+
+	authenticator, err := newRadiusAuthenticator(context.Background(), radius.NewPool(infos), infos[0], "ssh", "netconf", username, password, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return authenticator
+}
+
+func TestRadiusAuthentication(t *testing.T) {
+
+	server := startRadiusServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		authType string
+		username string
+		password string
+		pass     bool
+	}{
+		{"pap", "admin", "adminpass", true},
+		{"pap", "admin", "wrong", false},
+		{"pap", "nobody", "adminpass", false},
+		{"chap", "operator", "oppass", true},
+		{"chap", "operator", "wrong", false},
+	}
+
+	for _, test := range tests {
+		authenticator := testRadiusAuthenticator(t, []radius.RadiusInfo{server.Info(test.authType)}, test.username, test.password)
+		if authenticator.Authenticate() != test.pass {
+			t.Errorf("%s login of %s/%s: expected %v", test.authType, test.username, test.password, test.pass)
+		}
+	}
+
+	if _, err := newRadiusAuthenticator(context.Background(), nil, server.Info("mschapv2"), "ssh", "netconf", "admin", "adminpass", ""); err == nil {
+		t.Error("Unsupported authentication type accepted")
+	}
+}
+
+func TestRadiusAttributes(t *testing.T) {
+
+	server := startRadiusServer(t)
+	defer server.Close()
+
+	restore := setRolePolicy(t, testOperatorPolicy)
+	defer restore()
+
+	admin := testRadiusAuthenticator(t, []radius.RadiusInfo{server.Info("pap")}, "admin", "adminpass")
+	if !admin.Authenticate() {
+		t.Fatal("admin rejected")
+	}
+	if admin.privLvl != TacacsPrivLvlMax || strings.Join(admin.Groups(), ",") != TacacsAdminGroup {
+		t.Errorf("admin: priv-lvl %d groups %v", admin.privLvl, admin.Groups())
+	}
+
+	operator := testRadiusAuthenticator(t, []radius.RadiusInfo{server.Info("pap")}, "operator", "oppass")
+	if !operator.Authenticate() {
+		t.Fatal("operator rejected")
+	}
+	if operator.privLvl != TacacsPrivLvlUser || strings.Join(operator.Groups(), ",") != "operator" {
+		t.Errorf("operator: priv-lvl %d groups %v", operator.privLvl, operator.Groups())
+	}
+
+	if !operator.Authorize("get", "/sonic-vlan:sonic-vlan") || operator.Authorize("get", "/sonic-port:sonic-port") {
+		t.Error("netconf-modules of the Access-Accept not applied")
+	}
+}
+
+func TestRadiusAuthorize(t *testing.T) {
+
+	server := startRadiusServer(t)
+	defer server.Close()
+
+	restore := setRolePolicy(t, testOperatorPolicy)
+	defer restore()
+
+	tests := []struct {
+		username string
+		password string
+		cmd      string
+		allowed  bool
+	}{
+		{"admin", "adminpass", "edit-data", true},
+		{"viewer", "viewpass", "get", true},
+		{"viewer", "viewpass", "get-config", true},
+		{"viewer", "viewpass", "edit-data", false},
+		{"viewer", "viewpass", "copy-config", false},
+		{"viewer", "viewpass", "delete-config", false},
+		{"operator", "oppass", "edit-data", true},
+	}
+
+	for _, test := range tests {
+		authenticator := testRadiusAuthenticator(t, []radius.RadiusInfo{server.Info("pap")}, test.username, test.password)
+		if !authenticator.Authenticate() {
+			t.Fatalf("%s rejected", test.username)
+		}
+		if authenticator.Authorize(test.cmd, "/sonic-vlan:sonic-vlan") != test.allowed {
+			t.Errorf("%s of %s: expected %v", test.cmd, test.username, test.allowed)
+		}
+	}
+}
+
+func TestRadiusMessageAuthenticator(t *testing.T) {
+
+	server := startRadiusServer(t)
+	defer server.Close()
+
+	server.NoMessageAuthenticator = true
+
+	authenticator := testRadiusAuthenticator(t, []radius.RadiusInfo{server.Info("pap")}, "admin", "adminpass")
+	if authenticator.Authenticate() {
+		t.Error("Access-Accept without Message-Authenticator accepted")
+	}
+
+	if server.Requests() == 0 {
+		t.Error("No request sent")
+	}
+}
+
+func TestRadiusFailover(t *testing.T) {
+
+	down := radiustest.NewServer("testing123")
+	down.Drop = true
+	if err := down.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer down.Close()
+
+	up := startRadiusServer(t)
+	defer up.Close()
+
+	infos := []radius.RadiusInfo{down.Info("pap"), up.Info("pap")}
+	pool := radius.NewPool(infos)
+
+	for i := 0; i < 2; i++ {
+		authenticator, _ := newRadiusAuthenticator(context.Background(), pool, infos[0], "ssh", "netconf", "admin", "adminpass", "")
+		if !authenticator.Authenticate() {
+			t.Fatalf("Attempt %d: no failover to the second server", i)
+		}
+	}
+
+	// The server that did not answer is skipped until the retry interval elapsed
+	if down.Requests() != 1 {
+		t.Errorf("Dead server received %d requests", down.Requests())
+	}
+}
+
+func TestRadiusAccounting(t *testing.T) {
+
+	server := startRadiusServer(t)
+	defer server.Close()
+
+	admin := testRadiusAuthenticator(t, []radius.RadiusInfo{server.Info("pap")}, "admin", "adminpass")
+	admin.StartSession()
+	admin.StopSession()
+
+	records := server.Accounting()
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 accounting records, got %d", len(records))
+	}
+
+	for i, status := range []uint32{radius.AcctStatusStart, radius.AcctStatusStop} {
+		if value, _ := records[i].GetUint32(radius.AttrAcctStatusType); value != status {
+			t.Errorf("Record %d: status %d, expected %d", i, value, status)
+		}
+		if records[i].GetString(radius.AttrUserName) != "admin" {
+			t.Errorf("Record %d: user %s", i, records[i].GetString(radius.AttrUserName))
+		}
+	}
+
+	if start, stop := records[0].GetString(radius.AttrAcctSessionID), records[1].GetString(radius.AttrAcctSessionID); start == "" || start != stop {
+		t.Errorf("Session ids differ: %q %q", start, stop)
+	}
+
+	if _, ok := records[1].GetUint32(radius.AttrAcctSessionTime); !ok {
+		t.Error("No session time in the stop record")
+	}
+}
@@ -0,0 +1,219 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// Group based permissions of local users, role based authorization is
+// enabled when the file exists. Example:
+//
+//	{
+//	    "groups": {
+//	        "admin":    {"default": "rwx"},
+//	        "netadmin": {"default": "rw", "modules": {"sonic-system-": "r"}},
+//	        "operator": {"default": "r"}
+//	    },
+//	    "operations": {"copy-config": "x"}
+//	}
+var RolePolicyPath = "/etc/sonic/netconf/roles.json"
+
+// Permissions, a combination of these letters
+const (
+	PermissionRead  = "r"
+	PermissionWrite = "w"
+	PermissionExec  = "x"
+)
+
+// Permission needed by the operations, others need exec
+var defaultOperationPermissions = map[string]string{
+	"get":           PermissionRead,
+	"get-config":    PermissionRead,
+	"get-data":      PermissionRead,
+	"edit-data":     PermissionWrite,
+	"copy-config":   PermissionWrite,
+	"delete-config": PermissionWrite,
+}
+
+type RolePermissions struct {
+	Default string            `json:"default"`
+	Modules map[string]string `json:"modules"` // YANG module name prefix -> permissions
+}
+
+type RolePolicy struct {
+	Groups     map[string]RolePermissions `json:"groups"`
+	Operations map[string]string          `json:"operations"` // Operation -> needed permission
+	Default    string                     `json:"default"`    // Permissions of users in no listed group
+}
+
+// LoadRolePolicy reads the role policy, nil when role based authorization is disabled
+func LoadRolePolicy() (*RolePolicy, error) {
+
+	data, err := ioutil.ReadFile(RolePolicyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &RolePolicy{}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// permissions returns the permissions of a set of groups on a module, the
+// longest matching module prefix of a group overrides its default
+func (r *RolePolicy) permissions(groups []string, module string) string {
+
+	permissions := ""
+	listed := false
+
+	for _, group := range groups {
+
+		role, ok := r.Groups[group]
+		if !ok {
+			continue
+		}
+		listed = true
+
+		groupPermissions, prefixLength := role.Default, -1
+		for prefix, modulePermissions := range role.Modules {
+			if strings.HasPrefix(module, prefix) && len(prefix) > prefixLength {
+				groupPermissions, prefixLength = modulePermissions, len(prefix)
+			}
+		}
+
+		permissions += groupPermissions
+	}
+
+	if !listed {
+		return r.Default
+	}
+
+	return permissions
+}
+
+// needed returns the permission an operation requires
+func (r *RolePolicy) needed(cmd string) string {
+
+	if permission, ok := r.Operations[cmd]; ok {
+		return permission
+	}
+
+	if permission, ok := defaultOperationPermissions[cmd]; ok {
+		return permission
+	}
+
+	return PermissionExec
+}
+
+// allows checks whether a set of groups has the permission an operation on a
+// module requires, an operation needing an unknown permission is denied
+func (r *RolePolicy) allows(groups []string, cmd string, module string) bool {
+
+	needed := r.needed(cmd)
+
+	switch needed {
+	case PermissionRead, PermissionWrite, PermissionExec:
+		return strings.Contains(r.permissions(groups, module), needed)
+	}
+
+	glog.Warningf("[ROLE] Invalid permission %q needed by %s", needed, cmd)
+
+	return false
+}
+
+// commandModule returns the module of the path of data operations
+func commandModule(cmdArgs string) string {
+
+	if strings.HasPrefix(cmdArgs, "/") {
+		if i := strings.Index(cmdArgs, ":"); i > 0 {
+			return cmdArgs[1:i]
+		}
+	}
+
+	return ""
+}
+
+// hasModulePrefix returns true when a module name starts with one of the prefixes
+func hasModulePrefix(prefixes []string, module string) bool {
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(module, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RoleAuthenticator authorizes the operations of a local user from its groups
+type RoleAuthenticator struct {
+	*PAMAuthenticator
+	policy *RolePolicy
+}
+
+func NewRoleAuthenticator(pamAuthenticator *PAMAuthenticator, policy *RolePolicy) *RoleAuthenticator {
+	return &RoleAuthenticator{
+		PAMAuthenticator: pamAuthenticator,
+		policy:           policy,
+	}
+}
+
+// Authorize checks the permission of an operation, the module is read from
+// the path of data operations
+func (r *RoleAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+
+	module := commandModule(cmdArgs)
+
+	if !r.policy.allows(r.Groups(), cmd, module) {
+		glog.Infof("[ROLE] %s %s denied to user=%s groups=%v permissions=%q", cmd, cmdArgs, r.Username(), r.Groups(), r.policy.permissions(r.Groups(), module))
+		return false
+	}
+
+	return true
+}
+
+// localAuthenticator adds role based authorization to a local login when a policy is set
+func localAuthenticator(pamAuthenticator *PAMAuthenticator) Authenticator {
+
+	policy, err := LoadRolePolicy()
+	if err != nil {
+		// Fail closed, nothing is permitted
+		glog.Errorf("[ROLE] Unable to read %s: %v", RolePolicyPath, err)
+		policy = &RolePolicy{}
+	}
+
+	if policy == nil {
+		return pamAuthenticator
+	}
+
+	return NewRoleAuthenticator(pamAuthenticator, policy)
+}
@@ -0,0 +1,232 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	fmt.Println("+++++ init role_test +++++")
+}
+
+const testRolePolicy = `{
+    "groups": {
+        "admin":    {"default": "rwx"},
+        "netadmin": {"default": "rw", "modules": {"sonic-system-": "r", "sonic-system-aaa": "", "sonic-vlan": "rwx"}},
+        "operator": {"default": "r"}
+    },
+    "operations": {"copy-config": "x", "get-schema": "", "reboot": "rx", "restart": "z"},
+    "default": "r"
+}`
+
+// setRolePolicy writes a role policy file, none when data is empty, the path
+// is restored by the returned function
+func setRolePolicy(t *testing.T, data string) func() {
+
+	dir, err := ioutil.TempDir("", "role")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedPath := RolePolicyPath
+	RolePolicyPath = filepath.Join(dir, "roles.json")
+
+	if data != "" {
+		ioutil.WriteFile(RolePolicyPath, []byte(data), 0644)
+	}
+
+	return func() {
+		RolePolicyPath = savedPath
+		os.RemoveAll(dir)
+	}
+}
+
+func testPolicy(t *testing.T) *RolePolicy {
+
+	policy := &RolePolicy{}
+	if err := json.Unmarshal([]byte(testRolePolicy), policy); err != nil {
+		t.Fatal(err)
+	}
+
+	return policy
+}
+
+func TestRolePermissions(t *testing.T) {
+
+	policy := testPolicy(t)
+
+	tests := []struct {
+		groups      []string
+		module      string
+		permissions string
+	}{
+		{[]string{"admin"}, "sonic-port", "rwx"},
+		{[]string{"netadmin"}, "sonic-port", "rw"},
+		{[]string{"netadmin"}, "sonic-system-tacacs", "r"},
+		{[]string{"netadmin"}, "sonic-system-aaa", ""},
+		{[]string{"netadmin"}, "sonic-vlan", "rwx"},
+		{[]string{"netadmin"}, "", "rw"},
+		{[]string{"operator", "netadmin"}, "sonic-system-aaa", "r"},
+		{[]string{"users"}, "sonic-port", "r"},
+		{nil, "sonic-port", "r"},
+	}
+
+	for _, test := range tests {
+		if permissions := policy.permissions(test.groups, test.module); permissions != test.permissions {
+			t.Errorf("%v on %q: got %q, want: %q", test.groups, test.module, permissions, test.permissions)
+		}
+	}
+}
+
+func TestRoleNeeded(t *testing.T) {
+
+	policy := testPolicy(t)
+
+	tests := map[string]string{
+		"get":           PermissionRead,
+		"get-config":    PermissionRead,
+		"edit-data":     PermissionWrite,
+		"delete-config": PermissionWrite,
+		"copy-config":   PermissionExec, // Overridden by the policy
+		"get-schema":    "",
+		"lock":          PermissionExec,
+	}
+
+	for cmd, needed := range tests {
+		if permission := policy.needed(cmd); permission != needed {
+			t.Errorf("%s: got %q, want: %q", cmd, permission, needed)
+		}
+	}
+}
+
+func TestRoleAuthorize(t *testing.T) {
+
+	policy := testPolicy(t)
+
+	tests := []struct {
+		groups []string
+		cmd    string
+		args   string
+		pass   bool
+	}{
+		{[]string{"operator"}, "get", "/sonic-port:sonic-port", true},
+		{[]string{"operator"}, "edit-data", "/sonic-port:sonic-port", false},
+		{[]string{"operator"}, "lock", "running", false},
+		{[]string{"netadmin"}, "edit-data", "/sonic-port:sonic-port/PORT", true},
+		{[]string{"netadmin"}, "edit-data", "/sonic-system-tacacs:sonic-system-tacacs", false},
+		{[]string{"netadmin"}, "get", "/sonic-system-aaa:sonic-system-aaa", false},
+		{[]string{"netadmin"}, "copy-config", "running startup", false},
+		{[]string{"admin"}, "copy-config", "running startup", true},
+		{[]string{"admin"}, "get", "/sonic-port:sonic-port", true},
+		// Empty, combined and unknown permissions deny even to admins
+		{[]string{"admin"}, "get-schema", "", false},
+		{[]string{"admin"}, "reboot", "", false},
+		{[]string{"admin"}, "restart", "", false},
+		{nil, "get", "/sonic-port:sonic-port", true},
+		{nil, "edit-data", "/sonic-port:sonic-port", false},
+	}
+
+	for _, test := range tests {
+		authenticator := NewRoleAuthenticator(&PAMAuthenticator{username: "user", groups: test.groups}, policy)
+		if authenticator.Authorize(test.cmd, test.args) != test.pass {
+			t.Errorf("%v %s %s: expected %v", test.groups, test.cmd, test.args, test.pass)
+		}
+	}
+
+	// A policy that cannot be read permits nothing
+	closed := NewRoleAuthenticator(&PAMAuthenticator{username: "user", groups: []string{"admin"}}, &RolePolicy{})
+	if closed.Authorize("get", "/sonic-port:sonic-port") {
+		t.Error("empty policy permitted get")
+	}
+}
+
+func TestCommandModule(t *testing.T) {
+
+	tests := map[string]string{
+		"/sonic-vlan:sonic-vlan/VLAN": "sonic-vlan",
+		"/sonic-vlan:sonic-vlan":      "sonic-vlan",
+		"running startup":             "",
+		"/no-prefix":                  "",
+		"":                            "",
+	}
+
+	for args, module := range tests {
+		if result := commandModule(args); result != module {
+			t.Errorf("%q: got %q, want: %q", args, result, module)
+		}
+	}
+
+	if !hasModulePrefix([]string{"sonic-port", "sonic-vlan"}, "sonic-vlan-member") || hasModulePrefix([]string{"sonic-port"}, "sonic-vlan") || hasModulePrefix(nil, "sonic-vlan") {
+		t.Error("hasModulePrefix result was incorrect")
+	}
+}
+
+func TestSessionPolicy(t *testing.T) {
+
+	roles := map[string][]string{TacacsAttrRole: {"operator"}}
+
+	tests := []struct {
+		name   string
+		policy string
+		get    bool
+		edit   bool
+	}{
+		{"defined role", `{"groups": {"operator": {"default": "r"}}}`, true, false},
+		{"undefined role ignores the default", `{"groups": {"admin": {"default": "rwx"}}, "default": "rwx"}`, false, false},
+		{"missing policy", "", false, false},
+		{"invalid policy", "{", false, false},
+	}
+
+	for _, test := range tests {
+
+		restore := setRolePolicy(t, test.policy)
+		_, sessionRoles, _, policy := sessionAttributes("user", roles, TacacsPrivLvlUser)
+		restore()
+
+		get := sessionAllows("user", sessionRoles, nil, policy, "get", "/sonic-port:sonic-port")
+		edit := sessionAllows("user", sessionRoles, nil, policy, "edit-data", "/sonic-port:sonic-port")
+		if get != test.get || edit != test.edit {
+			t.Errorf("%s: got get %v edit %v, want: %v %v", test.name, get, edit, test.get, test.edit)
+		}
+	}
+
+	// The policy is read once per session
+	restore := setRolePolicy(t, `{"groups": {"operator": {"default": "r"}}}`)
+	defer restore()
+
+	_, sessionRoles, _, policy := sessionAttributes("user", roles, TacacsPrivLvlUser)
+	ioutil.WriteFile(RolePolicyPath, []byte(`{"groups": {"operator": {"default": "rw"}}}`), 0644)
+
+	if !sessionAllows("user", sessionRoles, nil, policy, "get", "/sonic-port:sonic-port") || sessionAllows("user", sessionRoles, nil, policy, "edit-data", "/sonic-port:sonic-port") {
+		t.Error("Policy of the session changed")
+	}
+
+	// Administrators without role are not restricted by the policy
+	_, adminRoles, _, adminPolicy := sessionAttributes("admin", map[string][]string{TacacsAttrPrivLvl: {"15"}}, TacacsPrivLvlUser)
+	if adminPolicy != nil || !sessionAllows("admin", adminRoles, nil, adminPolicy, "edit-data", "/sonic-port:sonic-port") {
+		t.Error("Administrator restricted by the role policy")
+	}
+}
@@ -19,8 +19,23 @@
 
 package lib
 
+import "time"
+
 type Authenticator interface {
 	Authenticate() bool
 	Authorize(cmd string, cmdArgs string) bool
 	Account(cmd string, cmdArgs string) bool
 }
+
+// Authenticators knowing the user and its groups, used by access control
+type Identity interface {
+	Username() string
+	Groups() []string
+}
+
+// Authenticators accounting the session lifetime and each completed command
+type SessionAccounter interface {
+	StartSession()
+	StopSession()
+	AccountCommand(cmd string, start time.Time, err error)
+}
@@ -0,0 +1,247 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSH algorithm presets
+const (
+	SSHPolicyModern = "modern" // No SHA-1 and no CBC
+	SSHPolicyFIPS   = "fips"   // FIPS 140 approved algorithms only
+	SSHPolicyCompat = "compat" // golang.org/x/crypto defaults, SHA-1 included
+)
+
+// Preset of the SSH algorithms
+var SSHPolicy = SSHPolicyModern
+
+// Comma separated algorithms in preference order, overriding those of the
+// preset when set
+var (
+	SSHKeyExchanges      = ""
+	SSHCiphers           = ""
+	SSHMACs              = ""
+	SSHHostKeyAlgorithms = ""
+)
+
+// Bytes sent or received before the keys are renegotiated, 0 uses the
+// golang.org/x/crypto default
+var SSHRekeyThreshold uint64 = 0
+
+// RSA host key algorithms signing with SHA-2 (RFC 8332)
+const (
+	hostKeyAlgoRSASHA256 = ssh.SigAlgoRSASHA2256
+	hostKeyAlgoRSASHA512 = ssh.SigAlgoRSASHA2512
+)
+
+// SSHAlgorithms are the algorithms offered by the server, in preference order
+type SSHAlgorithms struct {
+	KeyExchanges      []string
+	Ciphers           []string
+	MACs              []string
+	HostKeyAlgorithms []string
+	RekeyThreshold    uint64
+	// Types of the client public keys, nil accepts every supported type.
+	// golang.org/x/crypto verifies RSA user signatures in ssh-rsa (SHA-1)
+	// format only, RSA keys are refused when it is not allowed
+	PublicKeyAlgorithms []string
+}
+
+var sshPresets = map[string]SSHAlgorithms{
+	SSHPolicyModern: {
+		KeyExchanges:      []string{"curve25519-sha256@libssh.org", "ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521"},
+		Ciphers:           []string{"aes128-gcm@openssh.com", "chacha20-poly1305@openssh.com", "aes256-ctr", "aes192-ctr", "aes128-ctr"},
+		MACs:              []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256"},
+		HostKeyAlgorithms: []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, hostKeyAlgoRSASHA512, hostKeyAlgoRSASHA256},
+		PublicKeyAlgorithms: []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+			ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256, ssh.CertAlgoED25519v01, ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01,
+			ssh.CertAlgoECDSA521v01, ssh.CertAlgoSKED25519v01, ssh.CertAlgoSKECDSA256v01},
+	},
+	SSHPolicyFIPS: {
+		KeyExchanges:      []string{"ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521"},
+		Ciphers:           []string{"aes128-gcm@openssh.com", "aes256-ctr", "aes192-ctr", "aes128-ctr"},
+		MACs:              []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256"},
+		HostKeyAlgorithms: []string{ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, hostKeyAlgoRSASHA512, hostKeyAlgoRSASHA256},
+		PublicKeyAlgorithms: []string{ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+			ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01},
+	},
+	SSHPolicyCompat: {
+		HostKeyAlgorithms: []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521, hostKeyAlgoRSASHA512, hostKeyAlgoRSASHA256, ssh.KeyAlgoRSA},
+	},
+}
+
+// Algorithms implemented by golang.org/x/crypto on the server side
+var (
+	supportedKeyExchanges = []string{"curve25519-sha256@libssh.org", "ecdh-sha2-nistp256", "ecdh-sha2-nistp384", "ecdh-sha2-nistp521",
+		"diffie-hellman-group14-sha1", "diffie-hellman-group1-sha1"}
+	supportedCiphers = []string{"aes128-gcm@openssh.com", "chacha20-poly1305@openssh.com", "aes128-ctr", "aes192-ctr", "aes256-ctr",
+		"arcfour256", "arcfour128", "arcfour", "aes128-cbc", "3des-cbc"}
+	supportedMACs              = []string{"hmac-sha2-256-etm@openssh.com", "hmac-sha2-256", "hmac-sha1", "hmac-sha1-96"}
+	supportedHostKeyAlgorithms = []string{ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+		hostKeyAlgoRSASHA512, hostKeyAlgoRSASHA256, ssh.KeyAlgoRSA}
+)
+
+// LoadSSHAlgorithms returns the algorithms of the preset narrowed by the
+// configured lists
+func LoadSSHAlgorithms() (*SSHAlgorithms, error) {
+
+	preset, ok := sshPresets[SSHPolicy]
+	if !ok {
+		return nil, errors.New("Unknown SSH policy " + SSHPolicy)
+	}
+
+	algorithms := preset
+	algorithms.RekeyThreshold = SSHRekeyThreshold
+
+	var err error
+
+	if algorithms.KeyExchanges, err = algorithmList("key exchange", SSHKeyExchanges, preset.KeyExchanges, supportedKeyExchanges); err != nil {
+		return nil, err
+	}
+
+	if algorithms.Ciphers, err = algorithmList("cipher", SSHCiphers, preset.Ciphers, supportedCiphers); err != nil {
+		return nil, err
+	}
+
+	if algorithms.MACs, err = algorithmList("MAC", SSHMACs, preset.MACs, supportedMACs); err != nil {
+		return nil, err
+	}
+
+	if algorithms.HostKeyAlgorithms, err = algorithmList("host key algorithm", SSHHostKeyAlgorithms, preset.HostKeyAlgorithms, supportedHostKeyAlgorithms); err != nil {
+		return nil, err
+	}
+
+	glog.Infof("[SSH] Policy %s kex=%v ciphers=%v macs=%v hostkeys=%v pubkeys=%v", SSHPolicy, algorithms.KeyExchanges, algorithms.Ciphers, algorithms.MACs, algorithms.HostKeyAlgorithms, algorithms.PublicKeyAlgorithms)
+
+	return &algorithms, nil
+}
+
+// algorithmList parses a configured list, the preset is used when it is empty
+func algorithmList(kind string, configured string, preset []string, supported []string) ([]string, error) {
+
+	if strings.TrimSpace(configured) == "" {
+		return preset, nil
+	}
+
+	list := []string{}
+
+	for _, algorithm := range strings.Split(configured, ",") {
+
+		algorithm = strings.TrimSpace(algorithm)
+
+		if !containsString(supported, algorithm) {
+			return nil, fmt.Errorf("Unsupported SSH %s %s", kind, algorithm)
+		}
+
+		list = append(list, algorithm)
+	}
+
+	return list, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyTo sets the algorithms and the rekey threshold of a server configuration,
+// nil lists keep the golang.org/x/crypto defaults
+func (a *SSHAlgorithms) ApplyTo(config *ssh.ServerConfig) {
+	config.KeyExchanges = a.KeyExchanges
+	config.Ciphers = a.Ciphers
+	config.MACs = a.MACs
+	config.RekeyThreshold = a.RekeyThreshold
+}
+
+// CheckPublicKey refuses the client keys of a type the policy does not allow
+func (a *SSHAlgorithms) CheckPublicKey(key ssh.PublicKey) error {
+
+	if a.PublicKeyAlgorithms != nil && !containsString(a.PublicKeyAlgorithms, key.Type()) {
+		return fmt.Errorf("SSH public key type %s not allowed by policy %s", key.Type(), SSHPolicy)
+	}
+
+	return nil
+}
+
+// HostKeys returns the host keys offered with the allowed algorithms, in
+// preference order. RSA keys are offered once per allowed RSA algorithm
+func (a *SSHAlgorithms) HostKeys(signers []ssh.Signer) ([]ssh.Signer, error) {
+
+	hostKeys := []ssh.Signer{}
+
+	for _, algorithm := range a.HostKeyAlgorithms {
+		for _, signer := range signers {
+
+			keyType := signer.PublicKey().Type()
+
+			switch {
+			case keyType == algorithm:
+				hostKeys = append(hostKeys, signer)
+
+			case keyType == ssh.KeyAlgoRSA && (algorithm == hostKeyAlgoRSASHA256 || algorithm == hostKeyAlgoRSASHA512):
+				if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok {
+					hostKeys = append(hostKeys, &rsaSHA2Signer{AlgorithmSigner: algorithmSigner, algorithm: algorithm})
+				}
+			}
+		}
+	}
+
+	if len(hostKeys) == 0 {
+		return nil, errors.New("No host key matches the allowed host key algorithms")
+	}
+
+	return hostKeys, nil
+}
+
+// rsaSHA2Signer offers an RSA host key under an RFC 8332 algorithm, the key
+// exchange negotiates host keys by the type of their public key
+type rsaSHA2Signer struct {
+	ssh.AlgorithmSigner
+	algorithm string
+}
+
+func (s *rsaSHA2Signer) PublicKey() ssh.PublicKey {
+	return &rsaSHA2PublicKey{PublicKey: s.AlgorithmSigner.PublicKey(), algorithm: s.algorithm}
+}
+
+func (s *rsaSHA2Signer) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return s.AlgorithmSigner.SignWithAlgorithm(rand, data, s.algorithm)
+}
+
+// rsaSHA2PublicKey keeps the ssh-rsa encoding of the key
+type rsaSHA2PublicKey struct {
+	ssh.PublicKey
+	algorithm string
+}
+
+func (k *rsaSHA2PublicKey) Type() string {
+	return k.algorithm
+}
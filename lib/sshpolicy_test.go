@@ -0,0 +1,295 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func init() {
+	fmt.Println("+++++ init sshpolicy_test +++++")
+}
+
+func setSSHPolicy(policy string, kex string, ciphers string, macs string, hostKeyAlgorithms string) func() {
+
+	saved := []string{SSHPolicy, SSHKeyExchanges, SSHCiphers, SSHMACs, SSHHostKeyAlgorithms}
+
+	SSHPolicy, SSHKeyExchanges, SSHCiphers, SSHMACs, SSHHostKeyAlgorithms = policy, kex, ciphers, macs, hostKeyAlgorithms
+
+	return func() {
+		SSHPolicy, SSHKeyExchanges, SSHCiphers, SSHMACs, SSHHostKeyAlgorithms = saved[0], saved[1], saved[2], saved[3], saved[4]
+	}
+}
+
+func testHostSigners(t *testing.T) []ssh.Signer {
+
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signers := []ssh.Signer{}
+	for _, key := range []interface{}{ed25519Key, rsaKey} {
+		signer, err := ssh.NewSignerFromKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, signer)
+	}
+
+	return signers
+}
+
+func TestSSHPresets(t *testing.T) {
+
+	for _, policy := range []string{SSHPolicyModern, SSHPolicyFIPS} {
+
+		restore := setSSHPolicy(policy, "", "", "", "")
+		algorithms, err := LoadSSHAlgorithms()
+		restore()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		all := [][]string{algorithms.KeyExchanges, algorithms.Ciphers, algorithms.MACs, algorithms.HostKeyAlgorithms}
+		for _, list := range all {
+			for _, algorithm := range list {
+				if strings.Contains(algorithm, "sha1") || strings.Contains(algorithm, "cbc") || algorithm == ssh.KeyAlgoRSA {
+					t.Errorf("%s policy allows %s", policy, algorithm)
+				}
+			}
+		}
+	}
+
+	restore := setSSHPolicy(SSHPolicyFIPS, "", "", "", "")
+	defer restore()
+
+	algorithms, _ := LoadSSHAlgorithms()
+	for _, algorithm := range append(algorithms.KeyExchanges, algorithms.Ciphers...) {
+		if strings.Contains(algorithm, "curve25519") || strings.Contains(algorithm, "chacha20") {
+			t.Errorf("fips policy allows %s", algorithm)
+		}
+	}
+}
+
+func TestSSHPolicyOverrides(t *testing.T) {
+
+	restore := setSSHPolicy(SSHPolicyModern, "ecdh-sha2-nistp384, ecdh-sha2-nistp256", "", "hmac-sha2-256", "")
+	algorithms, err := LoadSSHAlgorithms()
+	restore()
+
+	if err != nil || strings.Join(algorithms.KeyExchanges, ",") != "ecdh-sha2-nistp384,ecdh-sha2-nistp256" ||
+		strings.Join(algorithms.MACs, ",") != "hmac-sha2-256" || len(algorithms.Ciphers) == 0 {
+		t.Errorf("Overrides not applied: %+v %v", algorithms, err)
+	}
+
+	for _, config := range [][]string{
+		{"unknown", "", "", "", ""},
+		{SSHPolicyModern, "diffie-hellman-group-exchange-sha256", "", "", ""},
+		{SSHPolicyModern, "", "aes256-gcm@openssh.com", "", ""},
+		{SSHPolicyModern, "", "", "hmac-md5", ""},
+		{SSHPolicyModern, "", "", "", "ssh-dss"},
+	} {
+		restore := setSSHPolicy(config[0], config[1], config[2], config[3], config[4])
+		if _, err := LoadSSHAlgorithms(); err == nil {
+			t.Errorf("Configuration %q accepted", config)
+		}
+		restore()
+	}
+}
+
+func TestSSHHostKeys(t *testing.T) {
+
+	signers := testHostSigners(t)
+
+	tests := []struct {
+		policy   string
+		expected string
+	}{
+		{SSHPolicyModern, "ssh-ed25519,rsa-sha2-512,rsa-sha2-256"},
+		{SSHPolicyFIPS, "rsa-sha2-512,rsa-sha2-256"},
+		{SSHPolicyCompat, "ssh-ed25519,rsa-sha2-512,rsa-sha2-256,ssh-rsa"},
+	}
+
+	for _, test := range tests {
+
+		restore := setSSHPolicy(test.policy, "", "", "", "")
+		algorithms, _ := LoadSSHAlgorithms()
+		restore()
+
+		hostKeys, err := algorithms.HostKeys(signers)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		types := []string{}
+		for _, hostKey := range hostKeys {
+			types = append(types, hostKey.PublicKey().Type())
+		}
+
+		if strings.Join(types, ",") != test.expected {
+			t.Errorf("%s policy host keys %v, expected %s", test.policy, types, test.expected)
+		}
+	}
+
+	restore := setSSHPolicy(SSHPolicyModern, "", "", "", "ecdsa-sha2-nistp256")
+	defer restore()
+
+	algorithms, _ := LoadSSHAlgorithms()
+	if _, err := algorithms.HostKeys(signers); err == nil {
+		t.Error("No host key left without error")
+	}
+}
+
+// handshake connects a client to a server configured with the policy, the
+// client public keys are checked when it has authentication methods
+func handshake(t *testing.T, algorithms *SSHAlgorithms, clientConfig *ssh.ClientConfig) error {
+
+	hostKeys, err := algorithms.HostKeys(testHostSigners(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serverConfig := &ssh.ServerConfig{
+		NoClientAuth: len(clientConfig.Auth) == 0,
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return nil, algorithms.CheckPublicKey(key)
+		},
+	}
+	algorithms.ApplyTo(serverConfig)
+	for _, hostKey := range hostKeys {
+		serverConfig.AddHostKey(hostKey)
+	}
+
+	// Both sides send their version first, a synchronous net.Pipe would block
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		serverConn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer serverConn.Close()
+		if conn, _, _, err := ssh.NewServerConn(serverConn, serverConfig); err == nil {
+			conn.Wait()
+		}
+	}()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+
+	clientConfig.User = "test"
+	clientConfig.HostKeyCallback = ssh.InsecureIgnoreHostKey()
+
+	conn, _, _, err := ssh.NewClientConn(clientConn, "pipe", clientConfig)
+	if err == nil {
+		conn.Close()
+	}
+
+	return err
+}
+
+func TestSSHPolicyHandshake(t *testing.T) {
+
+	restore := setSSHPolicy(SSHPolicyModern, "", "", "", "")
+	defer restore()
+
+	algorithms, err := LoadSSHAlgorithms()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handshake(t, algorithms, &ssh.ClientConfig{}); err != nil {
+		t.Errorf("Default client refused: %v", err)
+	}
+
+	// RSA host key signed with SHA-2
+	if err := handshake(t, algorithms, &ssh.ClientConfig{HostKeyAlgorithms: []string{ssh.SigAlgoRSASHA2512}}); err != nil {
+		t.Errorf("rsa-sha2-512 host key refused: %v", err)
+	}
+
+	refused := []ssh.ClientConfig{
+		{HostKeyAlgorithms: []string{ssh.KeyAlgoRSA}},
+		{Config: ssh.Config{MACs: []string{"hmac-sha1"}}},
+		{Config: ssh.Config{Ciphers: []string{"aes128-cbc"}}},
+		{Config: ssh.Config{KeyExchanges: []string{"diffie-hellman-group14-sha1"}}},
+	}
+
+	for _, clientConfig := range refused {
+		if err := handshake(t, algorithms, &clientConfig); err == nil {
+			t.Errorf("Client %+v accepted", clientConfig)
+		}
+	}
+}
+
+func TestSSHPolicyPublicKeys(t *testing.T) {
+
+	signers := testHostSigners(t)
+	ed25519Key, rsaKey := signers[0], signers[1]
+
+	tests := []struct {
+		policy   string
+		key      ssh.Signer
+		accepted bool
+	}{
+		{SSHPolicyModern, ed25519Key, true},
+		{SSHPolicyModern, rsaKey, false},
+		{SSHPolicyFIPS, ed25519Key, false},
+		{SSHPolicyFIPS, rsaKey, false},
+		{SSHPolicyCompat, rsaKey, true},
+	}
+
+	for _, test := range tests {
+
+		restore := setSSHPolicy(test.policy, "", "", "", "")
+		algorithms, err := LoadSSHAlgorithms()
+		restore()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = handshake(t, algorithms, &ssh.ClientConfig{Auth: []ssh.AuthMethod{ssh.PublicKeys(test.key)}})
+		if (err == nil) != test.accepted {
+			t.Errorf("%s policy, %s key: accepted %v expected, got %v", test.policy, test.key.PublicKey().Type(), test.accepted, err)
+		}
+	}
+}
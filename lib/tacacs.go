@@ -23,6 +23,8 @@ import (
 	"context"
 	"errors"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"orange/sonic-netconf-server/tacplus"
@@ -30,8 +32,38 @@ import (
 	"github.com/golang/glog"
 )
 
+// Interval of the watchdog accounting records of long sessions, 0 disables them
+var TacacsWatchdogInterval = 0 * time.Second
+
+// When set, Account sends the start record of the command before it is
+// executed and fails the operation when no server accepts it, the stop record
+// follows once the command completes. Otherwise the command is accounted once
+// completed and an accounting failure is only logged
+var TacacsAccountingRequired = true
+
+// Privilege levels of RFC 8907
+const (
+	TacacsPrivLvlMin  = 0
+	TacacsPrivLvlUser = 1
+	TacacsPrivLvlMax  = 15
+)
+
+// Attributes of the shell service authorization
+const (
+	TacacsAttrPrivLvl = "priv-lvl"
+	TacacsAttrRole    = "netconf-role"    // NETCONF roles, used as the user groups
+	TacacsAttrModules = "netconf-modules" // YANG module name prefixes the user may access
+)
+
+// Group of the users authorized with the maximum privilege level and no role
+const TacacsAdminGroup = "admin"
+
+const tacacsShellService = "shell"
+
+var errAuthorDenied = errors.New("authorization denied")
+
 type TacacsAuthenticator struct {
-	client        *tacplus.Client
+	pool          *tacplus.Pool
 	info          tacplus.TacacsInfo
 	context       context.Context
 	username      string
@@ -40,18 +72,41 @@ type TacacsAuthenticator struct {
 	service       string
 	remoteAddress string
 	authType      uint8
+
+	// Session authorization context, from the attributes of the server
+	privLvl     uint8
+	roles       []string
+	modules     []string
+	policy      *RolePolicy
+	authorCache authorCache
+
+	// Session accounting
+	mutex        sync.Mutex
+	sessionTask  string
+	sessionStart time.Time
+	commandTask  string
+	commandStart time.Time
+	commandArgs  []string
+	watchdogStop chan struct{}
 }
 
-// Will create a connection with the highest priority tacacs server
-func NewTacacsAuthenticator(context context.Context, protocol string, service string, username string, password string, remoteAddress string) (TacacsAuthenticator, error) {
+// Will use the server pool, requests go to the highest priority reachable server
+func NewTacacsAuthenticator(context context.Context, protocol string, service string, username string, password string, remoteAddress string) (*TacacsAuthenticator, error) {
 
-	client, info, err := tacplus.CreateClient(context)
+	pool, info, err := getTacacsPool(context)
 
 	if err != nil {
-		return TacacsAuthenticator{}, err
+		return nil, err
 	}
 
-	var authenticatorInfo TacacsAuthenticator
+	return newTacacsAuthenticator(context, pool, info, protocol, service, username, password, remoteAddress)
+}
+
+// newTacacsAuthenticator creates an authenticator sending its requests to
+// pool, info is the configuration of the preferred server
+func newTacacsAuthenticator(context context.Context, pool *tacplus.Pool, info tacplus.TacacsInfo, protocol string, service string, username string, password string, remoteAddress string) (*TacacsAuthenticator, error) {
+
+	authenticatorInfo := &TacacsAuthenticator{}
 
 	switch info.AuthType {
 	case "ascii":
@@ -62,11 +117,13 @@ func NewTacacsAuthenticator(context context.Context, protocol string, service st
 		authenticatorInfo.authType = tacplus.AuthenTypeCHAP
 	case "mschap":
 		authenticatorInfo.authType = tacplus.AuthenTypeMSCHAP
+	case "mschapv2":
+		authenticatorInfo.authType = tacplus.AuthenTypeMSCHAPv2
 	default:
-		return TacacsAuthenticator{}, errors.New("Unkown authentication type")
+		return nil, errors.New("Unkown authentication type")
 	}
 
-	authenticatorInfo.client = client
+	authenticatorInfo.pool = pool
 	authenticatorInfo.info = info
 	authenticatorInfo.context = context
 	authenticatorInfo.username = username
@@ -74,24 +131,33 @@ func NewTacacsAuthenticator(context context.Context, protocol string, service st
 	authenticatorInfo.protocol = protocol
 	authenticatorInfo.service = service
 	authenticatorInfo.remoteAddress = remoteAddress
+	authenticatorInfo.privLvl = TacacsPrivLvlUser
 
 	return authenticatorInfo, nil
 }
 
-func (t TacacsAuthenticator) Authenticate() bool {
+func (t *TacacsAuthenticator) Authenticate() bool {
+
+	// CHAP types send a challenge and its response, never the password
+	data, err := tacplus.AuthenData(t.authType, t.username, t.password)
+
+	if err != nil {
+		glog.Errorf("[AAA] Unable to build the authentication data of user %s: %v", t.username, err)
+		return false
+	}
 
 	authenReq := &tacplus.AuthenStart{
 		Action:        tacplus.AuthenActionLogin,
 		AuthenType:    t.authType,
-		AuthenService: tacplus.AuthenServicePPP,
-		PrivLvl:       0,
+		AuthenService: tacplus.AuthenServiceLogin,
+		PrivLvl:       t.privLvl,
 		Port:          t.protocol,
 		User:          t.username,
-		Data:          []byte(t.password),
+		Data:          data,
 		RemAddr:       t.remoteAddress,
 	}
 
-	authenRep, session, err := t.client.SendAuthenStart(t.context, authenReq)
+	authenRep, session, err := t.pool.SendAuthenStart(t.context, authenReq)
 
 	if err != nil {
 		return false
@@ -128,16 +194,123 @@ func (t TacacsAuthenticator) Authenticate() bool {
 	return true
 }
 
-func (t TacacsAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+// AuthorizeSession runs the shell service authorization of the session, the
+// attributes returned by the server set the privilege level, roles and
+// modules of the user. Users authenticated by key or certificate have no
+// password to send, the session authorization is their only check
+func (t *TacacsAuthenticator) AuthorizeSession() bool {
+
+	authorArgs := []string{"service=" + tacacsShellService, "cmd="}
+
+	authorReply, err := t.sendAuthorization(authorArgs)
+
+	if err != nil {
+		glog.Infof("Session authorization failed for user %s: %v", t.username, err)
+		return false
+	}
+
+	// Replaced attributes are used as sent, added ones complete the request
+	attributes := authorReply.Arg
+	if authorReply.Status == tacplus.AuthorStatusPassAdd {
+		attributes = append(authorArgs, authorReply.Arg...)
+	}
+
+	t.setAttributes(parseAVPairs(attributes))
+
+	glog.Infof("Session authorized for user %s priv-lvl=%d roles=%v modules=%v", t.username, t.privLvl, t.roles, t.modules)
+
+	return true
+}
+
+// setAttributes maps the authorization attributes into the session authorization context
+func (t *TacacsAuthenticator) setAttributes(attributes map[string][]string) {
+	t.privLvl, t.roles, t.modules, t.policy = sessionAttributes(t.username, attributes, t.privLvl)
+}
+
+// sessionAttributes returns the privilege level, roles and modules set by
+// the shell attributes of an AAA server, privLvl is kept when not set. The
+// role policy is read once for the session when the server sets roles
+func sessionAttributes(username string, attributes map[string][]string, privLvl uint8) (uint8, []string, []string, *RolePolicy) {
+
+	if values, ok := attributes[TacacsAttrPrivLvl]; ok {
+		if value, err := strconv.Atoi(values[len(values)-1]); err == nil && value >= TacacsPrivLvlMin && value <= TacacsPrivLvlMax {
+			privLvl = uint8(value)
+		} else {
+			glog.Warningf("Invalid %s %v for user %s", TacacsAttrPrivLvl, values, username)
+		}
+	}
 
-	authorArgs := []string{}
-	authorArgs = append(authorArgs, "protocol="+t.protocol)
-	authorArgs = append(authorArgs, "service="+t.service)
-	authorArgs = append(authorArgs, "timeout="+strconv.Itoa(t.info.Timeout))
+	roles := splitAttribute(attributes[TacacsAttrRole])
+	modules := splitAttribute(attributes[TacacsAttrModules])
 
-	cmd = "cmd=" + cmd
-	if len(cmd) >= 255 {
-		cmd = cmd[:251] + "..."
+	if len(roles) != 0 {
+		return privLvl, roles, modules, sessionPolicy(username, roles)
+	}
+
+	if privLvl == TacacsPrivLvlMax {
+		roles = []string{TacacsAdminGroup}
+	}
+
+	return privLvl, roles, modules, nil
+}
+
+// sessionPolicy returns the role policy applying to the roles of an AAA
+// server. It fails closed: roles the policy does not define have no
+// permission, the default of local users does not apply to them and no
+// role has any permission when the policy is missing or invalid
+func sessionPolicy(username string, roles []string) *RolePolicy {
+
+	policy, err := LoadRolePolicy()
+	if err != nil {
+		glog.Errorf("[ROLE] Unable to read %s: %v", RolePolicyPath, err)
+	}
+
+	if policy == nil {
+		glog.Warningf("[ROLE] No role policy, roles %v of user %s have no permission", roles, username)
+		return &RolePolicy{}
+	}
+
+	for _, role := range roles {
+		if _, ok := policy.Groups[role]; !ok {
+			glog.Warningf("[ROLE] Role %s of user %s not defined in %s", role, username, RolePolicyPath)
+		}
+	}
+
+	sessionPolicy := *policy
+	sessionPolicy.Default = ""
+
+	return &sessionPolicy
+}
+
+// sessionAllows checks an operation against the modules and roles given to
+// the session by an AAA server, policy is nil when the server set no role
+func sessionAllows(username string, roles []string, modules []string, policy *RolePolicy, cmd string, cmdArgs string) bool {
+
+	module := commandModule(cmdArgs)
+
+	if len(modules) != 0 && module != "" && !hasModulePrefix(modules, module) {
+		glog.Infof("%s %s denied to user %s, module %s not in %s", cmd, cmdArgs, username, module, TacacsAttrModules)
+		return false
+	}
+
+	// Roles handed out by the server are defined by the local role policy
+	if policy != nil && !policy.allows(roles, cmd, module) {
+		glog.Infof("[ROLE] %s %s denied to user=%s roles=%v", cmd, cmdArgs, username, roles)
+		return false
+	}
+
+	return true
+}
+
+func (t *TacacsAuthenticator) Authorize(cmd string, cmdArgs string) bool {
+
+	if !sessionAllows(t.username, t.roles, t.modules, t.policy, cmd, cmdArgs) {
+		return false
+	}
+
+	authorCmd := "cmd=" + cmd
+	if len(authorCmd) >= 255 {
+		authorCmd = authorCmd[:251] + "..."
 	}
 
 	cmdArgs = "cmd-arg=" + cmdArgs
@@ -145,67 +318,275 @@ func (t TacacsAuthenticator) Authorize(cmd string, cmdArgs string) bool {
 		cmdArgs = cmdArgs[:251] + "..."
 	}
 
-	authorArgs = append(authorArgs, cmd)
-	authorArgs = append(authorArgs, cmdArgs)
+	// Decisions are cached by the arguments sent, long paths share their
+	// truncated prefix as the server receives the same arguments for them
+	cacheKey := authorCmd + "\n" + cmdArgs
+	if allowed, ok := t.authorCache.get(cacheKey); ok {
+		return allowed
+	}
+
+	authorReply, err := t.sendAuthorization([]string{"service=" + tacacsShellService, authorCmd, cmdArgs})
+
+	if err != nil {
+		glog.Infof("%s denied to user %s: %v", cmd, t.username, err)
+		if err == errAuthorDenied {
+			t.authorCache.put(cacheKey, false)
+		}
+		return false
+	}
+
+	// The server may not substitute another command
+	if authorReply.Status == tacplus.AuthorStatusPassRepl {
+		if replaced, ok := parseAVPairs(authorReply.Arg)["cmd"]; ok && replaced[0] != cmd {
+			glog.Infof("%s denied to user %s, replaced by %s", cmd, t.username, replaced[0])
+			t.authorCache.put(cacheKey, false)
+			return false
+		}
+	}
+
+	t.authorCache.put(cacheKey, true)
+
+	return true
+}
+
+// sendAuthorization sends an authorization request, errAuthorDenied is
+// returned when the server does not pass it
+func (t *TacacsAuthenticator) sendAuthorization(authorArgs []string) (*tacplus.AuthorResponse, error) {
 
 	authorReq := &tacplus.AuthorRequest{
 		AuthenMethod:  tacplus.AuthenMethodTACACSPlus,
-		PrivLvl:       0,
+		PrivLvl:       t.privLvl,
 		AuthenType:    t.authType,
-		AuthenService: tacplus.AuthenServicePPP,
+		AuthenService: tacplus.AuthenServiceLogin,
 		User:          t.username,
+		Port:          t.protocol,
 		Arg:           authorArgs,
 		RemAddr:       t.remoteAddress,
 	}
 
-	authorReply, err := t.client.SendAuthorRequest(t.context, authorReq)
+	authorReply, err := t.pool.SendAuthorRequest(t.context, authorReq)
 
-	if err != nil || authorReply.Status != tacplus.AuthorStatusPassAdd {
-		return false
+	if err != nil {
+		return nil, err
 	}
 
-	return true
+	if authorReply.Status != tacplus.AuthorStatusPassAdd && authorReply.Status != tacplus.AuthorStatusPassRepl {
+		glog.Infof("Authorization %v of user %s: status %d %s", authorArgs, t.username, authorReply.Status, authorReply.ServerMsg)
+		return nil, errAuthorDenied
+	}
+
+	return authorReply, nil
 }
 
-func (t TacacsAuthenticator) Account(cmd string, cmdArgs string) bool {
+// parseAVPairs returns the values of mandatory (=) and optional (*) attributes
+func parseAVPairs(args []string) map[string][]string {
 
-	acctArgs := []string{}
-	acctArgs = append(acctArgs, "start_time="+strconv.FormatInt(time.Now().Unix(), 10))
-	acctArgs = append(acctArgs, "service="+t.service)
+	attributes := map[string][]string{}
 
-	cmd = "cmd=" + cmd
-	if len(cmd) >= 255 {
-		cmd = cmd[:254]
+	for _, arg := range args {
+		if i := strings.IndexAny(arg, "=*"); i > 0 {
+			attributes[arg[:i]] = append(attributes[arg[:i]], arg[i+1:])
+		}
 	}
 
-	cmdArgs = "cmd-arg=" + cmdArgs
-	if len(cmdArgs) >= 255 {
-		cmdArgs = cmdArgs[:251] + "..."
+	return attributes
+}
+
+// splitAttribute returns the comma or space separated items of attribute values
+func splitAttribute(values []string) []string {
+
+	items := []string{}
+	for _, value := range values {
+		items = append(items, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' '
+		})...)
+	}
+
+	if len(items) == 0 {
+		return nil
 	}
 
-	acctArgs = append(acctArgs, cmd)
-	acctArgs = append(acctArgs, cmdArgs)
+	return items
+}
+
+// Account is called before the command is executed, it records the
+// arguments sent in the stop record by AccountCommand. With
+// TacacsAccountingRequired a start record is sent and false is returned when
+// it is not accepted, the command must then not be executed
+func (t *TacacsAuthenticator) Account(cmd string, cmdArgs string) bool {
+
+	cmdArgs = strings.TrimSuffix(cmdArgs, ", ")
+
+	t.mutex.Lock()
+	if t.commandTask == "" {
+		t.commandTask = strconv.Itoa(tacplus.GenerateRandomInt())
+		t.commandStart = time.Now()
+	}
+	task, start := t.commandTask, t.commandStart
+	t.commandArgs = append(t.commandArgs, cmdArgs)
+	t.mutex.Unlock()
+
+	if !TacacsAccountingRequired {
+		return true
+	}
+
+	return t.sendAccounting(tacplus.AcctFlagStart, []string{
+		"task_id=" + task,
+		"start_time=" + strconv.FormatInt(start.Unix(), 10),
+		"timezone=UTC",
+		"service=" + t.service,
+		"protocol=" + t.protocol,
+		commandAttribute("cmd=", cmd),
+		commandAttribute("cmd-arg=", cmdArgs),
+	})
+}
+
+// commandAttribute truncates a command attribute to the 255 bytes of an argument
+func commandAttribute(name string, value string) string {
+
+	attribute := name + value
+	if len(attribute) >= 255 {
+		attribute = attribute[:251] + "..."
+	}
+
+	return attribute
+}
+
+// StartSession sends the start record of the session and starts the watchdog updates
+func (t *TacacsAuthenticator) StartSession() {
+
+	t.mutex.Lock()
+	t.sessionTask = strconv.Itoa(tacplus.GenerateRandomInt())
+	t.sessionStart = time.Now()
+	t.mutex.Unlock()
+
+	t.sendAccounting(tacplus.AcctFlagStart, []string{
+		"task_id=" + t.sessionTask,
+		"start_time=" + strconv.FormatInt(t.sessionStart.Unix(), 10),
+		"timezone=UTC",
+		"service=" + t.service,
+		"protocol=" + t.protocol,
+	})
+
+	if TacacsWatchdogInterval <= 0 {
+		return
+	}
+
+	t.watchdogStop = make(chan struct{})
+
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(TacacsWatchdogInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.sendAccounting(tacplus.AcctFlagWatchdog, []string{
+					"task_id=" + t.sessionTask,
+					"elapsed_time=" + elapsedSeconds(t.sessionStart),
+					"service=" + t.service,
+					"protocol=" + t.protocol,
+				})
+			case <-stop:
+				return
+			}
+		}
+	}(t.watchdogStop)
+}
+
+// StopSession sends the stop record of the session
+func (t *TacacsAuthenticator) StopSession() {
+
+	if t.watchdogStop != nil {
+		close(t.watchdogStop)
+		t.watchdogStop = nil
+	}
+
+	t.sendAccounting(tacplus.AcctFlagStop, []string{
+		"task_id=" + t.sessionTask,
+		"start_time=" + strconv.FormatInt(t.sessionStart.Unix(), 10),
+		"stop_time=" + strconv.FormatInt(time.Now().Unix(), 10),
+		"elapsed_time=" + elapsedSeconds(t.sessionStart),
+		"timezone=UTC",
+		"service=" + t.service,
+		"protocol=" + t.protocol,
+	})
+}
+
+// AccountCommand sends the stop record of a completed command, with the task
+// id and start time of its start record or its own
+func (t *TacacsAuthenticator) AccountCommand(cmd string, start time.Time, err error) {
+
+	t.mutex.Lock()
+	cmdArgs := strings.Join(t.commandArgs, ", ")
+	task := t.commandTask
+	if task != "" {
+		start = t.commandStart
+	}
+	t.commandArgs, t.commandTask = nil, ""
+	t.mutex.Unlock()
+
+	if task == "" {
+		task = strconv.Itoa(tacplus.GenerateRandomInt())
+	}
+
+	status := "success"
+	if err != nil {
+		status = "failure"
+	}
+
+	t.sendAccounting(tacplus.AcctFlagStop, []string{
+		"task_id=" + task,
+		"start_time=" + strconv.FormatInt(start.Unix(), 10),
+		"stop_time=" + strconv.FormatInt(time.Now().Unix(), 10),
+		"elapsed_time=" + elapsedSeconds(start),
+		"timezone=UTC",
+		"service=" + t.service,
+		"protocol=" + t.protocol,
+		"status=" + status,
+		commandAttribute("cmd=", cmd),
+		commandAttribute("cmd-arg=", cmdArgs),
+	})
+}
+
+func (t *TacacsAuthenticator) sendAccounting(flags uint8, acctArgs []string) bool {
 
 	acctReq := &tacplus.AcctRequest{
-		Flags:         tacplus.AcctFlagStart,
+		Flags:         flags,
 		AuthenMethod:  tacplus.AuthenMethodTACACSPlus,
-		PrivLvl:       0,
+		PrivLvl:       t.privLvl,
 		AuthenType:    t.authType,
-		AuthenService: tacplus.AuthenServicePPP,
+		AuthenService: tacplus.AuthenServiceLogin,
 		User:          t.username,
+		Port:          t.protocol,
 		Arg:           acctArgs,
 		RemAddr:       t.remoteAddress,
 	}
 
-	acctRep, err := t.client.SendAcctRequest(t.context, acctReq)
+	acctRep, err := t.pool.SendAcctRequest(t.context, acctReq)
 
 	if err != nil || acctRep.Status != tacplus.AcctStatusSuccess {
+		glog.Warningf("Accounting record %v of user %s not accepted: %v", acctArgs, t.username, err)
 		return false
 	}
 
 	return true
 }
 
-func (t TacacsAuthenticator) Disconnect() {
-	t.client.Close()
+func elapsedSeconds(start time.Time) string {
+	return strconv.FormatInt(int64(time.Since(start)/time.Second), 10)
+}
+
+func (t *TacacsAuthenticator) Username() string {
+	return t.username
+}
+
+// Groups returns the roles given by the server in the session authorization
+func (t *TacacsAuthenticator) Groups() []string {
+	return t.roles
+}
+
+// Disconnect drops the authorization decisions of the session, connections
+// belong to the shared server pool and are closed once idle
+func (t *TacacsAuthenticator) Disconnect() {
+	t.authorCache.clear()
 }
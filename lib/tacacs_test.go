@@ -0,0 +1,291 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"orange/sonic-netconf-server/tacplus"
+	"orange/sonic-netconf-server/tacplus/tacplustest"
+)
+
+func init() {
+	fmt.Println("+++++ init tacacs_test +++++")
+}
+
+// Permissions of the operator role of the test servers
+const testOperatorPolicy = `{"groups": {"operator": {"default": "rw"}}}`
+
+func startTacacsServer(t *testing.T) *tacplustest.Server {
+
+	server := tacplustest.NewServer("testing123")
+	server.AddUser("admin", "adminpass", "priv-lvl=15")
+	server.AddUser("operator", "oppass", "netconf-role=operator", "netconf-modules=sonic-port,sonic-vlan")
+	server.Rules = []tacplustest.Rule{
+		{User: "operator", Cmd: "edit-data", Permit: false},
+		{User: "operator", Cmd: "get", Arg: "/sonic-vlan:", Permit: true},
+	}
+
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return server
+}
+
+func testAuthenticator(t *testing.T, server *tacplustest.Server, authType string, username string, password string) *TacacsAuthenticator {
+
+	info := server.Info(authType)
+
+	authenticator, err := newTacacsAuthenticator(context.Background(), tacplus.NewPool([]tacplus.TacacsInfo{info}), info, "ssh", "netconf", username, password, "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return authenticator
+}
+
+func TestTacacsAuthentication(t *testing.T) {
+
+	server := startTacacsServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		authType string
+		username string
+		password string
+		pass     bool
+	}{
+		{"pap", "admin", "adminpass", true},
+		{"pap", "admin", "wrong", false},
+		{"pap", "nobody", "adminpass", false},
+		// ASCII logins are answered with GETPASS, the password is sent in a continue
+		{"ascii", "operator", "oppass", true},
+		{"ascii", "operator", "wrong", false},
+		{"chap", "admin", "adminpass", true},
+		{"chap", "admin", "wrong", false},
+		{"mschap", "operator", "oppass", true},
+		{"mschap", "operator", "wrong", false},
+		{"mschapv2", "admin", "adminpass", true},
+		{"mschapv2", "admin", "wrong", false},
+	}
+
+	for _, test := range tests {
+		authenticator := testAuthenticator(t, server, test.authType, test.username, test.password)
+		if authenticator.Authenticate() != test.pass {
+			t.Errorf("%s login of %s/%s: expected %v", test.authType, test.username, test.password, test.pass)
+		}
+	}
+}
+
+func TestTacacsSessionAuthorization(t *testing.T) {
+
+	server := startTacacsServer(t)
+	defer server.Close()
+
+	admin := testAuthenticator(t, server, "pap", "admin", "adminpass")
+	if !admin.AuthorizeSession() {
+		t.Fatal("Session of admin not authorized")
+	}
+	if admin.privLvl != TacacsPrivLvlMax || strings.Join(admin.Groups(), ",") != TacacsAdminGroup {
+		t.Errorf("admin: priv-lvl %d groups %v", admin.privLvl, admin.Groups())
+	}
+
+	operator := testAuthenticator(t, server, "pap", "operator", "oppass")
+	if !operator.AuthorizeSession() {
+		t.Fatal("Session of operator not authorized")
+	}
+	if operator.privLvl != TacacsPrivLvlUser || strings.Join(operator.Groups(), ",") != "operator" ||
+		strings.Join(operator.modules, ",") != "sonic-port,sonic-vlan" {
+		t.Errorf("operator: priv-lvl %d groups %v modules %v", operator.privLvl, operator.Groups(), operator.modules)
+	}
+
+	// Replaced attributes drop the request ones
+	server.Users["operator"].ReplaceAttributes = true
+	operator = testAuthenticator(t, server, "pap", "operator", "oppass")
+	if !operator.AuthorizeSession() || len(operator.Groups()) != 1 {
+		t.Errorf("operator with replaced attributes: groups %v", operator.Groups())
+	}
+
+	nobody := testAuthenticator(t, server, "pap", "nobody", "")
+	if nobody.AuthorizeSession() {
+		t.Error("Session of an unknown user authorized")
+	}
+}
+
+func TestTacacsCommandAuthorization(t *testing.T) {
+
+	server := startTacacsServer(t)
+	defer server.Close()
+
+	restore := setRolePolicy(t, testOperatorPolicy)
+	defer restore()
+
+	operator := testAuthenticator(t, server, "pap", "operator", "oppass")
+	if !operator.AuthorizeSession() {
+		t.Fatal("Session of operator not authorized")
+	}
+
+	tests := []struct {
+		cmd   string
+		args  string
+		allow bool
+	}{
+		{"get", "/sonic-vlan:sonic-vlan/VLAN", true},
+		{"edit-data", "/sonic-vlan:sonic-vlan/VLAN", false},
+		// Outside netconf-modules, refused without asking the server
+		{"get", "/sonic-system-aaa:sonic-system-aaa", false},
+	}
+
+	for _, test := range tests {
+		if operator.Authorize(test.cmd, test.args) != test.allow {
+			t.Errorf("%s %s: expected %v", test.cmd, test.args, test.allow)
+		}
+	}
+
+	for _, request := range server.Authorizations() {
+		if request.PrivLvl != TacacsPrivLvlUser || request.AuthenService != tacplus.AuthenServiceLogin {
+			t.Errorf("Authorization sent with priv-lvl %d service %d", request.PrivLvl, request.AuthenService)
+		}
+		if strings.Contains(strings.Join(request.Arg, " "), "sonic-system-aaa") {
+			t.Errorf("Command outside netconf-modules sent to the server: %v", request.Arg)
+		}
+	}
+}
+
+func TestTacacsAuthorizationCache(t *testing.T) {
+
+	server := startTacacsServer(t)
+	defer server.Close()
+
+	restore := setRolePolicy(t, testOperatorPolicy)
+	defer restore()
+
+	operator := testAuthenticator(t, server, "pap", "operator", "oppass")
+	operator.AuthorizeSession()
+	sent := len(server.Authorizations())
+
+	for i := 0; i < 3; i++ {
+		operator.Authorize("get", "/sonic-vlan:sonic-vlan/VLAN")
+		operator.Authorize("edit-data", "/sonic-vlan:sonic-vlan/VLAN")
+	}
+
+	if len(server.Authorizations()) != sent+2 {
+		t.Errorf("Expected 2 authorization requests with the cache, got %d", len(server.Authorizations())-sent)
+	}
+
+	// The cache ends with the session
+	operator.Disconnect()
+	operator.Authorize("get", "/sonic-vlan:sonic-vlan/VLAN")
+
+	if len(server.Authorizations()) != sent+3 {
+		t.Errorf("Cache not cleared at the end of the session")
+	}
+}
+
+func TestTacacsAccounting(t *testing.T) {
+
+	server := startTacacsServer(t)
+	defer server.Close()
+
+	savedRequired := TacacsAccountingRequired
+	defer func() { TacacsAccountingRequired = savedRequired }()
+
+	TacacsAccountingRequired = false
+
+	admin := testAuthenticator(t, server, "pap", "admin", "adminpass")
+	admin.AuthorizeSession()
+
+	admin.StartSession()
+	admin.Account("get-data", "/sonic-port:sonic-port")
+	admin.AccountCommand("get-data", time.Now(), nil)
+	admin.AccountCommand("edit-data", time.Now(), errors.New("denied"))
+	admin.StopSession()
+
+	records := server.Accounting()
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 accounting records, got %d", len(records))
+	}
+
+	flags := []uint8{tacplus.AcctFlagStart, tacplus.AcctFlagStop, tacplus.AcctFlagStop, tacplus.AcctFlagStop}
+	for i, record := range records {
+		if record.Flags != flags[i] {
+			t.Errorf("Record %d: flags %#x, expected %#x", i, record.Flags, flags[i])
+		}
+		if record.User != "admin" || record.PrivLvl != TacacsPrivLvlMax {
+			t.Errorf("Record %d: user %s priv-lvl %d", i, record.User, record.PrivLvl)
+		}
+	}
+
+	session := parseAVPairs(records[0].Arg)["task_id"]
+	if stop := parseAVPairs(records[3].Arg)["task_id"]; len(session) != 1 || len(stop) != 1 || session[0] != stop[0] {
+		t.Errorf("Session start and stop task ids differ: %v %v", records[0].Arg, records[3].Arg)
+	}
+
+	command := parseAVPairs(records[1].Arg)
+	if command["cmd"][0] != "get-data" || command["cmd-arg"][0] != "/sonic-port:sonic-port" || command["status"][0] != "success" {
+		t.Errorf("Unexpected command record %v", records[1].Arg)
+	}
+
+	if parseAVPairs(records[2].Arg)["status"][0] != "failure" {
+		t.Errorf("Failed command not recorded as failure: %v", records[2].Arg)
+	}
+}
+
+func TestTacacsAccountingRequired(t *testing.T) {
+
+	server := startTacacsServer(t)
+	defer server.Close()
+
+	admin := testAuthenticator(t, server, "pap", "admin", "adminpass")
+	admin.AuthorizeSession()
+
+	if !admin.Account("edit-data", "/sonic-port:sonic-port") {
+		t.Fatal("Start record not accepted")
+	}
+	admin.AccountCommand("edit-data", time.Now().Add(-time.Hour), nil)
+
+	records := server.Accounting()
+	if len(records) != 2 || records[0].Flags != tacplus.AcctFlagStart || records[1].Flags != tacplus.AcctFlagStop {
+		t.Fatalf("Expected a start and a stop record, got %v", records)
+	}
+
+	start, stop := parseAVPairs(records[0].Arg), parseAVPairs(records[1].Arg)
+	if start["cmd"][0] != "edit-data" || start["cmd-arg"][0] != "/sonic-port:sonic-port" || start["task_id"][0] != stop["task_id"][0] {
+		t.Errorf("Unexpected command records %v %v", records[0].Arg, records[1].Arg)
+	}
+
+	// The stop record times the command from its start record
+	if start["start_time"][0] != stop["start_time"][0] || stop["elapsed_time"][0] != "0" {
+		t.Errorf("Stop record not timed from the start record %v %v", records[0].Arg, records[1].Arg)
+	}
+
+	// The operation fails when the start record is not accepted
+	server.AcctError = true
+
+	if admin.Account("edit-data", "/sonic-port:sonic-port") {
+		t.Error("Account succeeded without accounting server")
+	}
+}
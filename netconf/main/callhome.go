@@ -0,0 +1,127 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"strconv"
+	"time"
+
+	"orange/sonic-netconf-server/lib"
+
+	gliderssh "github.com/gliderlabs/ssh"
+	"github.com/golang/glog"
+)
+
+const callHomeDialTimeout = 10 * time.Second
+
+// startCallHome initiates the connections of the call home clients read from
+// CONFIG_DB, the server then acts as SSH or TLS server on them (RFC 8071)
+func startCallHome(srv *gliderssh.Server) {
+
+	clients, err := lib.GetCallHomeClients()
+	if err != nil {
+		glog.Errorf("[CALLHOME] Unable to read the call home clients: %v", err)
+		return
+	}
+
+	for _, client := range clients {
+		glog.Infof("[CALLHOME] Starting client %s (%s, %s) with %d endpoints", client.Name, client.Transport, client.ConnectionType, len(client.Endpoints))
+		go callHome(srv, client)
+	}
+}
+
+// callHome tries the endpoints of a client in turn, then reconnects following
+// the connection type: at once for a persistent connection that stayed up,
+// with an exponential backoff otherwise, or after each period
+func callHome(srv *gliderssh.Server, client lib.CallHomeClient) {
+
+	backoff := lib.CallHomeMinBackoff
+	last := 0
+
+	for {
+		started := time.Now()
+		connected := false
+		var session time.Duration
+
+		for _, i := range client.EndpointOrder(last) {
+
+			endpoint := client.Endpoints[i]
+			address := net.JoinHostPort(endpoint.Address, strconv.Itoa(endpoint.Port))
+
+			for attempt := 1; attempt <= client.MaxAttempts && !connected; attempt++ {
+
+				conn, err := net.DialTimeout("tcp", address, callHomeDialTimeout)
+				if err != nil {
+					glog.Infof("[CALLHOME] Client %s endpoint %s attempt %d failed: %v", client.Name, endpoint.Name, attempt, err)
+					continue
+				}
+
+				glog.Infof("[CALLHOME] Client %s connected to endpoint %s (%s)", client.Name, endpoint.Name, address)
+
+				connected, last = true, i
+				connectedAt := time.Now()
+				serveCallHome(srv, client, conn)
+				session = time.Since(connectedAt)
+
+				glog.Infof("[CALLHOME] Client %s disconnected from endpoint %s after %v", client.Name, endpoint.Name, session)
+			}
+
+			if connected {
+				break
+			}
+		}
+
+		var wait time.Duration
+		wait, backoff = client.ReconnectWait(time.Since(started), session, connected, backoff)
+
+		if wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+}
+
+// serveCallHome runs the server side of the transport on an outbound connection
+func serveCallHome(srv *gliderssh.Server, client lib.CallHomeClient, conn net.Conn) {
+
+	defer conn.Close()
+
+	if client.ConnectionType == lib.CallHomePeriodic && client.IdleTimeout > 0 {
+		conn = &idleConn{Conn: conn, timeout: time.Duration(client.IdleTimeout) * time.Second}
+	}
+
+	switch client.Transport {
+	case lib.CallHomeTLS:
+		config, err := tlsConfig()
+		if err != nil {
+			glog.Errorf("[CALLHOME] TLS unavailable for client %s: %v", client.Name, err)
+			return
+		}
+		serveTLSConn(tls.Server(conn, config))
+	default:
+		srv.HandleConn(conn)
+	}
+}
+
+// idleConn drops a connection without traffic from the client for the idle timeout
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(b)
+}
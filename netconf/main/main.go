@@ -14,17 +14,15 @@
 package main
 
 import (
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
-	"errors"
 	"flag"
-	"io/ioutil"
-	"os"
+	"net"
 	"strconv"
+	"time"
 
+	"orange/sonic-netconf-server/lib"
 	"orange/sonic-netconf-server/netconf/server"
+	"orange/sonic-netconf-server/radius"
+	"orange/sonic-netconf-server/tacplus"
 
 	gliderssh "github.com/gliderlabs/ssh"
 	"github.com/golang/glog"
@@ -37,92 +35,229 @@ import (
 var (
 	port             int    // Server port
 	clientAuth       string // Client auth mode
-	publicKeyPath    = "/etc/sonic/netconf-key.pub"
-	privateKeyPath   = "/etc/sonic/netconf-key"
+	maxAuthTries     = 3 // Authentication attempts per SSH connection
 )
 
 func init() {
 	// Parse command line
 	flag.IntVar(&port, "port", 830, "Listen port")
+	flag.StringVar(&lib.HostKeyDir, "host_key_dir", lib.HostKeyDir, "Directory of the generated host keys")
+	flag.StringVar(&lib.HostKeyTypes, "host_key_types", lib.HostKeyTypes, "Comma separated host key types, among ed25519, ecdsa and rsa")
+	flag.StringVar(&lib.HostKeyFiles, "host_keys", lib.HostKeyFiles, "Comma separated additional host private key files")
+	flag.BoolVar(&lib.UseSystemHostKeys, "system_host_keys", lib.UseSystemHostKeys, "Serve the /etc/ssh host keys when they exist")
+	flag.StringVar(&lib.SSHPolicy, "ssh_policy", lib.SSHPolicy, "SSH algorithm preset, modern, fips or compat")
+	flag.StringVar(&lib.SSHKeyExchanges, "ssh_kex", lib.SSHKeyExchanges, "Comma separated SSH key exchanges in preference order, overriding the preset")
+	flag.StringVar(&lib.SSHCiphers, "ssh_ciphers", lib.SSHCiphers, "Comma separated SSH ciphers in preference order, overriding the preset")
+	flag.StringVar(&lib.SSHMACs, "ssh_macs", lib.SSHMACs, "Comma separated SSH MACs in preference order, overriding the preset")
+	flag.StringVar(&lib.SSHHostKeyAlgorithms, "ssh_host_key_algorithms", lib.SSHHostKeyAlgorithms, "Comma separated SSH host key algorithms in preference order, overriding the preset")
+	flag.Uint64Var(&lib.SSHRekeyThreshold, "ssh_rekey_bytes", lib.SSHRekeyThreshold, "Bytes transferred before SSH keys are renegotiated, 0 uses the library default")
+	flag.StringVar(&server.YangModelDir, "yang_dir", server.YangModelDir, "Directory of the YANG models served by get-schema")
+	flag.StringVar(&server.StartupConfigPath, "startup_config", server.StartupConfigPath, "Startup datastore file")
+	flag.StringVar(&server.URLSandboxDir, "url_dir", server.URLSandboxDir, "Directory of the files reachable through file:// URLs")
+	flag.StringVar(&server.NACMConfigPath, "nacm_config", server.NACMConfigPath, "Persisted ietf-netconf-acm configuration")
+	flag.StringVar(&lib.RolePolicyPath, "role_policy", lib.RolePolicyPath, "Group permissions of local users, role based authorization is enabled when the file exists")
+	flag.StringVar(&lib.PAMService, "pam_service", lib.PAMService, "PAM service used to authenticate local users")
+	flag.StringVar(&lib.AuthorizedKeysDir, "authorized_keys_dir", lib.AuthorizedKeysDir, "Central directory of user authorized keys, in addition to ~/.ssh/authorized_keys")
+	flag.StringVar(&lib.TrustedUserCAKeysPath, "trusted_user_ca_keys", lib.TrustedUserCAKeysPath, "CA keys allowed to sign user certificates")
+	flag.StringVar(&lib.AuthorizedPrincipalsPath, "authorized_principals", lib.AuthorizedPrincipalsPath, "Certificate principal to username mapping file")
+	flag.StringVar(&lib.RevokedKeysPath, "revoked_keys", lib.RevokedKeysPath, "Revoked keys, certificate serials and key ids")
+	flag.DurationVar(&lib.TacacsWatchdogInterval, "tacacs_watchdog", lib.TacacsWatchdogInterval, "Interval of the TACACS+ watchdog accounting records, 0 disables them")
+	flag.BoolVar(&lib.TacacsAccountingRequired, "tacacs_acct_required", lib.TacacsAccountingRequired, "Fail the operations whose TACACS+ accounting start record is not accepted, otherwise commands are only accounted once completed")
+	flag.DurationVar(&tacplus.ServerRetryInterval, "tacacs_retry", tacplus.ServerRetryInterval, "Time an unreachable TACACS+ server is skipped before it is probed again, 0 disables the probes")
+	flag.DurationVar(&radius.ServerRetryInterval, "radius_retry", radius.ServerRetryInterval, "Time a RADIUS server that did not answer is skipped")
+	flag.DurationVar(&lib.AuthorizationCacheTTL, "authorization_cache_ttl", lib.AuthorizationCacheTTL, "Lifetime of the cached TACACS+ authorization decisions of a session, 0 disables the cache")
+	flag.DurationVar(&lib.AuthorizationNegativeCacheTTL, "authorization_negative_ttl", lib.AuthorizationNegativeCacheTTL, "Lifetime of the cached TACACS+ authorization denials, 0 disables negative caching")
+	flag.IntVar(&maxAuthTries, "max_auth_tries", maxAuthTries, "Authentication attempts per SSH connection")
+	flag.IntVar(&lib.LoginMaxFailures, "login_max_failures", lib.LoginMaxFailures, "Failed logins of a source or user before a lockout, 0 disables lockouts")
+	flag.DurationVar(&lib.LoginLockoutTime, "login_lockout", lib.LoginLockoutTime, "Duration of a login lockout")
+	flag.DurationVar(&lib.LoginBackoffBase, "login_backoff", lib.LoginBackoffBase, "Answer delay of a failed login, doubled at each failure")
+	flag.DurationVar(&lib.LoginBackoffMax, "login_backoff_max", lib.LoginBackoffMax, "Maximum answer delay of a failed login")
+	flag.IntVar(&lib.MaxUnauthenticated, "max_unauthenticated", lib.MaxUnauthenticated, "Concurrent connections not yet authenticated, 0 is unlimited")
+	flag.IntVar(&lib.MaxSessionsPerUser, "max_sessions_per_user", lib.MaxSessionsPerUser, "Concurrent NETCONF sessions of a user, 0 is unlimited")
+	flag.IntVar(&tlsPort, "tls_port", tlsPort, "NETCONF over TLS listen port, 0 disables TLS")
+	flag.StringVar(&tlsCertPath, "tls_cert", tlsCertPath, "TLS server certificate")
+	flag.StringVar(&tlsKeyPath, "tls_key", tlsKeyPath, "TLS server private key")
+	flag.StringVar(&tlsClientCAPath, "tls_client_ca", tlsClientCAPath, "CA certificates of the TLS clients")
 	// flag.StringVar(&clientAuth, "client_auth", "none", "Client auth mode - none|user")
 	flag.Parse()
+	server.URLProtectedPaths = append(server.URLProtectedPaths, tlsCertPath, tlsKeyPath, tlsClientCAPath)
 	// Suppress warning messages related to logging before flag parse
 	flag.CommandLine.Parse([]string{})
 }
 
 func main() {
 
-	MakeSSHKeyPair(publicKeyPath, privateKeyPath)
+	algorithms, err := lib.LoadSSHAlgorithms()
+	if err != nil {
+		glog.Fatalf("[SSH] Invalid algorithm policy: %v", err)
+	}
+
+	hostKeys, err := lib.LoadHostKeys()
+	if err != nil {
+		glog.Fatalf("[SSH] Unable to load the host keys: %v", err)
+	}
+
+	// Keys are offered with the allowed algorithms only
+	hostKeys, err = algorithms.HostKeys(hostKeys)
+	if err != nil {
+		glog.Fatalf("[SSH] %v", err)
+	}
 
 	srv := &gliderssh.Server{Addr: ":" + strconv.Itoa(port), Handler: server.DefaultHandler}
 
+	for _, hostKey := range hostKeys {
+		srv.AddHostKey(hostKey)
+	}
+
 	srv.SubsystemHandlers = map[string]gliderssh.SubsystemHandler{}
 
-	srv.SetOption(gliderssh.HostKeyFile(privateKeyPath))
 	srv.SetOption(gliderssh.NoPty())
 	srv.SetOption(gliderssh.PasswordAuth(authenticate))
+	srv.SetOption(gliderssh.PublicKeyAuth(func(ctx gliderssh.Context, key gliderssh.PublicKey) bool {
+		// RSA user keys are signed with SHA-1, refused by the modern and fips policies
+		if err := algorithms.CheckPublicKey(key); err != nil {
+			glog.Infof("[SSH] Public key refused user:(%s) %v", ctx.User(), err)
+			return false
+		}
+		return authenticatePublicKey(ctx, key)
+	}))
+	srv.SetOption(gliderssh.WrapConn(limitUnauthenticated))
+
+	srv.ServerConfigCallback = func(ctx gliderssh.Context) *cryptossh.ServerConfig {
+		config := &cryptossh.ServerConfig{MaxAuthTries: maxAuthTries}
+		algorithms.ApplyTo(config)
+		return config
+	}
+
+	srv.SubsystemHandlers["netconf"] = netconfSubsystem
+
+	// Set before ListenAndServe, call home connections are handled concurrently
+	srv.ChannelHandlers = map[string]gliderssh.ChannelHandler{"session": gliderssh.DefaultSessionHandler}
+
+	if tlsPort != 0 {
+		go listenAndServeTLS()
+	}
+
+	startCallHome(srv)
 
-	srv.SubsystemHandlers["netconf"] = server.SessionHandler
 	srv.ListenAndServe()
 }
 
 func authenticate(ctx gliderssh.Context, password string) bool {
 
-	pamAuthenticator := server.NewPAMAuthenticator(ctx.User(), password)
-
-	if !pamAuthenticator.Authenticate() {
-		glog.Errorf("[PAM] Authentication failed user:(%s)", ctx.User())
+	if err := lib.CheckLogin(ctx.User(), remoteHost(ctx)); err != nil {
+		glog.Warningf("[AAA] Login refused user:(%s) %v", ctx.User(), err)
 		return false
 	}
 
-	ctx.SetValue("auth-type", "local")
+	authenticator, method, err := lib.Login(ctx, "ssh", "netconf", ctx.User(), password, remoteHost(ctx))
+
+	if err != nil {
+		glog.Errorf("[AAA] Authentication failed user:(%s) %v", ctx.User(), err)
+		// Slow down password guessing
+		time.Sleep(lib.LoginFailed(ctx.User(), remoteHost(ctx)))
+		return false
+	}
 
-	ctx.SetValue("auth", pamAuthenticator)
+	lib.LoginSucceeded(ctx.User(), remoteHost(ctx))
 
-	ctx.SetValue("uuid", uuid.New().String())
+	setSession(ctx, authenticator, method)
 
 	glog.Infof("Authentication success user:(%s)", ctx.User())
 
 	return true
 }
 
-func MakeSSHKeyPair(pubKeyPath, privateKeyPath string) error {
+func authenticatePublicKey(ctx gliderssh.Context, key gliderssh.PublicKey) bool {
 
-	if fileExists(publicKeyPath) && fileExists(privateKeyPath) {
-		glog.Info("SSH key generation skipped, files exists")
-		return nil
+	// Offered keys are not counted as failures, clients try several of them
+	if err := lib.CheckLogin(ctx.User(), remoteHost(ctx)); err != nil {
+		glog.Warningf("[AAA] Login refused user:(%s) %v", ctx.User(), err)
+		return false
 	}
 
-	glog.Info("SSH keys not found, generating server keys")
-
-	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
-	if err != nil {
-		return err
+	// Also called for keys that are only offered, the login itself is done
+	// by netconfSubsystem once the client signed with the key
+	if err := lib.CheckPublicKey(ctx.User(), key, remoteHost(ctx)); err != nil {
+		glog.Infof("[AAA] Public key authentication failed user:(%s) %v", ctx.User(), err)
+		return false
 	}
 
-	// generate and write private key as PEM
-	privateKeyFile, err := os.Create(privateKeyPath)
-	defer privateKeyFile.Close()
-	if err != nil {
-		return err
+	return true
+}
+
+// netconfSubsystem serves the netconf subsystem, users authenticated by
+// public key are authorized by the AAA login methods on their first request
+func netconfSubsystem(s gliderssh.Session) {
+
+	ctx := s.Context().(gliderssh.Context)
+
+	ctx.Lock()
+	if ctx.Value("auth") == nil {
+
+		authenticator, method, err := lib.LoginVerified(ctx, "ssh", "netconf", ctx.User(), remoteHost(ctx))
+
+		if err != nil {
+			ctx.Unlock()
+			glog.Errorf("[AAA] Public key login failed user:(%s) %v", ctx.User(), err)
+			s.Exit(1)
+			return
+		}
+
+		setSession(ctx, authenticator, method)
+
+		glog.Infof("Public key authentication success user:(%s)", ctx.User())
 	}
-	privateKeyPEM := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)}
-	if err := pem.Encode(privateKeyFile, privateKeyPEM); err != nil {
-		return err
+	ctx.Unlock()
+
+	server.SessionHandler(s)
+}
+
+// setSession stores the login result used by the session handler
+func setSession(ctx gliderssh.Context, authenticator lib.Authenticator, method string) {
+
+	ctx.SetValue("auth-type", method)
+
+	ctx.SetValue("auth", authenticator)
+
+	ctx.SetValue("uuid", uuid.New().String())
+
+	if slot, ok := ctx.Value("login-slot").(*lib.LoginSlot); ok {
+		slot.Release()
 	}
+}
 
-	// generate and write public key
-	pub, err := cryptossh.NewPublicKey(&privateKey.PublicKey)
-	if err != nil {
-		return err
+// limitUnauthenticated refuses connections over the unauthenticated
+// connections limit, the slot is released by the login or the close
+func limitUnauthenticated(ctx gliderssh.Context, conn net.Conn) net.Conn {
+
+	slot := lib.AcquireLoginSlot()
+	if slot == nil {
+		glog.Warningf("[SSH] Too many unauthenticated connections, %s refused", conn.RemoteAddr())
+		return nil
 	}
 
-	return ioutil.WriteFile(pubKeyPath, cryptossh.MarshalAuthorizedKey(pub), 0655)
+	ctx.SetValue("login-slot", slot)
+
+	return &slotConn{Conn: conn, slot: slot}
 }
 
-func fileExists(path string) bool {
-	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
-		return false
+type slotConn struct {
+	net.Conn
+	slot *lib.LoginSlot
+}
+
+func (c *slotConn) Close() error {
+	c.slot.Release()
+	return c.Conn.Close()
+}
+
+func remoteHost(ctx gliderssh.Context) string {
+
+	remoteAddress := ctx.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(remoteAddress); err == nil {
+		remoteAddress = host
 	}
-	return true
-}
\ No newline at end of file
+
+	return remoteAddress
+}
@@ -0,0 +1,175 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"time"
+
+	"orange/sonic-netconf-server/lib"
+	"orange/sonic-netconf-server/netconf/server"
+
+	"github.com/golang/glog"
+)
+
+// NETCONF over TLS parameters (RFC 7589)
+var (
+	tlsPort          = 6513
+	tlsCertPath      = "/etc/sonic/netconf/server.crt"
+	tlsKeyPath       = "/etc/sonic/netconf/server.key"
+	tlsClientCAPath  = "/etc/sonic/netconf/client-ca.crt"
+	tlsHandshakeTime = 30 * time.Second
+)
+
+// tlsConfig requires a client certificate issued by the configured CAs
+func tlsConfig() (*tls.Config, error) {
+
+	certificate, err := tls.LoadX509KeyPair(tlsCertPath, tlsKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	caData, err := ioutil.ReadFile(tlsClientCAPath)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caData) {
+		return nil, errors.New("No CA certificate found in " + tlsClientCAPath)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func listenAndServeTLS() {
+
+	config, err := tlsConfig()
+	if err != nil {
+		glog.Errorf("[TLS] Listener disabled: %v", err)
+		return
+	}
+
+	listener, err := tls.Listen("tcp", ":"+strconv.Itoa(tlsPort), config)
+	if err != nil {
+		glog.Errorf("[TLS] Unable to listen on port %d: %v", tlsPort, err)
+		return
+	}
+
+	glog.Infof("[TLS] Listening on port %d", tlsPort)
+
+	var tempDelay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if max := 1 * time.Second; tempDelay > max {
+					tempDelay = max
+				}
+				glog.Warningf("[TLS] Accept failed: %v, retrying in %v", err, tempDelay)
+				time.Sleep(tempDelay)
+				continue
+			}
+			// Closed listener or permanent error
+			glog.Errorf("[TLS] Listener stopped: %v", err)
+			listener.Close()
+			return
+		}
+		tempDelay = 0
+
+		go serveTLSConn(conn.(*tls.Conn))
+	}
+}
+
+// serveTLSConn maps the client certificate to a username, authorizes the
+// session following the AAA login order and runs the NETCONF session
+func serveTLSConn(conn *tls.Conn) {
+
+	defer conn.Close()
+
+	remoteAddress := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(remoteAddress); err == nil {
+		remoteAddress = host
+	}
+
+	slot := lib.AcquireLoginSlot()
+	if slot == nil {
+		glog.Warningf("[TLS] Too many unauthenticated connections, %s refused", remoteAddress)
+		return
+	}
+	defer slot.Release()
+
+	conn.SetDeadline(time.Now().Add(tlsHandshakeTime))
+	if err := conn.Handshake(); err != nil {
+		glog.Infof("[TLS] Handshake failed from %s: %v", remoteAddress, err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	state := conn.ConnectionState()
+
+	// The verified chain holds the client certificate and its CAs
+	chain := state.PeerCertificates
+	if len(state.VerifiedChains) > 0 {
+		chain = state.VerifiedChains[0]
+	}
+
+	list, err := lib.GetCertToName()
+	if err != nil {
+		glog.Errorf("[TLS] Unable to read the cert-to-name list: %v", err)
+		return
+	}
+
+	username, err := lib.MapCertificateToName(chain, list)
+	if err != nil {
+		glog.Infof("[TLS] Client %s rejected: %v", remoteAddress, err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := lib.CheckLogin(username, remoteAddress); err != nil {
+		glog.Warningf("[AAA] Login refused user:(%s) %v", username, err)
+		return
+	}
+
+	authenticator, _, err := lib.LoginVerified(ctx, "tls", "netconf", username, remoteAddress)
+	if err != nil {
+		glog.Errorf("[AAA] Authentication failed user:(%s) %v", username, err)
+		return
+	}
+
+	slot.Release()
+
+	glog.Infof("TLS authentication success user:(%s)", username)
+
+	server.ServeSession(conn, authenticator)
+}
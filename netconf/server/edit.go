@@ -0,0 +1,460 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"orange/sonic-netconf-server/lib"
+
+	"github.com/Azure/sonic-mgmt-common/translib"
+	"github.com/Azure/sonic-mgmt-common/translib/tlerr"
+	"github.com/antchfx/xmlquery"
+	"github.com/golang/glog"
+)
+
+const (
+	OperationMerge   = "merge"
+	OperationReplace = "replace"
+	OperationCreate  = "create"
+	OperationDelete  = "delete"
+	OperationRemove  = "remove"
+	OperationNone    = "none"
+)
+
+var editOperations = []string{OperationMerge, OperationReplace, OperationCreate, OperationDelete, OperationRemove}
+
+// dataEdit is a single translib write derived from the config of an edit request
+type dataEdit struct {
+	operation string
+	path      string
+	payload   []byte
+}
+
+// namespacePrefix resolves an XML namespace prefix declared on the node or its ancestors
+func namespacePrefix(node *xmlquery.Node, prefix string) string {
+	for ; node != nil; node = node.Parent {
+		for _, attr := range node.Attr {
+			if attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// operationAttr returns the value of the NETCONF operation attribute of an element
+func operationAttr(element *xmlquery.Node) (string, error) {
+
+	for _, attr := range element.Attr {
+		if attr.Name.Local != "operation" || attr.Name.Space == "" || attr.Name.Space == "xmlns" {
+			continue
+		}
+		if namespacePrefix(element, attr.Name.Space) != NsNetconfBase {
+			continue
+		}
+		if !contains(editOperations, attr.Value) {
+			err := NewRPCError(ErrorTypeProtocol, ErrorTagBadAttribute, "Invalid operation "+attr.Value)
+			err.ErrorInfo.BadAttribute = "operation"
+			err.ErrorInfo.BadElement = element.Data
+			return "", err
+		}
+		return attr.Value, nil
+	}
+
+	return "", nil
+}
+
+func unknownElementError(element *xmlquery.Node) error {
+	err := NewRPCError(ErrorTypeApplication, ErrorTagUnknownElement, "Unknown element "+element.Data)
+	err.ErrorInfo.BadElement = element.Data
+	return err
+}
+
+// qualifiedName returns the JSON and translib name of a node, prefixed by its
+// module when it differs from the one of its parent (RFC 7951 section 4)
+func qualifiedName(schema *SchemaNode) string {
+	if schema.Parent == nil || schema.Parent.Kind == "module" || schema.Parent.Module != schema.Module {
+		return schema.Module + ":" + schema.Name
+	}
+	return schema.Name
+}
+
+// planEdit converts the config element of an edit request to translib writes,
+// each element carrying an operation attribute becomes a separate write applied
+// after the one of its parent
+func planEdit(config *xmlquery.Node, tree map[string]*SchemaNode, defaultOperation string) ([]dataEdit, error) {
+
+	edits := []dataEdit{}
+
+	for _, element := range elementChildren(config) {
+
+		schema := schemaRootNode(tree, element.NamespaceURI, element.Data)
+		if schema == nil {
+			return nil, unknownElementError(element)
+		}
+
+		if _, _, err := planNode(element, schema, "", defaultOperation, &edits); err != nil {
+			return nil, err
+		}
+	}
+
+	return edits, nil
+}
+
+// planNode returns the JSON value of an element when it is part of the payload
+// of its parent, writes of its own are appended to edits
+func planNode(element *xmlquery.Node, schema *SchemaNode, parentPath string, inherited string, edits *[]dataEdit) (interface{}, bool, error) {
+
+	operation, err := operationAttr(element)
+	if err != nil {
+		return nil, false, err
+	}
+
+	path := parentPath + "/" + qualifiedName(schema)
+
+	if schema.Kind == "list" {
+		for _, key := range schema.Keys {
+			keyNode := xmlquery.FindOne(element, "./*[local-name() = '"+key+"']")
+			if keyNode == nil {
+				rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagMissingElement, "Missing key "+key+" of "+schema.Name)
+				rpcErr.ErrorInfo.BadElement = key
+				return nil, false, rpcErr
+			}
+			path += "[" + key + "=" + strings.TrimSpace(keyNode.InnerText()) + "]"
+		}
+	}
+
+	if schema.Kind == "leaf-list" {
+		path += "[" + schema.Name + "=" + strings.TrimSpace(element.InnerText()) + "]"
+	}
+
+	top := parentPath == ""
+
+	if operation == "" || operation == inherited {
+		if !top || inherited == OperationNone {
+			if inherited == OperationNone {
+				return nil, false, planChildren(element, schema, path, OperationNone, edits)
+			}
+			value, err := nodeValue(element, schema, path, inherited, edits)
+			return value, err == nil, err
+		}
+		operation = inherited
+	}
+
+	switch operation {
+	case OperationDelete, OperationRemove:
+		*edits = append(*edits, dataEdit{operation: operation, path: path})
+		return nil, false, nil
+	case OperationCreate:
+		if top {
+			rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagOperationNotSupported, "Create is not supported on top level nodes")
+			rpcErr.ErrorInfo.BadAttribute = "operation"
+			rpcErr.ErrorInfo.BadElement = element.Data
+			return nil, false, rpcErr
+		}
+	}
+
+	// The write of the node comes before the ones of its descendants
+	index := len(*edits)
+	*edits = append(*edits, dataEdit{operation: operation, path: path})
+
+	value, err := nodeValue(element, schema, path, operation, edits)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if schema.Kind == "list" || schema.Kind == "leaf-list" {
+		value = []interface{}{value}
+	}
+
+	// Create targets the parent, as a POST would
+	if operation == OperationCreate {
+		(*edits)[index].path = parentPath
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{qualifiedName(schema): value})
+	if err != nil {
+		return nil, false, err
+	}
+
+	(*edits)[index].payload = payload
+
+	return nil, false, nil
+}
+
+// planChildren walks the children of a node that is not written itself
+func planChildren(element *xmlquery.Node, schema *SchemaNode, path string, inherited string, edits *[]dataEdit) error {
+
+	for _, child := range elementChildren(element) {
+		childSchema := schema.Child(child.Data)
+		if childSchema == nil {
+			return unknownElementError(child)
+		}
+		if _, _, err := planNode(child, childSchema, path, inherited, edits); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nodeValue returns the RFC 7951 JSON encoding of an element
+func nodeValue(element *xmlquery.Node, schema *SchemaNode, path string, operation string, edits *[]dataEdit) (interface{}, error) {
+
+	switch schema.Kind {
+	case "leaf", "leaf-list":
+		return leafValue(element, schema)
+	case "anydata", "anyxml":
+		return element.InnerText(), nil
+	}
+
+	value := map[string]interface{}{}
+
+	for _, child := range elementChildren(element) {
+
+		childSchema := schema.Child(child.Data)
+		if childSchema == nil {
+			return nil, unknownElementError(child)
+		}
+
+		childValue, include, err := planNode(child, childSchema, path, operation, edits)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+
+		name := qualifiedName(childSchema)
+		if childSchema.Kind == "list" || childSchema.Kind == "leaf-list" {
+			list, _ := value[name].([]interface{})
+			value[name] = append(list, childValue)
+		} else {
+			value[name] = childValue
+		}
+	}
+
+	return value, nil
+}
+
+func leafValue(element *xmlquery.Node, schema *SchemaNode) (interface{}, error) {
+
+	text := strings.TrimSpace(element.InnerText())
+
+	invalid := func() error {
+		err := NewRPCError(ErrorTypeApplication, ErrorTagInvalidValue, fmt.Sprintf("Invalid value %q for %s", text, schema.Name))
+		err.ErrorInfo.BadElement = element.Data
+		return err
+	}
+
+	switch leafType := schema.LeafType(); leafType {
+	case "int8", "int16", "int32":
+		bits, _ := strconv.Atoi(strings.TrimPrefix(leafType, "int"))
+		if _, err := strconv.ParseInt(text, 10, bits); err != nil {
+			return nil, invalid()
+		}
+		return json.Number(text), nil
+	case "uint8", "uint16", "uint32":
+		bits, _ := strconv.Atoi(strings.TrimPrefix(leafType, "uint"))
+		if _, err := strconv.ParseUint(text, 10, bits); err != nil {
+			return nil, invalid()
+		}
+		return json.Number(text), nil
+	case "boolean":
+		if text != "true" && text != "false" {
+			return nil, invalid()
+		}
+		return text == "true", nil
+	case "empty":
+		return []interface{}{nil}, nil
+	case "identityref":
+		// XML prefixes are replaced by module names
+		if i := strings.Index(text, ":"); i >= 0 {
+			if module := moduleByNamespace(namespacePrefix(element, text[:i])); module != "" {
+				return module + ":" + text[i+1:], nil
+			}
+			return text, nil
+		}
+		return schema.Module + ":" + text, nil
+	}
+
+	// 64 bit integers and decimal64 are encoded as strings
+	return element.InnerText(), nil
+}
+
+// moduleByNamespace returns the name of the module with the given namespace,
+// identities are often defined in import only modules
+func moduleByNamespace(namespace string) string {
+	for _, mod := range YangModules.Modules {
+		if mod.Namespace != nil && *mod.Namespace == namespace {
+			return *mod.Name
+		}
+	}
+	return ""
+}
+
+// applyEdit performs a single write through translib
+func applyEdit(edit dataEdit) error {
+
+	glog.Infof("Applying %s on %s payload %s", edit.operation, edit.path, edit.payload)
+
+	req := translib.SetRequest{Path: edit.path, Payload: edit.payload}
+
+	var err error
+
+	switch edit.operation {
+	case OperationMerge:
+		_, err = translib.Update(req)
+	case OperationReplace:
+		_, err = translib.Replace(req)
+	case OperationCreate:
+		_, err = translib.Create(req)
+	case OperationDelete, OperationRemove:
+		// Delete fails on missing data, translib deletes silently
+		if _, getErr := translib.Get(translib.GetRequest{Path: edit.path}); getErr != nil {
+			err = getErr
+			break
+		}
+		_, err = translib.Delete(req)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	var rpcErr *RPCError
+
+	switch err.(type) {
+	case tlerr.NotFoundError:
+		if edit.operation == OperationRemove {
+			return nil
+		}
+		rpcErr = NewRPCError(ErrorTypeApplication, ErrorTagDataMissing, err.Error())
+	case tlerr.AlreadyExistsError:
+		rpcErr = NewRPCError(ErrorTypeApplication, ErrorTagDataExists, err.Error())
+	default:
+		rpcErr = NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, err.Error())
+	}
+
+	rpcErr.ErrorPath = edit.path
+
+	return rpcErr
+}
+
+// EditDataHandler handles the RFC 8526 edit-data operation, writes are applied
+// in document order and are not rolled back on error
+func EditDataHandler(authenticator lib.Authenticator, rootNode *xmlquery.Node) (string, error) {
+
+	editData := xmlquery.FindOne(rootNode, "//*[local-name() = 'edit-data']")
+
+	datastore, err := datastoreParam(editData)
+	if err != nil {
+		return "", err
+	}
+
+	if datastore != DsRunning {
+		rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Datastore "+datastore+" is not writable")
+		rpcErr.ErrorInfo.BadElement = "datastore"
+		return "", rpcErr
+	}
+
+	defaultOperation := OperationMerge
+	if node := xmlquery.FindOne(editData, "./*[local-name() = 'default-operation']"); node != nil {
+		defaultOperation = strings.TrimSpace(node.InnerText())
+		if defaultOperation != OperationMerge && defaultOperation != OperationReplace && defaultOperation != OperationNone {
+			rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Invalid default-operation "+defaultOperation)
+			rpcErr.ErrorInfo.BadElement = "default-operation"
+			return "", rpcErr
+		}
+	}
+
+	config := xmlquery.FindOne(editData, "./*[local-name() = 'config']")
+	if config == nil {
+		if xmlquery.FindOne(editData, "./*[local-name() = 'url']") != nil {
+			return "", NewRPCError(ErrorTypeProtocol, ErrorTagOperationNotSupported, "URL edits are not supported")
+		}
+		rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagMissingElement, "Config not specified")
+		rpcErr.ErrorInfo.BadElement = "config"
+		return "", rpcErr
+	}
+
+	session := nacmFor(authenticator)
+
+	// The nacm container is kept by the server, not translib. Like the other
+	// edits, it is only stored once the whole edit is planned and authorized
+	var nacm *Nacm
+	for _, element := range elementChildren(config) {
+		if element.NamespaceURI == NsNetconfAcm && element.Data == "nacm" {
+			if !authenticator.Authorize("edit-data", "/ietf-netconf-acm:nacm") {
+				return "", errors.New("[AUTH] Unauthorized access /ietf-netconf-acm:nacm")
+			}
+			if nacm, err = planNacm(session, element, defaultOperation); err != nil {
+				return "", err
+			}
+			removeNode(element)
+		}
+	}
+
+	edits, err := planEdit(config, SchemaTree(), defaultOperation)
+	if err != nil {
+		return "", err
+	}
+
+	for _, edit := range edits {
+		// Authorize
+		if !authenticator.Authorize("edit-data", edit.path) {
+			return "", errors.New(fmt.Sprintf("[AUTH] Unauthorized access %+s", edit.path))
+		}
+		if err := session.checkEdit(edit); err != nil {
+			return "", err
+		}
+		glog.Infof("[AUTH] authorization passed %+s", edit.path)
+	}
+
+	args := ""
+	if nacm != nil {
+		args += "edit /ietf-netconf-acm:nacm, "
+	}
+	for _, edit := range edits {
+		args += edit.operation + " " + edit.path + ", "
+	}
+
+	// Account, before anything is changed
+	if !authenticator.Account("edit-data", args) {
+		return "", errors.New(fmt.Sprintf("[AUTH] Accounting failed edit-data - args:%s", args))
+	}
+
+	glog.Infof("[AUTH] Accounting passed - edit-data: %s", args)
+
+	if nacm != nil {
+		if err := storeNacm(nacm); err != nil {
+			glog.Errorf("[NACM] Unable to store %s: %v", NACMConfigPath, err)
+			return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to store the nacm configuration")
+		}
+		glog.Info("[NACM] Configuration updated")
+	}
+
+	for _, edit := range edits {
+		if err := applyEdit(edit); err != nil {
+			return "", err
+		}
+	}
+
+	return "ok", nil
+}
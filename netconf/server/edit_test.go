@@ -0,0 +1,89 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+)
+
+func init() {
+	fmt.Println("+++++ init edit_test +++++")
+}
+
+func testPlanEdit(config string, defaultOperation string) ([]dataEdit, error) {
+	node, _ := xmlquery.Parse(strings.NewReader(`<config xmlns:nc="` + NsNetconfBase + `">` + config + `</config>`))
+	return planEdit(xmlquery.FindOne(node, "config"), testSchemaTree(), defaultOperation)
+}
+
+func TestPlanEdit(t *testing.T) {
+
+	savedModules := YangModules.Modules
+	defer func() { YangModules.Modules = savedModules }()
+
+	types, namespace := "test-types", "urn:test:types"
+	YangModules.Modules = []Module{{Name: &types, Namespace: &namespace}}
+
+	edits, err := testPlanEdit(`<interfaces xmlns="urn:test:interfaces" xmlns:tt="urn:test:types">
+		<interface>
+			<name>Ethernet0</name>
+			<mtu>9100</mtu>
+			<enabled>true</enabled>
+			<speed>tt:speed-10g</speed>
+			<alias>a</alias><alias>b</alias>
+			<description xmlns="urn:test:augment">uplink</description>
+			<vlan nc:operation="delete"/>
+		</interface>
+	</interfaces>`, OperationMerge)
+
+	if err != nil || len(edits) != 2 {
+		t.Fatalf("Result was incorrect, got: %+v %v, want: 2 edits", edits, err)
+	}
+
+	correct := dataEdit{
+		operation: OperationMerge,
+		path:      "/test-interfaces:interfaces",
+		payload:   []byte(`{"test-interfaces:interfaces":{"interface":[{"alias":["a","b"],"enabled":true,"mtu":9100,"name":"Ethernet0","speed":"test-types:speed-10g","test-augment:description":"uplink"}]}}`),
+	}
+
+	if edits[0].operation != correct.operation || edits[0].path != correct.path || string(edits[0].payload) != string(correct.payload) {
+		t.Errorf("Result was incorrect, got: %s %s %s, want: %s %s %s.", edits[0].operation, edits[0].path, edits[0].payload, correct.operation, correct.path, correct.payload)
+	}
+
+	if edits[1].operation != OperationDelete || edits[1].path != "/test-interfaces:interfaces/interface[name=Ethernet0]/vlan" {
+		t.Errorf("Result was incorrect, got: %s %s", edits[1].operation, edits[1].path)
+	}
+
+	edits, err = testPlanEdit(`<interfaces xmlns="urn:test:interfaces"><interface nc:operation="create"><name>Ethernet4</name></interface></interfaces>`, OperationNone)
+	if err != nil || len(edits) != 1 || edits[0].path != "/test-interfaces:interfaces" || string(edits[0].payload) != `{"interface":[{"name":"Ethernet4"}]}` {
+		t.Errorf("Result was incorrect, got: %+v %v", edits, err)
+	}
+
+	errorTags := map[string]string{
+		`<interfaces xmlns="urn:test:interfaces"><interface><mtu>1500</mtu></interface></interfaces>`:                ErrorTagMissingElement,
+		`<interfaces xmlns="urn:test:interfaces"><interface><name>e</name><mtu>70000</mtu></interface></interfaces>`: ErrorTagInvalidValue,
+		`<interfaces xmlns="urn:test:interfaces"><unknown/></interfaces>`:                                            ErrorTagUnknownElement,
+		`<interfaces xmlns="urn:test:interfaces" nc:operation="move"/>`:                                              ErrorTagBadAttribute,
+	}
+
+	for config, tag := range errorTags {
+		_, err := testPlanEdit(config, OperationMerge)
+		if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != tag {
+			t.Errorf("Result was incorrect for %s, got: %v, want: %s.", config, err, tag)
+		}
+	}
+}
@@ -18,11 +18,14 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"regexp"
 	"runtime"
 	"strings"
 	"time"
 
+	"orange/sonic-netconf-server/lib"
+
 	"github.com/antchfx/xmlquery"
 	"github.com/gliderlabs/ssh"
 	"github.com/golang/glog"
@@ -35,13 +38,49 @@ const (
 	declaration = "<?xml version=\"1.0\" encoding=\"utf-8\"?>"
 )
 
+// Transport carries the framed messages of a session, an SSH channel or a TLS connection
+type Transport interface {
+	io.ReadWriteCloser
+}
+
 type SessionRequest struct{
 	xml string
-	authenticator Authenticator
-	session ssh.Session
+	authenticator lib.Authenticator
+	session Transport
+	// closed is set by close-session, other session ends are dropped sessions
+	closed *bool
 }
 
+// SessionHandler serves the netconf SSH subsystem
 func SessionHandler(s ssh.Session) {
+	ServeSession(s, s.Context().Value("auth").(lib.Authenticator))
+}
+
+// ServeSession runs a NETCONF session for an authenticated user on any transport
+func ServeSession(s Transport, authenticator lib.Authenticator) {
+
+	// Release the connection of the login method, TACACS+ sessions are kept open
+	if disconnecter, ok := authenticator.(lib.Disconnecter); ok {
+		defer disconnecter.Disconnect()
+	}
+
+	// Sessions per user limit
+	if identity, ok := authenticator.(lib.Identity); ok {
+		if !lib.AcquireSession(identity.Username()) {
+			glog.Warningf("[AAA] Too many sessions of user:(%s), session refused", identity.Username())
+			s.Close()
+			return
+		}
+		defer lib.ReleaseSession(identity.Username())
+	}
+
+	countStatistic(&inSessions)
+
+	// Session start and stop records, sent before the connection is released
+	if accounter, ok := authenticator.(lib.SessionAccounter); ok {
+		accounter.StartSession()
+		defer accounter.StopSession()
+	}
 
 	scanner := bufio.NewScanner(s)
 	scanner.Split(SplitAt)
@@ -54,19 +93,29 @@ func SessionHandler(s ssh.Session) {
 	scanner.Scan()
 	err := readCapabilities(scanner.Text())
 	if err != nil {
+		countStatistic(&inBadHellos)
 		writeResponse(s, createErrorResponse("1", err))
 		s.Close()
 	}
 
 	glog.Info("Capabilities exchange success, starting main loop")
 
+	// RFC 6022 dropped-sessions counts sessions ended without close-session
+	closed := false
+	defer func() {
+		if !closed {
+			countStatistic(&droppedSessions)
+		}
+	}()
+
 	for scanner.Scan() {
 		requestStr := scanner.Text()
 		glog.Infof("\nReceving request <<< %s >>> \n %s \n\n", time.Now().Local().String(), requestStr)
 		request := SessionRequest{
 			xml : requestStr,
-			authenticator: s.Context().Value("auth").(Authenticator),
+			authenticator: authenticator,
 			session: s,
+			closed: &closed,
 		}
 		response := process(request)
 		glog.Infof("\nSending response <<< %s >>> \n %s \n\n", time.Now().Local().String(), response)
@@ -87,17 +136,19 @@ func capabilitesXML() []byte {
 	serverHello.Capabilities = append(serverHello.Capabilities, CapXPath)
 	serverHello.Capabilities = append(serverHello.Capabilities, CapMonitoring)
 	serverHello.Capabilities = append(serverHello.Capabilities, CapStartup)
+	serverHello.Capabilities = append(serverHello.Capabilities, urlCapability())
 
 	if !yangModulesInit {
 		readYangModules()
 	}
 
-	capYangLib := "urn:ietf:params:netconf:capability:yang-library:1.0?module-set-id=" + *YangModules.ModuleSetId
-	serverHello.Capabilities = append(serverHello.Capabilities, capYangLib)
+	// Only the RFC 8525 library is advertised, modules-state is still served
+	// for clients reading it without the capability
+	capYangLib11 := CapYangLibrary11 + "?revision=" + YangLibraryRevision + "&content-id=" + YangLib.ContentId
+	serverHello.Capabilities = append(serverHello.Capabilities, capYangLib11)
 
 	for _, module := range YangModules.Modules {
-		supportedCap := *module.Namespace + "?module=" + *module.Name + "&revision=" + *module.Revision
-		serverHello.Capabilities = append(serverHello.Capabilities, supportedCap)
+		serverHello.Capabilities = append(serverHello.Capabilities, moduleCapability(module))
 	}
 
 	output, _ := xml.Marshal(serverHello)
@@ -129,27 +180,43 @@ func process(request SessionRequest) string {
 
 	defer doRecover(request.session, request.xml)
 
+	countStatistic(&inRpcs)
+
 	rpcNode, err := xmlquery.Parse(strings.NewReader(request.xml))
 
 	if err != nil {
+		countBadRPC()
 		return createErrorResponse(extractMessageId(request.xml), errors.New("[Malformed XML] Unable to parser request string"))
 	}
 
 	rootNode := xmlquery.FindOne(rpcNode, "*")
 
 	if rootNode == nil {
+		countBadRPC()
 		return createErrorResponse(extractMessageId(request.xml), errors.New("[Malformed XML] Root node not found"))
 	}
 
 	messageId := rootNode.SelectAttr("message-id")
 
 	if messageId == "" {
+		countBadRPC()
 		return createErrorResponse(extractMessageId(request.xml), errors.New("[Missing data] Unable to read message-id in rpc"))
 	}
 
+	start := time.Now()
 	response, err := handleRequest(request, rootNode)
 
+	// Stop record of the command with its status and elapsed time
+	if accounter, ok := request.authenticator.(lib.SessionAccounter); ok {
+		operation := "unknown"
+		if typeNode := xmlquery.FindOne(rootNode, "//*[local-name() = 'rpc']/*"); typeNode != nil {
+			operation = typeNode.Data
+		}
+		accounter.AccountCommand(operation, start, err)
+	}
+
 	if err != nil {
+		countStatistic(&outRPCErrors)
 		return createErrorResponse(messageId, err)
 	}
 
@@ -163,12 +230,30 @@ func handleRequest(request SessionRequest, rpcXML *xmlquery.Node) (string, error
 
 	typeNode := xmlquery.FindOne(rpcXML, "//*[local-name() = 'rpc']/*") // Get request type 
 
+	// Access control of the protocol operation
+	if err := nacmFor(request.authenticator).checkExec(typeNode); err != nil {
+		return "", err
+	}
+
 	switch typeNode.Data {
 	case "get":
 		response, err = GetRequestHandler(request.authenticator, rpcXML)
 	case "get-schema":
 		response, err = GetSchemaHandler(rpcXML)
+	case "get-config":
+		response, err = GetConfigHandler(request.authenticator, rpcXML)
+	case "copy-config":
+		response, err = CopyConfigHandler(request.authenticator, rpcXML)
+	case "delete-config":
+		response, err = DeleteConfigHandler(request.authenticator, rpcXML)
+	case "get-data":
+		response, err = GetDataHandler(request.authenticator, rpcXML)
+	case "edit-data":
+		response, err = EditDataHandler(request.authenticator, rpcXML)
 	case "close-session":
+		if request.closed != nil {
+			*request.closed = true
+		}
 		time.AfterFunc(1* time.Second, func() {request.session.Close()}) // probably a better way to do this ?
 		return "ok", nil
 	default:
@@ -202,19 +287,58 @@ func CreateResponse(messageId string, responsePayload []byte) string {
 	return declaration + reply
 }
 
-func writeResponse(session ssh.Session, message string) {
+func writeResponse(session Transport, message string) {
 	responseString := fmt.Sprintf(ChunkedMessage, len(message), message)
 	session.Write([]byte(responseString))
 }
 
-func writeOkResponse(session ssh.Session, id string) {
+func writeOkResponse(session Transport, id string) {
 	writeResponse(session, CreateResponse(id, []byte("ok")))
 }
 
 func createErrorXML(err error) string {
+	if rpcErr, ok := err.(*RPCError); ok {
+		return createRPCErrorXML(rpcErr)
+	}
 	return fmt.Sprintf("<rpc-error><error-type>rpc</error-type><error-severity>error</error-severity><error-message xml:lang=\"en\">%s</error-message></rpc-error>", err.Error())
 }
 
+func createRPCErrorXML(err *RPCError) string {
+
+	errorXML := "<rpc-error><error-type>" + err.ErrorType + "</error-type><error-tag>" + err.ErrorTag + "</error-tag><error-severity>" + err.ErrorSeverity + "</error-severity>"
+
+	if err.ErrorAppTag != "" {
+		errorXML += "<error-app-tag>" + xmlEscape(err.ErrorAppTag) + "</error-app-tag>"
+	}
+
+	if err.ErrorPath != "" {
+		errorXML += "<error-path>" + xmlEscape(err.ErrorPath) + "</error-path>"
+	}
+
+	errorXML += "<error-message xml:lang=\"en\">" + xmlEscape(err.ErrorMessage) + "</error-message>"
+
+	info := ""
+	if err.ErrorInfo.BadElement != "" {
+		info += "<bad-element>" + xmlEscape(err.ErrorInfo.BadElement) + "</bad-element>"
+	}
+	if err.ErrorInfo.BadAttribute != "" {
+		info += "<bad-attribute>" + xmlEscape(err.ErrorInfo.BadAttribute) + "</bad-attribute>"
+	}
+	if err.ErrorInfo.BadNamespace != "" {
+		info += "<bad-namespace>" + xmlEscape(err.ErrorInfo.BadNamespace) + "</bad-namespace>"
+	}
+	if err.ErrorInfo.SessionID != "" {
+		info += "<session-id>" + err.ErrorInfo.SessionID + "</session-id>"
+	}
+	info += string(err.ErrorInfo.InnerXML)
+
+	if info != "" {
+		errorXML += "<error-info>" + info + "</error-info>"
+	}
+
+	return errorXML + "</rpc-error>"
+}
+
 func createErrorResponse(messageId string, err error) string {
 	return CreateResponse(messageId, []byte(createErrorXML(err)))
 }
@@ -254,7 +378,7 @@ func trimInput(input string) string {
 	return trimmed
 }
 
-func doRecover(session ssh.Session, inputStr string) {
+func doRecover(session Transport, inputStr string) {
 	if err := recover(); err != nil {
 
 		buf := make([]byte, 64<<10)
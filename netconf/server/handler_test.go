@@ -14,8 +14,12 @@
 package server
 
 import (
+	"bufio"
 	"fmt"
+	"net"
+	"strings"
 	"testing"
+	"time"
 )
 
 func init(){
@@ -105,4 +109,36 @@ func TestProcessRequestAuthFailed(t *testing.T) {
 	if result != correct {
 		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
 	}
-}
\ No newline at end of file
+}
+
+func TestServeSession(t *testing.T) {
+
+	client, transport := net.Pipe()
+	defer client.Close()
+
+	done := make(chan struct{})
+	go func() {
+		ServeSession(transport, NewTestAuthenticator(true))
+		close(done)
+	}()
+
+	scanner := bufio.NewScanner(client)
+	scanner.Split(SplitAt)
+
+	if !scanner.Scan() || !strings.Contains(scanner.Text(), "<hello") {
+		t.Fatalf("Expected the server hello, got: %s", scanner.Text())
+	}
+
+	client.Write([]byte("<hello xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities></hello>]]>]]>"))
+	client.Write([]byte("<rpc xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\" message-id=\"7\"><close-session/></rpc>]]>]]>"))
+
+	if !scanner.Scan() || !strings.Contains(scanner.Text(), "message-id=\"7\"><ok/></rpc-reply>") {
+		t.Errorf("Expected an ok reply to close-session, got: %s", scanner.Text())
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Session not closed after close-session")
+	}
+}
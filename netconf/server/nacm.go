@@ -0,0 +1,1058 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"orange/sonic-netconf-server/lib"
+
+	"github.com/Azure/sonic-mgmt-common/translib"
+	"github.com/antchfx/xmlquery"
+	"github.com/golang/glog"
+)
+
+var (
+	// ietf-netconf-acm configuration, NACM is disabled until it is configured
+	NACMConfigPath = "/etc/sonic/netconf/nacm.xml"
+
+	// Sessions of this user are not subject to access control (RFC 8341 section 3.4.2)
+	NACMRecoveryUser = "root"
+)
+
+const (
+	NacmRead   = "read"
+	NacmCreate = "create"
+	NacmUpdate = "update"
+	NacmDelete = "delete"
+	NacmExec   = "exec"
+
+	NacmPermit = "permit"
+	NacmDeny   = "deny"
+)
+
+// Nodes marked nacm:default-deny-all, SONiC models do not mark their secrets
+// so the passkey leaves of the TACACS+ and RADIUS tables are added here
+var nacmDenyAllModules = []string{"ietf-netconf-acm"}
+var nacmDenyAllNodes = []string{"passkey"}
+
+// Operations marked nacm:default-deny-all in ietf-netconf
+var nacmDenyAllRPCs = []string{"kill-session", "delete-config"}
+
+// Modules served by the server itself, they may be missing from the yang library
+var nacmModules = map[string]string{
+	NsNetconfBase:       "ietf-netconf",
+	NsNetconfNmda:       "ietf-netconf-nmda",
+	NsNetconfMonitoring: "ietf-netconf-monitoring",
+	NsNetconfAcm:        "ietf-netconf-acm",
+	NsYangLibrary:       "ietf-yang-library",
+	NsNetconfLogin:      "sonic-netconf-login",
+}
+
+// nacmModule returns the module of a namespace, fallback when it is unknown
+func nacmModule(namespace string, fallback string) string {
+	if module, ok := nacmModules[namespace]; ok {
+		return module
+	}
+	if module := moduleByNamespace(namespace); module != "" {
+		return module
+	}
+	return fallback
+}
+
+type NacmGroup struct {
+	Name     string   `xml:"name"`
+	UserName []string `xml:"user-name"`
+}
+
+type NacmRule struct {
+	Name             string `xml:"name"`
+	ModuleName       string `xml:"module-name,omitempty"`
+	RPCName          string `xml:"rpc-name,omitempty"`
+	NotificationName string `xml:"notification-name,omitempty"`
+	Path             string `xml:"path,omitempty"`
+	AccessOperations string `xml:"access-operations,omitempty"`
+	Action           string `xml:"action"`
+	Comment          string `xml:"comment,omitempty"`
+}
+
+type NacmRuleList struct {
+	Name  string     `xml:"name"`
+	Group []string   `xml:"group"`
+	Rule  []NacmRule `xml:"rule"`
+}
+
+// Nacm is the ietf-netconf-acm nacm container, unset leafs take their YANG default
+type Nacm struct {
+	XMLName              xml.Name       `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-acm nacm"`
+	EnableNacm           *bool          `xml:"enable-nacm,omitempty"`
+	ReadDefault          string         `xml:"read-default,omitempty"`
+	WriteDefault         string         `xml:"write-default,omitempty"`
+	ExecDefault          string         `xml:"exec-default,omitempty"`
+	EnableExternalGroups *bool          `xml:"enable-external-groups,omitempty"`
+	DeniedOperations     *uint32        `xml:"denied-operations,omitempty"`
+	DeniedDataWrites     *uint32        `xml:"denied-data-writes,omitempty"`
+	DeniedNotifications  *uint32        `xml:"denied-notifications,omitempty"`
+	Groups               []NacmGroup    `xml:"groups>group"`
+	RuleList             []NacmRuleList `xml:"rule-list"`
+}
+
+func (n *Nacm) enabled() bool {
+	return n.EnableNacm == nil || *n.EnableNacm
+}
+
+func (n *Nacm) externalGroups() bool {
+	return n.EnableExternalGroups == nil || *n.EnableExternalGroups
+}
+
+func (n *Nacm) defaultAction(access string) string {
+
+	action, fallback := n.ExecDefault, NacmPermit
+
+	switch access {
+	case NacmRead:
+		action = n.ReadDefault
+	case NacmCreate, NacmUpdate, NacmDelete:
+		action, fallback = n.WriteDefault, NacmDeny
+	}
+
+	if action == "" {
+		return fallback
+	}
+	return action
+}
+
+var (
+	nacmMutex  sync.Mutex
+	nacmConfig *Nacm
+
+	nacmDeniedOperations uint32
+	nacmDeniedDataWrites uint32
+)
+
+// currentNacm returns the NACM configuration, it is replaced and never
+// modified so the returned value can be used without locking
+func currentNacm() *Nacm {
+
+	nacmMutex.Lock()
+	defer nacmMutex.Unlock()
+
+	if nacmConfig == nil {
+		nacmConfig = loadNacm()
+	}
+
+	return nacmConfig
+}
+
+func loadNacm() *Nacm {
+
+	disabled := false
+
+	data, err := ioutil.ReadFile(NACMConfigPath)
+	if os.IsNotExist(err) {
+		glog.Infof("[NACM] %s not found, access control disabled", NACMConfigPath)
+		return &Nacm{EnableNacm: &disabled}
+	}
+
+	var config *Nacm
+	if err == nil {
+		var doc *xmlquery.Node
+		if doc, err = xmlquery.Parse(bytes.NewReader(data)); err == nil {
+			config, err = parseNacm(xmlquery.FindOne(doc, "*"))
+		}
+	}
+
+	if err != nil {
+		// Fail closed, only the defaults apply
+		glog.Errorf("[NACM] Unable to read %s: %v", NACMConfigPath, err)
+		return &Nacm{}
+	}
+
+	return config
+}
+
+// storeNacm persists a new NACM configuration and makes it current
+func storeNacm(config *Nacm) error {
+
+	data, err := xml.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	nacmMutex.Lock()
+	defer nacmMutex.Unlock()
+
+	if err := writeFileAtomic(NACMConfigPath, append(data, '\n')); err != nil {
+		return err
+	}
+
+	nacmConfig = config
+
+	return nil
+}
+
+// nacmXML returns the nacm container with its counters
+func nacmXML() string {
+
+	config := *currentNacm()
+
+	deniedOperations := atomic.LoadUint32(&nacmDeniedOperations)
+	deniedDataWrites := atomic.LoadUint32(&nacmDeniedDataWrites)
+	deniedNotifications := uint32(0)
+
+	config.DeniedOperations = &deniedOperations
+	config.DeniedDataWrites = &deniedDataWrites
+	config.DeniedNotifications = &deniedNotifications
+
+	output, err := xml.Marshal(config)
+	if err != nil {
+		glog.Errorf("[NACM] Unable to encode nacm %v", err)
+		return ""
+	}
+
+	return string(output)
+}
+
+// parseNacm reads the content of a nacm element
+func parseNacm(node *xmlquery.Node) (*Nacm, error) {
+
+	if node == nil || node.Data != "nacm" {
+		return nil, NewRPCError(ErrorTypeApplication, ErrorTagMissingElement, "Missing nacm element")
+	}
+
+	config := &Nacm{}
+
+	for _, child := range elementChildren(node) {
+
+		text := strings.TrimSpace(child.InnerText())
+
+		var err error
+
+		switch child.Data {
+		case "enable-nacm":
+			config.EnableNacm, err = nacmBool(child, text)
+		case "enable-external-groups":
+			config.EnableExternalGroups, err = nacmBool(child, text)
+		case "read-default":
+			config.ReadDefault, err = nacmAction(child, text)
+		case "write-default":
+			config.WriteDefault, err = nacmAction(child, text)
+		case "exec-default":
+			config.ExecDefault, err = nacmAction(child, text)
+		case "groups":
+			for _, groupNode := range elementChildren(child) {
+				group := NacmGroup{Name: childText(groupNode, "name")}
+				for _, user := range xmlquery.Find(groupNode, "./*[local-name() = 'user-name']") {
+					group.UserName = append(group.UserName, strings.TrimSpace(user.InnerText()))
+				}
+				if group.Name == "" {
+					return nil, nacmMissingName(groupNode)
+				}
+				config.Groups = append(config.Groups, group)
+			}
+		case "rule-list":
+			var ruleList NacmRuleList
+			if ruleList, err = parseNacmRuleList(child); err == nil {
+				config.RuleList = append(config.RuleList, ruleList)
+			}
+		case "denied-operations", "denied-data-writes", "denied-notifications":
+			// State data, ignored
+		default:
+			err = unknownElementError(child)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+func parseNacmRuleList(node *xmlquery.Node) (NacmRuleList, error) {
+
+	ruleList := NacmRuleList{}
+
+	for _, child := range elementChildren(node) {
+
+		text := strings.TrimSpace(child.InnerText())
+
+		switch child.Data {
+		case "name":
+			ruleList.Name = text
+		case "group":
+			ruleList.Group = append(ruleList.Group, text)
+		case "rule":
+			rule := NacmRule{}
+			for _, field := range elementChildren(child) {
+				value := strings.TrimSpace(field.InnerText())
+				switch field.Data {
+				case "name":
+					rule.Name = value
+				case "module-name":
+					rule.ModuleName = value
+				case "rpc-name":
+					rule.RPCName = value
+				case "notification-name":
+					rule.NotificationName = value
+				case "path":
+					rule.Path = value
+				case "access-operations":
+					rule.AccessOperations = value
+				case "action":
+					action, err := nacmAction(field, value)
+					if err != nil {
+						return ruleList, err
+					}
+					rule.Action = action
+				case "comment":
+					rule.Comment = value
+				default:
+					return ruleList, unknownElementError(field)
+				}
+			}
+			if rule.Name == "" {
+				return ruleList, nacmMissingName(child)
+			}
+			if rule.Action == "" {
+				rpcErr := NewRPCError(ErrorTypeApplication, ErrorTagMissingElement, "Missing action of rule "+rule.Name)
+				rpcErr.ErrorInfo.BadElement = "action"
+				return ruleList, rpcErr
+			}
+			ruleList.Rule = append(ruleList.Rule, rule)
+		default:
+			return ruleList, unknownElementError(child)
+		}
+	}
+
+	if ruleList.Name == "" {
+		return ruleList, nacmMissingName(node)
+	}
+
+	return ruleList, nil
+}
+
+func nacmBool(node *xmlquery.Node, text string) (*bool, error) {
+	if text != "true" && text != "false" {
+		err := NewRPCError(ErrorTypeApplication, ErrorTagInvalidValue, "Invalid value "+text+" for "+node.Data)
+		err.ErrorInfo.BadElement = node.Data
+		return nil, err
+	}
+	value := text == "true"
+	return &value, nil
+}
+
+func nacmAction(node *xmlquery.Node, text string) (string, error) {
+	if text != NacmPermit && text != NacmDeny {
+		err := NewRPCError(ErrorTypeApplication, ErrorTagInvalidValue, "Invalid value "+text+" for "+node.Data)
+		err.ErrorInfo.BadElement = node.Data
+		return "", err
+	}
+	return text, nil
+}
+
+func nacmMissingName(node *xmlquery.Node) error {
+	err := NewRPCError(ErrorTypeApplication, ErrorTagMissingElement, "Missing name of "+node.Data)
+	err.ErrorInfo.BadElement = "name"
+	return err
+}
+
+func childText(node *xmlquery.Node, name string) string {
+	if child := xmlquery.FindOne(node, "./*[local-name() = '"+name+"']"); child != nil {
+		return strings.TrimSpace(child.InnerText())
+	}
+	return ""
+}
+
+// mergeNacm merges an edit in a configuration, lists are merged by name
+func mergeNacm(base *Nacm, edit *Nacm) *Nacm {
+
+	merged := *base
+	merged.Groups = append([]NacmGroup{}, base.Groups...)
+	merged.RuleList = append([]NacmRuleList{}, base.RuleList...)
+
+	if edit.EnableNacm != nil {
+		merged.EnableNacm = edit.EnableNacm
+	}
+	if edit.EnableExternalGroups != nil {
+		merged.EnableExternalGroups = edit.EnableExternalGroups
+	}
+	if edit.ReadDefault != "" {
+		merged.ReadDefault = edit.ReadDefault
+	}
+	if edit.WriteDefault != "" {
+		merged.WriteDefault = edit.WriteDefault
+	}
+	if edit.ExecDefault != "" {
+		merged.ExecDefault = edit.ExecDefault
+	}
+
+	for _, group := range edit.Groups {
+		found := false
+		for i := range merged.Groups {
+			if merged.Groups[i].Name != group.Name {
+				continue
+			}
+			users := append([]string{}, merged.Groups[i].UserName...)
+			for _, user := range group.UserName {
+				if !contains(users, user) {
+					users = append(users, user)
+				}
+			}
+			merged.Groups[i].UserName = users
+			found = true
+		}
+		if !found {
+			merged.Groups = append(merged.Groups, group)
+		}
+	}
+
+	for _, ruleList := range edit.RuleList {
+		found := false
+		for i := range merged.RuleList {
+			if merged.RuleList[i].Name != ruleList.Name {
+				continue
+			}
+			merged.RuleList[i] = mergeNacmRuleList(merged.RuleList[i], ruleList)
+			found = true
+		}
+		if !found {
+			merged.RuleList = append(merged.RuleList, ruleList)
+		}
+	}
+
+	return &merged
+}
+
+func mergeNacmRuleList(base NacmRuleList, edit NacmRuleList) NacmRuleList {
+
+	merged := base
+	merged.Group = append([]string{}, base.Group...)
+	merged.Rule = append([]NacmRule{}, base.Rule...)
+
+	for _, group := range edit.Group {
+		if !contains(merged.Group, group) {
+			merged.Group = append(merged.Group, group)
+		}
+	}
+
+	for _, rule := range edit.Rule {
+		found := false
+		for i := range merged.Rule {
+			if merged.Rule[i].Name == rule.Name {
+				merged.Rule[i] = rule
+				found = true
+			}
+		}
+		if !found {
+			merged.Rule = append(merged.Rule, rule)
+		}
+	}
+
+	return merged
+}
+
+// nacmPathStep is a node of a rule path, with the key values of list entries
+type nacmPathStep struct {
+	name string
+	keys map[string]string
+}
+
+// parseNacmPath splits a rule or translib path, prefixes are ignored and
+// predicates are read as key values with or without quotes
+func parseNacmPath(path string) []nacmPathStep {
+
+	steps := []nacmPathStep{}
+
+	var current *nacmPathStep
+	var token strings.Builder
+	var quote rune
+	inPredicate := false
+
+	flushName := func() {
+		name := token.String()
+		if i := strings.Index(name, ":"); i >= 0 {
+			name = name[i+1:]
+		}
+		if name != "" {
+			steps = append(steps, nacmPathStep{name: name, keys: map[string]string{}})
+			current = &steps[len(steps)-1]
+		}
+		token.Reset()
+	}
+
+	for _, c := range path {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				token.WriteRune(c)
+			}
+		case inPredicate && (c == '\'' || c == '"'):
+			quote = c
+		case inPredicate && c == ']':
+			predicate := token.String()
+			if i := strings.Index(predicate, "="); i >= 0 && current != nil {
+				key := strings.TrimSpace(predicate[:i])
+				if j := strings.Index(key, ":"); j >= 0 {
+					key = key[j+1:]
+				}
+				current.keys[key] = strings.TrimSpace(predicate[i+1:])
+			}
+			token.Reset()
+			inPredicate = false
+		case inPredicate:
+			token.WriteRune(c)
+		case c == '[':
+			flushName()
+			inPredicate = true
+		case c == '/':
+			flushName()
+		default:
+			token.WriteRune(c)
+		}
+	}
+
+	flushName()
+
+	return steps
+}
+
+// nacmStep is a data node being checked, key returns the value of a key of a list entry
+type nacmStep struct {
+	name string
+	key  func(name string) (string, bool)
+}
+
+func pathKeys(keys map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := keys[name]
+		return value, ok
+	}
+}
+
+func xmlKeys(node *xmlquery.Node) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		child := xmlquery.FindOne(node, "./*[local-name() = '"+name+"']")
+		if child == nil {
+			return "", false
+		}
+		return strings.TrimSpace(child.InnerText()), true
+	}
+}
+
+func jsonKeys(value interface{}) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		for field, fieldValue := range object {
+			if field == name || strings.HasSuffix(field, ":"+name) {
+				return fmt.Sprint(fieldValue), true
+			}
+		}
+		return "", false
+	}
+}
+
+// matchNacmPath returns true when a node is the one of a rule path or one of its descendants
+func matchNacmPath(rule []nacmPathStep, node []nacmStep) bool {
+
+	if len(rule) > len(node) {
+		return false
+	}
+
+	for i, step := range rule {
+		if step.name != "*" && step.name != node[i].name {
+			return false
+		}
+		for key, value := range step.keys {
+			if node[i].key == nil {
+				return false
+			}
+			if nodeValue, ok := node[i].key(key); !ok || nodeValue != value {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (r NacmRule) allows(access string) bool {
+	if r.AccessOperations == "" || r.AccessOperations == "*" {
+		return true
+	}
+	return contains(strings.Fields(r.AccessOperations), access)
+}
+
+func (r NacmRule) matchModule(module string) bool {
+	return r.ModuleName == "" || r.ModuleName == "*" || r.ModuleName == module
+}
+
+// nacmSession holds the rule-lists applying to the user of a session,
+// a nil session is not subject to access control
+type nacmSession struct {
+	config    *Nacm
+	username  string
+	ruleLists []*NacmRuleList
+}
+
+func nacmFor(authenticator lib.Authenticator) *nacmSession {
+
+	config := currentNacm()
+	if !config.enabled() {
+		return nil
+	}
+
+	session := &nacmSession{config: config}
+
+	var external []string
+	if identity, ok := authenticator.(lib.Identity); ok {
+		session.username = identity.Username()
+		external = identity.Groups()
+	}
+
+	if session.username != "" && session.username == NACMRecoveryUser {
+		return nil
+	}
+
+	groups := []string{}
+	for _, group := range config.Groups {
+		if contains(group.UserName, session.username) {
+			groups = append(groups, group.Name)
+		}
+	}
+
+	if config.externalGroups() {
+		groups = append(groups, external...)
+	}
+
+	for i := range config.RuleList {
+		ruleList := &config.RuleList[i]
+		for _, group := range ruleList.Group {
+			if group == "*" || contains(groups, group) {
+				session.ruleLists = append(session.ruleLists, ruleList)
+				break
+			}
+		}
+	}
+
+	return session
+}
+
+// execAllowed checks the exec access to a protocol operation (RFC 8341 section 3.4.4)
+func (s *nacmSession) execAllowed(module string, rpc string) bool {
+
+	if s == nil || rpc == "close-session" {
+		return true
+	}
+
+	for _, ruleList := range s.ruleLists {
+		for _, rule := range ruleList.Rule {
+			if !rule.matchModule(module) || !rule.allows(NacmExec) {
+				continue
+			}
+			if rule.NotificationName != "" || rule.Path != "" {
+				continue
+			}
+			if rule.RPCName != "" && rule.RPCName != "*" && rule.RPCName != rpc {
+				continue
+			}
+			return rule.Action == NacmPermit
+		}
+	}
+
+	if module == "ietf-netconf" && contains(nacmDenyAllRPCs, rpc) {
+		return false
+	}
+
+	return s.config.defaultAction(NacmExec) == NacmPermit
+}
+
+// dataAllowed checks an access to a data node (RFC 8341 sections 3.4.5 and 3.4.6)
+func (s *nacmSession) dataAllowed(module string, path []nacmStep, access string) bool {
+
+	if s == nil {
+		return true
+	}
+
+	for _, ruleList := range s.ruleLists {
+		for _, rule := range ruleList.Rule {
+			if !rule.matchModule(module) || !rule.allows(access) {
+				continue
+			}
+			if rule.RPCName != "" || rule.NotificationName != "" {
+				continue
+			}
+			if rule.Path != "" && !matchNacmPath(parseNacmPath(rule.Path), path) {
+				continue
+			}
+			return rule.Action == NacmPermit
+		}
+	}
+
+	if contains(nacmDenyAllModules, module) {
+		return false
+	}
+
+	for _, step := range path {
+		if contains(nacmDenyAllNodes, step.name) {
+			return false
+		}
+	}
+
+	return s.config.defaultAction(access) == NacmPermit
+}
+
+func nacmAccessDenied(message string, path string) error {
+	err := NewRPCError(ErrorTypeApplication, ErrorTagAccessDenied, message)
+	err.ErrorPath = path
+	return err
+}
+
+// checkExec rejects a protocol operation the user may not execute
+func (s *nacmSession) checkExec(operation *xmlquery.Node) error {
+
+	module := nacmModule(operation.NamespaceURI, "")
+
+	if s.execAllowed(module, operation.Data) {
+		return nil
+	}
+
+	atomic.AddUint32(&nacmDeniedOperations, 1)
+	glog.Infof("[NACM] exec of %s:%s denied to user=%s", module, operation.Data, s.username)
+
+	err := NewRPCError(ErrorTypeProtocol, ErrorTagAccessDenied, "Access denied to "+operation.Data)
+	err.ErrorInfo.BadElement = operation.Data
+	return err
+}
+
+// checkDatastore checks an access to a whole datastore, only the rules of
+// all modules without path apply
+func (s *nacmSession) checkDatastore(access string, datastore string) error {
+
+	if s.dataAllowed("*", nil, access) {
+		return nil
+	}
+
+	if access != NacmRead {
+		atomic.AddUint32(&nacmDeniedDataWrites, 1)
+	}
+
+	glog.Infof("[NACM] %s of %s denied to user=%s", access, datastore, s.username)
+
+	return nacmAccessDenied("Access denied to "+datastore, "")
+}
+
+// checkConfig checks the write access to the nodes changed by replacing the
+// current configuration of a datastore: new nodes are created, changed leafs
+// updated and missing nodes deleted
+func (s *nacmSession) checkConfig(datastore string, current ConfigDb, config ConfigDb) error {
+
+	if s == nil {
+		return nil
+	}
+
+	created, updated, deleted := configDbChanges(current, config)
+	tree := SchemaTree()
+
+	for _, change := range []struct {
+		access string
+		config ConfigDb
+	}{{NacmCreate, created}, {NacmUpdate, updated}, {NacmDelete, deleted}} {
+		if _, path := s.checkPayload("", nil, configDbPayload(change.config, tree), change.access); path != "" {
+			atomic.AddUint32(&nacmDeniedDataWrites, 1)
+			glog.Infof("[NACM] %s of %s in %s denied to user=%s", change.access, path, datastore, s.username)
+			return nacmAccessDenied("Access denied to "+path, path)
+		}
+	}
+
+	return nil
+}
+
+// pruneRead silently removes the nodes of a reply the user may not read
+func (s *nacmSession) pruneRead(data string) string {
+
+	if s == nil || data == "" {
+		return data
+	}
+
+	doc, err := xmlquery.Parse(strings.NewReader("<nacm-data>" + data + "</nacm-data>"))
+	if err != nil {
+		glog.Errorf("[NACM] Unable to parse reply data, nothing returned: %v", err)
+		return ""
+	}
+
+	root := xmlquery.FindOne(doc, "nacm-data")
+
+	pruned := false
+	for _, node := range elementChildren(root) {
+		if s.pruneNode(node, node.Data, nil) {
+			pruned = true
+		}
+	}
+
+	if !pruned {
+		return data
+	}
+
+	var b strings.Builder
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		writeXMLNode(&b, child)
+	}
+
+	return b.String()
+}
+
+func (s *nacmSession) pruneNode(node *xmlquery.Node, module string, parent []nacmStep) bool {
+
+	module = nacmModule(node.NamespaceURI, module)
+
+	path := append(parent[:len(parent):len(parent)], nacmStep{name: node.Data, key: xmlKeys(node)})
+
+	if !s.dataAllowed(module, path, NacmRead) {
+		removeNode(node)
+		return true
+	}
+
+	pruned := false
+	for _, child := range elementChildren(node) {
+		if s.pruneNode(child, module, path) {
+			pruned = true
+		}
+	}
+
+	return pruned
+}
+
+// checkEdit checks the write access to the nodes of a translib write
+func (s *nacmSession) checkEdit(edit dataEdit) error {
+
+	if s == nil {
+		return nil
+	}
+
+	target := parseNacmPath(edit.path)
+	module := ""
+	if parts := strings.SplitN(strings.TrimPrefix(edit.path, "/"), ":", 2); len(parts) == 2 {
+		module = parts[0]
+	}
+
+	steps := []nacmStep{}
+	for _, step := range target {
+		steps = append(steps, nacmStep{name: step.name, key: pathKeys(step.keys)})
+	}
+
+	denied := func(path string) error {
+		atomic.AddUint32(&nacmDeniedDataWrites, 1)
+		glog.Infof("[NACM] %s of %s denied to user=%s", edit.operation, path, s.username)
+		return nacmAccessDenied("Access denied to "+path, path)
+	}
+
+	if edit.operation == OperationDelete || edit.operation == OperationRemove {
+		if !s.dataAllowed(module, steps, NacmDelete) {
+			return denied(edit.path)
+		}
+		return nil
+	}
+
+	var payload map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(edit.payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err != nil {
+		return err
+	}
+
+	// The payload holds the target node, except for create which targets the parent
+	parent := steps
+	if edit.operation != OperationCreate && len(parent) > 0 {
+		parent = parent[:len(parent)-1]
+	}
+
+	access := NacmCreate
+	if edit.operation != OperationCreate {
+		_, updatePath := s.checkPayload(module, parent, payload, NacmUpdate)
+		_, createPath := s.checkPayload(module, parent, payload, NacmCreate)
+		switch {
+		case updatePath == "" && createPath == "":
+			return nil
+		case updatePath != "" && createPath != "":
+			access = NacmUpdate
+		default:
+			// Existing nodes are updated, others are created
+			access = NacmCreate
+			if _, err := translib.Get(translib.GetRequest{Path: edit.path}); err == nil {
+				access = NacmUpdate
+			}
+		}
+	}
+
+	if _, path := s.checkPayload(module, parent, payload, access); path != "" {
+		return denied(path)
+	}
+
+	return nil
+}
+
+// checkPayload walks an RFC 7951 JSON payload, returning the path of the
+// first node the access is denied to
+func (s *nacmSession) checkPayload(module string, parent []nacmStep, object map[string]interface{}, access string) (bool, string) {
+
+	for name, value := range object {
+
+		nodeModule := module
+		if i := strings.Index(name, ":"); i >= 0 {
+			nodeModule, name = name[:i], name[i+1:]
+		}
+
+		entries, isList := value.([]interface{})
+		if !isList {
+			entries = []interface{}{value}
+		}
+
+		for _, entry := range entries {
+
+			path := append(parent[:len(parent):len(parent)], nacmStep{name: name, key: jsonKeys(entry)})
+
+			if !s.dataAllowed(nodeModule, path, access) {
+				return false, nacmStepsString(path)
+			}
+
+			if child, ok := entry.(map[string]interface{}); ok {
+				if allowed, denied := s.checkPayload(nodeModule, path, child, access); !allowed {
+					return false, denied
+				}
+			}
+		}
+	}
+
+	return true, ""
+}
+
+func nacmStepsString(path []nacmStep) string {
+	names := []string{}
+	for _, step := range path {
+		names = append(names, step.name)
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+// planNacm checks an edit of the nacm container, not handled by translib, and
+// returns the resulting configuration. It is stored with storeNacm once the
+// rest of the edit is authorized
+func planNacm(session *nacmSession, node *xmlquery.Node, defaultOperation string) (*Nacm, error) {
+
+	operation, err := operationAttr(node)
+	if err != nil {
+		return nil, err
+	}
+	if operation == "" {
+		operation = defaultOperation
+	}
+
+	access := NacmUpdate
+	if operation == OperationDelete || operation == OperationRemove {
+		access = NacmDelete
+	}
+
+	if !session.dataAllowed("ietf-netconf-acm", []nacmStep{{name: "nacm"}}, access) {
+		atomic.AddUint32(&nacmDeniedDataWrites, 1)
+		glog.Infof("[NACM] %s of nacm denied to user=%s", operation, session.username)
+		return nil, nacmAccessDenied("Access denied to nacm", "/ietf-netconf-acm:nacm")
+	}
+
+	switch operation {
+	case OperationDelete, OperationRemove:
+		return &Nacm{}, nil
+	case OperationReplace, OperationCreate:
+		return parseNacm(node)
+	}
+
+	edit, err := parseNacm(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeNacm(currentNacm(), edit), nil
+}
+
+// removeNode detaches a node from its parent
+func removeNode(node *xmlquery.Node) {
+
+	if node.PrevSibling != nil {
+		node.PrevSibling.NextSibling = node.NextSibling
+	} else if node.Parent != nil {
+		node.Parent.FirstChild = node.NextSibling
+	}
+
+	if node.NextSibling != nil {
+		node.NextSibling.PrevSibling = node.PrevSibling
+	} else if node.Parent != nil {
+		node.Parent.LastChild = node.PrevSibling
+	}
+
+	node.Parent, node.PrevSibling, node.NextSibling = nil, nil, nil
+}
+
+// writeXMLNode encodes a parsed node back, keeping its prefixes and attributes
+func writeXMLNode(b *strings.Builder, node *xmlquery.Node) {
+
+	switch node.Type {
+	case xmlquery.TextNode, xmlquery.CharDataNode:
+		b.WriteString(xmlEscape(node.Data))
+		return
+	case xmlquery.ElementNode:
+	default:
+		return
+	}
+
+	name := node.Data
+	if node.Prefix != "" {
+		name = node.Prefix + ":" + name
+	}
+
+	b.WriteString("<" + name)
+
+	for _, attr := range node.Attr {
+		attrName := attr.Name.Local
+		if attr.Name.Space != "" {
+			attrName = attr.Name.Space + ":" + attrName
+		}
+		b.WriteString(" " + attrName + "=\"" + xmlEscapeAttr(attr.Value) + "\"")
+	}
+
+	if node.FirstChild == nil {
+		b.WriteString("/>")
+		return
+	}
+
+	b.WriteString(">")
+
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeXMLNode(b, child)
+	}
+
+	b.WriteString("</" + name + ">")
+}
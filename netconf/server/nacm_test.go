@@ -0,0 +1,287 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+)
+
+func init() {
+	fmt.Println("+++++ init nacm_test +++++")
+}
+
+type nacmTestUser struct {
+	TestAuthenticator
+	name   string
+	groups []string
+}
+
+func (u nacmTestUser) Username() string {
+	return u.name
+}
+
+func (u nacmTestUser) Groups() []string {
+	return u.groups
+}
+
+const testNacm = `<nacm xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-acm">
+  <enable-nacm>true</enable-nacm>
+  <write-default>deny</write-default>
+  <groups>
+    <group><name>operators</name><user-name>alice</user-name></group>
+  </groups>
+  <rule-list>
+    <name>operators</name>
+    <group>operators</group>
+    <rule><name>no-edit</name><module-name>ietf-netconf-nmda</module-name><rpc-name>edit-data</rpc-name><access-operations>exec</access-operations><action>deny</action></rule>
+    <rule><name>no-vlan200</name><module-name>sonic-vlan</module-name><path>/sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name='Vlan200']</path><access-operations>read</access-operations><action>deny</action></rule>
+  </rule-list>
+  <rule-list>
+    <name>admins</name>
+    <group>netadmin</group>
+    <rule><name>vlan-write</name><module-name>sonic-vlan</module-name><access-operations>create update delete</access-operations><action>permit</action></rule>
+  </rule-list>
+</nacm>`
+
+func setTestNacm(t *testing.T, config string) {
+
+	doc, err := xmlquery.Parse(strings.NewReader(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nacm, err := parseNacm(xmlquery.FindOne(doc, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nacmMutex.Lock()
+	nacmConfig = nacm
+	nacmMutex.Unlock()
+}
+
+func resetTestNacm() {
+	nacmMutex.Lock()
+	nacmConfig = nil
+	nacmMutex.Unlock()
+}
+
+func TestParseNacmPath(t *testing.T) {
+
+	steps := parseNacmPath("/sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name='Vlan100'][x:mode=\"a/b\"]/members")
+
+	if len(steps) != 4 || steps[0].name != "sonic-vlan" || steps[2].name != "VLAN_LIST" || steps[3].name != "members" {
+		t.Fatalf("Result was incorrect, got: %+v", steps)
+	}
+
+	if steps[2].keys["name"] != "Vlan100" || steps[2].keys["mode"] != "a/b" {
+		t.Errorf("Result was incorrect, got keys: %+v", steps[2].keys)
+	}
+
+	translibSteps := parseNacmPath("/sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name=Vlan100]")
+	if len(translibSteps) != 3 || translibSteps[2].keys["name"] != "Vlan100" {
+		t.Errorf("Result was incorrect, got: %+v", translibSteps)
+	}
+}
+
+func TestNacmExec(t *testing.T) {
+
+	setTestNacm(t, testNacm)
+	defer resetTestNacm()
+
+	alice := nacmFor(nacmTestUser{name: "alice"})
+	bob := nacmFor(nacmTestUser{name: "bob", groups: []string{"netadmin"}})
+
+	if alice.execAllowed("ietf-netconf-nmda", "edit-data") {
+		t.Errorf("Expected edit-data to be denied to alice")
+	}
+
+	if !alice.execAllowed("ietf-netconf-nmda", "get-data") || !bob.execAllowed("ietf-netconf-nmda", "edit-data") {
+		t.Errorf("Expected exec-default to permit get-data and edit-data")
+	}
+
+	if bob.execAllowed("ietf-netconf", "delete-config") || !bob.execAllowed("ietf-netconf", "close-session") {
+		t.Errorf("Expected delete-config to be denied by default and close-session to be permitted")
+	}
+
+	if nacmFor(nacmTestUser{name: NACMRecoveryUser}) != nil {
+		t.Errorf("Expected the recovery session to bypass access control")
+	}
+}
+
+func TestNacmPruneRead(t *testing.T) {
+
+	setTestNacm(t, testNacm)
+	defer resetTestNacm()
+
+	alice := nacmFor(nacmTestUser{name: "alice"})
+
+	data := `<sonic-vlan><VLAN><VLAN_LIST><name>Vlan100</name></VLAN_LIST><VLAN_LIST><name>Vlan200</name></VLAN_LIST></VLAN></sonic-vlan>` +
+		`<sonic-system-tacacs><TACPLUS><global><timeout>5</timeout><passkey>secret</passkey></global></TACPLUS></sonic-system-tacacs>`
+
+	result := alice.pruneRead(data)
+	correct := `<sonic-vlan><VLAN><VLAN_LIST><name>Vlan100</name></VLAN_LIST></VLAN></sonic-vlan>` +
+		`<sonic-system-tacacs><TACPLUS><global><timeout>5</timeout></global></TACPLUS></sonic-system-tacacs>`
+
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+
+	if nacmXMLRead := alice.pruneRead(nacmXML()); nacmXMLRead != "" {
+		t.Errorf("Expected the nacm container to be hidden, got: %s", nacmXMLRead)
+	}
+}
+
+func TestNacmCheckEdit(t *testing.T) {
+
+	setTestNacm(t, testNacm)
+	defer resetTestNacm()
+
+	alice := nacmFor(nacmTestUser{name: "alice"})
+	bob := nacmFor(nacmTestUser{name: "bob", groups: []string{"netadmin"}})
+
+	remove := dataEdit{operation: OperationDelete, path: "/sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name=Vlan100]"}
+	merge := dataEdit{
+		operation: OperationMerge,
+		path:      "/sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name=Vlan100]",
+		payload:   []byte(`{"VLAN_LIST":[{"name":"Vlan100","vlanid":100}]}`),
+	}
+
+	err := alice.checkEdit(remove)
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != ErrorTagAccessDenied {
+		t.Errorf("Expected access-denied, got: %v", err)
+	}
+
+	if err := bob.checkEdit(remove); err != nil {
+		t.Errorf("Expected delete to be permitted, got: %v", err)
+	}
+
+	if err := bob.checkEdit(merge); err != nil {
+		t.Errorf("Expected merge to be permitted, got: %v", err)
+	}
+
+	tacacs := dataEdit{
+		operation: OperationMerge,
+		path:      "/sonic-system-tacacs:sonic-system-tacacs/TACPLUS",
+		payload:   []byte(`{"TACPLUS":{"global":{"passkey":"secret"}}}`),
+	}
+
+	if err := bob.checkEdit(tacacs); err == nil {
+		t.Errorf("Expected write-default to deny the TACACS+ edit")
+	}
+}
+
+func TestEditNacm(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "nacm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedPath := NACMConfigPath
+	defer func() { NACMConfigPath = savedPath }()
+
+	NACMConfigPath = filepath.Join(dir, "nacm.xml")
+	resetTestNacm()
+	defer resetTestNacm()
+
+	if currentNacm().enabled() {
+		t.Fatalf("Expected NACM to be disabled without configuration")
+	}
+
+	editNacm := func(node *xmlquery.Node) error {
+		config, err := planNacm(nil, node, OperationMerge)
+		if err != nil {
+			return err
+		}
+		return storeNacm(config)
+	}
+
+	doc, _ := xmlquery.Parse(strings.NewReader(testNacm))
+	if err := editNacm(xmlquery.FindOne(doc, "*")); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := `<nacm xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-acm"><groups><group><name>operators</name><user-name>carol</user-name></group></groups></nacm>`
+	doc, _ = xmlquery.Parse(strings.NewReader(edit))
+	if err := editNacm(xmlquery.FindOne(doc, "*")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reload from the file
+	resetTestNacm()
+	config := currentNacm()
+
+	if !config.enabled() || len(config.RuleList) != 2 || len(config.Groups) != 1 {
+		t.Fatalf("Result was incorrect, got: %+v", config)
+	}
+
+	if users := config.Groups[0].UserName; len(users) != 2 || users[1] != "carol" {
+		t.Errorf("Result was incorrect, got users: %v", users)
+	}
+}
+
+func TestEditDataNacmNotStoredOnError(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "nacm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedPath := NACMConfigPath
+	defer func() { NACMConfigPath = savedPath }()
+
+	NACMConfigPath = filepath.Join(dir, "nacm.xml")
+	resetTestNacm()
+	defer resetTestNacm()
+
+	request := `<rpc message-id="1" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><edit-data xmlns="` + NsNetconfNmda + `" xmlns:ds="` + NsDatastores + `">
+		<datastore>ds:running</datastore>
+		<config>` + testNacm + `<unknown xmlns="urn:test:unknown"/></config>
+	</edit-data></rpc>`
+
+	doc, _ := xmlquery.Parse(strings.NewReader(request))
+	_, err = EditDataHandler(NewTestAuthenticator(true), doc)
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != ErrorTagUnknownElement {
+		t.Fatalf("Result was incorrect, got: %v, want: %s.", err, ErrorTagUnknownElement)
+	}
+
+	if _, err := os.Stat(NACMConfigPath); !os.IsNotExist(err) {
+		t.Errorf("nacm stored by a rejected edit-data")
+	}
+
+	// Nor when the accounting start record is refused
+	request = `<rpc message-id="2" xmlns="urn:ietf:params:xml:ns:netconf:base:1.0"><edit-data xmlns="` + NsNetconfNmda + `" xmlns:ds="` + NsDatastores + `">
+		<datastore>ds:running</datastore>
+		<config>` + testNacm + `</config>
+	</edit-data></rpc>`
+
+	doc, _ = xmlquery.Parse(strings.NewReader(request))
+	if _, err = EditDataHandler(unaccountedAuthenticator{NewTestAuthenticator(true)}, doc); err == nil {
+		t.Fatal("edit-data succeeded without accounting")
+	}
+
+	if _, err := os.Stat(NACMConfigPath); !os.IsNotExist(err) {
+		t.Errorf("nacm stored without accounting")
+	}
+}
@@ -0,0 +1,354 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"orange/sonic-netconf-server/lib"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/golang/glog"
+)
+
+// Datastores accepted by get-data. Running and intended are CONFIG_DB, SONiC
+// has no template expansion so intended is the same as running (RFC 8342
+// section 5.1.4). Operational is the translib view of CONFIG_DB with the state
+// of APPL_DB and STATE_DB
+var nmdaReadable = []string{DsRunning, DsStartup, DsIntended, DsOperational}
+
+// dataFilter holds the RFC 8526 get-data parameters applied to the collected data
+type dataFilter struct {
+	datastore     string
+	configFilter  *bool
+	maxDepth      int // 0 is unbounded
+	originFilter  []string
+	negatedOrigin bool
+	withOrigin    bool
+}
+
+// dataNode is an element of a get-data reply
+type dataNode struct {
+	name      string
+	namespace string
+	origin    string
+	text      string
+	children  []*dataNode
+}
+
+// identityValue returns the identity of a datastore or origin value with the
+// prefix normalized to the one used in replies
+func identityValue(value string, prefix string) string {
+	value = strings.TrimSpace(value)
+	if i := strings.Index(value, ":"); i >= 0 {
+		value = value[i+1:]
+	}
+	return prefix + ":" + value
+}
+
+// datastoreParam reads the mandatory datastore parameter of get-data and edit-data
+func datastoreParam(node *xmlquery.Node) (string, error) {
+
+	datastore := xmlquery.FindOne(node, "./*[local-name() = 'datastore']")
+	if datastore == nil {
+		err := NewRPCError(ErrorTypeProtocol, ErrorTagMissingElement, "Datastore not specified")
+		err.ErrorInfo.BadElement = "datastore"
+		return "", err
+	}
+
+	return identityValue(datastore.InnerText(), "ds"), nil
+}
+
+func parseGetDataRequest(node *xmlquery.Node) (dataFilter, error) {
+
+	filter := dataFilter{}
+
+	datastore, err := datastoreParam(node)
+	if err != nil {
+		return filter, err
+	}
+
+	if !contains(nmdaReadable, datastore) {
+		err := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Datastore "+datastore+" is not supported")
+		err.ErrorInfo.BadElement = "datastore"
+		return filter, err
+	}
+
+	filter.datastore = datastore
+
+	if xpath := xmlquery.FindOne(node, "./*[local-name() = 'xpath-filter']"); xpath != nil {
+		err := NewRPCError(ErrorTypeProtocol, ErrorTagOperationNotSupported, "XPath filters are not supported")
+		err.ErrorInfo.BadElement = "xpath-filter"
+		return filter, err
+	}
+
+	if configFilter := xmlquery.FindOne(node, "./*[local-name() = 'config-filter']"); configFilter != nil {
+		value, err := strconv.ParseBool(strings.TrimSpace(configFilter.InnerText()))
+		if err != nil {
+			rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Invalid config-filter value")
+			rpcErr.ErrorInfo.BadElement = "config-filter"
+			return filter, rpcErr
+		}
+		filter.configFilter = &value
+	}
+
+	if maxDepth := xmlquery.FindOne(node, "./*[local-name() = 'max-depth']"); maxDepth != nil {
+		value := strings.TrimSpace(maxDepth.InnerText())
+		if value != "unbounded" {
+			depth, err := strconv.ParseUint(value, 10, 16)
+			if err != nil || depth == 0 {
+				rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Invalid max-depth value")
+				rpcErr.ErrorInfo.BadElement = "max-depth"
+				return filter, rpcErr
+			}
+			filter.maxDepth = int(depth)
+		}
+	}
+
+	origins := xmlquery.Find(node, "./*[local-name() = 'origin-filter']")
+	negated := xmlquery.Find(node, "./*[local-name() = 'negated-origin-filter']")
+
+	if len(origins) > 0 && len(negated) > 0 {
+		err := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "origin-filter and negated-origin-filter are exclusive")
+		err.ErrorInfo.BadElement = "negated-origin-filter"
+		return filter, err
+	}
+
+	if len(negated) > 0 {
+		origins = negated
+		filter.negatedOrigin = true
+	}
+
+	for _, origin := range origins {
+		filter.originFilter = append(filter.originFilter, identityValue(origin.InnerText(), "or"))
+	}
+
+	filter.withOrigin = xmlquery.FindOne(node, "./*[local-name() = 'with-origin']") != nil
+
+	// Origin metadata is only defined for the operational datastore
+	if datastore != DsOperational && (filter.withOrigin || len(origins) > 0) {
+		err := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Origin parameters are only supported on "+DsOperational)
+		err.ErrorInfo.BadElement = "with-origin"
+		if len(origins) > 0 {
+			err.ErrorInfo.BadElement = origins[0].Data
+		}
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// GetDataHandler handles the RFC 8526 get-data operation
+func GetDataHandler(authenticator lib.Authenticator, rootNode *xmlquery.Node) (string, error) {
+
+	getData := xmlquery.FindOne(rootNode, "//*[local-name() = 'get-data']")
+
+	filter, err := parseGetDataRequest(getData)
+	if err != nil {
+		return "", err
+	}
+
+	var result string
+
+	if filter.datastore == DsStartup {
+		result, err = startupData(authenticator, "get-data", rootNode)
+	} else {
+		var requests []GetRequest
+		requests, err = ParseGetRequest(rootNode)
+
+		glog.Infof("Extracted get-data requests %+v from %s", requests, filter.datastore)
+
+		if err == nil {
+			result, err = collectData(authenticator, "get-data", rootNode, requests)
+		}
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	doc, err := xmlquery.Parse(strings.NewReader("<data>" + result + "</data>"))
+	if err != nil {
+		glog.Errorf("Unable to parse collected data %v", err)
+		return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to read data")
+	}
+
+	return filter.reply(xmlquery.FindOne(doc, "data"), SchemaTree()), nil
+}
+
+// reply prunes the collected data and wraps it in the get-data reply element
+func (f dataFilter) reply(data *xmlquery.Node, tree map[string]*SchemaNode) string {
+
+	root := "<data xmlns=\"" + NsNetconfNmda + "\""
+	if f.withOrigin {
+		root += " xmlns:or=\"" + NsOrigin + "\""
+	}
+
+	return root + ">" + f.content(data, tree) + "</data>"
+}
+
+// content prunes the collected data according to the datastore and filters
+func (f dataFilter) content(data *xmlquery.Node, tree map[string]*SchemaNode) string {
+
+	var b strings.Builder
+
+	for _, element := range elementChildren(data) {
+		schema := schemaRootNode(tree, element.NamespaceURI, element.Data)
+		if schema == nil {
+			glog.V(1).Infof("No schema for %s %s, returned unfiltered", element.NamespaceURI, element.Data)
+		}
+		if node := f.filter(element, schema, true, 1); node != nil {
+			node.write(&b, "", "", f.withOrigin)
+		}
+	}
+
+	return b.String()
+}
+
+// filter returns the element if it or one of its descendants is selected, nil otherwise
+func (f dataFilter) filter(element *xmlquery.Node, schema *SchemaNode, parentConfig bool, depth int) *dataNode {
+
+	config := parentConfig
+	if schema != nil {
+		config = schema.Config
+	}
+
+	// Conventional datastores only hold configuration
+	if !config && f.datastore != DsOperational {
+		return nil
+	}
+
+	// Configuration is applied from intended, state is provided by the system
+	origin := ""
+	if f.datastore == DsOperational {
+		origin = OriginSystem
+		if config {
+			origin = OriginIntended
+		}
+	}
+
+	node := &dataNode{name: element.Data, namespace: element.NamespaceURI, origin: origin}
+	if schema != nil {
+		node.namespace = schema.Namespace
+	}
+
+	children := elementChildren(element)
+	selected := f.selected(config, origin)
+
+	if len(children) == 0 {
+		if !selected {
+			return nil
+		}
+		node.text = element.InnerText()
+		return node
+	}
+
+	// Keys are part of every list entry that is returned
+	keys := []*dataNode{}
+	if schema != nil && schema.Kind == "list" {
+		for _, child := range children {
+			if key := schema.Child(child.Data); key != nil && key.IsKey() {
+				keys = append(keys, &dataNode{name: child.Data, namespace: key.Namespace, origin: origin, text: child.InnerText()})
+			}
+		}
+	}
+
+	if f.maxDepth == 0 || depth < f.maxDepth {
+		for _, child := range children {
+			childSchema := schema.Child(child.Data)
+			if childSchema != nil && childSchema.IsKey() {
+				continue
+			}
+			if schema != nil && childSchema == nil {
+				glog.V(1).Infof("Unknown node %s under %s", child.Data, schema.Name)
+			}
+			if childNode := f.filter(child, childSchema, config, depth+1); childNode != nil {
+				node.children = append(node.children, childNode)
+			}
+		}
+	}
+
+	if len(node.children) == 0 && !selected {
+		return nil
+	}
+
+	node.children = append(keys, node.children...)
+
+	return node
+}
+
+// selected returns true if a node matches the config and origin filters
+func (f dataFilter) selected(config bool, origin string) bool {
+
+	if f.configFilter != nil && *f.configFilter != config {
+		return false
+	}
+
+	if len(f.originFilter) == 0 {
+		return true
+	}
+
+	// All origin identities derive from or:origin
+	matched := contains(f.originFilter, origin) || contains(f.originFilter, "or:origin")
+
+	return matched != f.negatedOrigin
+}
+
+// write encodes a node, the origin is annotated where it differs from the parent
+func (n *dataNode) write(b *strings.Builder, parentNamespace string, parentOrigin string, withOrigin bool) {
+
+	b.WriteString("<" + n.name)
+
+	if n.namespace != "" && n.namespace != parentNamespace {
+		b.WriteString(" xmlns=\"" + xmlEscapeAttr(n.namespace) + "\"")
+	}
+
+	if withOrigin && n.origin != "" && n.origin != parentOrigin {
+		b.WriteString(" or:origin=\"" + n.origin + "\"")
+	}
+
+	if len(n.children) == 0 && n.text == "" {
+		b.WriteString("/>")
+		return
+	}
+
+	b.WriteString(">")
+
+	if len(n.children) == 0 {
+		b.WriteString(xmlEscape(n.text))
+	}
+
+	namespace := parentNamespace
+	if n.namespace != "" {
+		namespace = n.namespace
+	}
+
+	for _, child := range n.children {
+		child.write(b, namespace, n.origin, withOrigin)
+	}
+
+	b.WriteString("</" + n.name + ">")
+}
+
+// elementChildren returns the element children of a node
+func elementChildren(node *xmlquery.Node) []*xmlquery.Node {
+	children := []*xmlquery.Node{}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xmlquery.ElementNode {
+			children = append(children, child)
+		}
+	}
+	return children
+}
@@ -0,0 +1,137 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+)
+
+func init() {
+	fmt.Println("+++++ init nmda_test +++++")
+}
+
+const testInterfacesData = `<data><interfaces xmlns="urn:test:interfaces">
+	<interface>
+		<name>Ethernet0</name>
+		<mtu>9100</mtu>
+		<description xmlns="urn:test:augment">uplink &amp; spine</description>
+		<counters><in-octets>42</in-octets></counters>
+	</interface>
+</interfaces></data>`
+
+func getDataRequest(body string) *xmlquery.Node {
+	node, _ := xmlquery.Parse(strings.NewReader(`<get-data xmlns="` + NsNetconfNmda + `" xmlns:ds="` + NsDatastores + `" xmlns:or="` + NsOrigin + `">` + body + `</get-data>`))
+	return xmlquery.FindOne(node, "get-data")
+}
+
+func getDataReply(t *testing.T, body string) string {
+
+	filter, err := parseGetDataRequest(getDataRequest(body))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	data, _ := xmlquery.Parse(strings.NewReader(testInterfacesData))
+
+	return filter.reply(xmlquery.FindOne(data, "data"), testSchemaTree())
+}
+
+func TestParseGetDataRequest(t *testing.T) {
+
+	errorTags := map[string]string{
+		"":                                    ErrorTagMissingElement,
+		"<datastore>ds:candidate</datastore>": ErrorTagInvalidValue,
+		"<datastore>ds:running</datastore><with-origin/>":                                                                                         ErrorTagInvalidValue,
+		"<datastore>ds:intended</datastore><origin-filter>or:intended</origin-filter>":                                                            ErrorTagInvalidValue,
+		"<datastore>ds:operational</datastore><origin-filter>or:system</origin-filter><negated-origin-filter>or:intended</negated-origin-filter>": ErrorTagInvalidValue,
+		"<datastore>ds:running</datastore><max-depth>0</max-depth>":                                                                               ErrorTagInvalidValue,
+		"<datastore>ds:running</datastore><xpath-filter>/a</xpath-filter>":                                                                        ErrorTagOperationNotSupported,
+	}
+
+	for body, tag := range errorTags {
+		_, err := parseGetDataRequest(getDataRequest(body))
+		if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != tag {
+			t.Errorf("Result was incorrect for %s, got: %v, want: %s.", body, err, tag)
+		}
+	}
+
+	filter, err := parseGetDataRequest(getDataRequest("<datastore>ds:startup</datastore><max-depth>2</max-depth><config-filter>true</config-filter>"))
+	if err != nil || filter.datastore != DsStartup || filter.maxDepth != 2 || !*filter.configFilter {
+		t.Errorf("Result was incorrect, got: %+v %v", filter, err)
+	}
+
+	filter, err = parseGetDataRequest(getDataRequest("<datastore>ds:operational</datastore><negated-origin-filter>intended</negated-origin-filter><with-origin/>"))
+	if err != nil || !filter.negatedOrigin || filter.originFilter[0] != OriginIntended || !filter.withOrigin {
+		t.Errorf("Result was incorrect, got: %+v %v", filter, err)
+	}
+}
+
+func TestGetDataReply(t *testing.T) {
+
+	result := getDataReply(t, "<datastore>ds:running</datastore>")
+	correct := `<data xmlns="` + NsNetconfNmda + `"><interfaces xmlns="urn:test:interfaces"><interface><name>Ethernet0</name><mtu>9100</mtu><description xmlns="urn:test:augment">uplink &#38; spine</description></interface></interfaces></data>`
+
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+
+	result = getDataReply(t, "<datastore>ds:running</datastore><config-filter>false</config-filter>")
+	if result != `<data xmlns="`+NsNetconfNmda+`"></data>` {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+
+	result = getDataReply(t, "<datastore>ds:running</datastore><max-depth>2</max-depth>")
+	if !strings.Contains(result, "<interface><name>Ethernet0</name></interface>") {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+
+	result = getDataReply(t, "<datastore>ds:running</datastore><max-depth>3</max-depth>")
+	if !strings.Contains(result, "<mtu>9100</mtu>") || strings.Contains(result, "counters") {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+
+	if result := getDataReply(t, "<datastore>ds:intended</datastore>"); result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+
+	result = getDataReply(t, "<datastore>ds:operational</datastore><config-filter>false</config-filter>")
+	if !strings.Contains(result, "<interface><name>Ethernet0</name><counters><in-octets>42</in-octets></counters></interface>") || strings.Contains(result, "<mtu>") {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+
+	result = getDataReply(t, "<datastore>ds:operational</datastore><max-depth>3</max-depth>")
+	if !strings.Contains(result, "<mtu>9100</mtu>") || !strings.Contains(result, "<counters/>") {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+
+	result = getDataReply(t, "<datastore>ds:operational</datastore><with-origin/>")
+	if !strings.Contains(result, `xmlns:or="`+NsOrigin+`"`) || !strings.Contains(result, `<interfaces xmlns="urn:test:interfaces" or:origin="or:intended">`) ||
+		!strings.Contains(result, `<counters or:origin="or:system">`) || strings.Contains(result, `<mtu or:origin`) {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+
+	result = getDataReply(t, "<datastore>ds:operational</datastore><origin-filter>or:system</origin-filter>")
+	if strings.Contains(result, "<mtu>") || !strings.Contains(result, "<in-octets>42</in-octets>") {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+
+	result = getDataReply(t, "<datastore>ds:operational</datastore><negated-origin-filter>or:system</negated-origin-filter>")
+	if !strings.Contains(result, "<mtu>9100</mtu>") || strings.Contains(result, "counters") {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+}
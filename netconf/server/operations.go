@@ -19,6 +19,7 @@ const (
 	RPCGetRequest       = "GET"
 	RPCGetConfigRequest = "GET-Config"
 	RPCGetSchemas       = "/netconf-state:netconf-state/schemas"
+	RPCGetStatistics    = "/netconf-state:netconf-state/statistics"
 	RPCGetYangModules   = "/modules-state:modules-state[xmlns=urn:ietf:params:xml:ns:yang:ietf-yang-library]"
 
 	RPCDelimiter   = "]]>]]>"
@@ -26,8 +27,15 @@ const (
 
 	ChunkedMessage = "\n#%d\n%s\n##\n"
 
+	NsNetconfBase       = "urn:ietf:params:xml:ns:netconf:base:1.0"
 	NsNetconfMonitoring = "urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring"
+	NsYangLibrary       = "urn:ietf:params:xml:ns:yang:ietf-yang-library"
+	NsDatastores        = "urn:ietf:params:xml:ns:yang:ietf-datastores"
+	NsNetconfNmda       = "urn:ietf:params:xml:ns:yang:ietf-netconf-nmda"
+	NsOrigin            = "urn:ietf:params:xml:ns:yang:ietf-origin"
 	NsTailfActions      = "http://tail-f.com/ns/netconf/actions/1.0"
+	NsNetconfAcm        = "urn:ietf:params:xml:ns:yang:ietf-netconf-acm"
+	NsNetconfLogin      = "urn:orange:params:xml:ns:yang:sonic-netconf-login"
 
 	CapNetconf10       = "urn:ietf:params:netconf:base:1.0"
 	CapNetconf11       = "urn:ietf:params:netconf:base:1.1"
@@ -42,8 +50,41 @@ const (
 	CapRollbackOnError = "urn:ietf:params:netconf:capability:rollback-on-error:1.0"
 	CapURL             = "urn:ietf:params:netconf:capability:url:1.0"
 	CapXPath           = "urn:ietf:params:netconf:capability:xpath:1.0"
+	CapYangLibrary10   = "urn:ietf:params:netconf:capability:yang-library:1.0"
+	CapYangLibrary11   = "urn:ietf:params:netconf:capability:yang-library:1.1"
 	CapMonitoring      = NsNetconfMonitoring
 	CapTailfActions    = NsTailfActions
+
+	YangLibraryRevision  = "2019-01-04"
+	NetconfNmdaRevision  = "2019-01-07"
+	OriginRevision       = "2018-02-14"
+	NetconfLoginRevision = "2026-10-18"
+
+	DsRunning     = "ds:running"
+	DsStartup     = "ds:startup"
+	DsIntended    = "ds:intended"
+	DsOperational = "ds:operational"
+
+	OriginIntended = "or:intended"
+	OriginSystem   = "or:system"
+
+	ErrorTypeTransport   = "transport"
+	ErrorTypeRPC         = "rpc"
+	ErrorTypeProtocol    = "protocol"
+	ErrorTypeApplication = "application"
+
+	ErrorTagInvalidValue          = "invalid-value"
+	ErrorTagMissingElement        = "missing-element"
+	ErrorTagBadElement            = "bad-element"
+	ErrorTagUnknownElement        = "unknown-element"
+	ErrorTagBadAttribute          = "bad-attribute"
+	ErrorTagOperationFailed       = "operation-failed"
+	ErrorTagDataExists            = "data-exists"
+	ErrorTagDataMissing           = "data-missing"
+	ErrorTagAccessDenied          = "access-denied"
+	ErrorTagOperationNotSupported = "operation-not-supported"
+
+	ErrorAppTagDataNotUnique = "data-not-unique"
 )
 
 type RPCError struct {
@@ -63,6 +104,20 @@ type RPCError struct {
 	} `xml:"error-info"`
 }
 
+// NewRPCError returns an error reported to the client with the given type and tag
+func NewRPCError(errorType string, errorTag string, message string) *RPCError {
+	return &RPCError{
+		ErrorType:     errorType,
+		ErrorTag:      errorTag,
+		ErrorSeverity: "error",
+		ErrorMessage:  message,
+	}
+}
+
+func (e *RPCError) Error() string {
+	return e.ErrorMessage
+}
+
 type Filter struct {
 	Type    string `xml:"urn:ietf:params:xml:ns:netconf:base:1.0 type,attr,omitempty"`
 	Select  string `xml:"select,attr,omitempty"`
@@ -96,24 +151,96 @@ type Schema struct {
 type ModulesState struct {
 	XMLName     xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-library modules-state"`
 	ModuleSetId *string  `xml:"module-set-id"`
-	Modules     []Module `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-library modules"`
+	Modules     []Module `xml:"module"`
 }
 
 type Module struct {
-	XMLName         xml.Name `xml:"module"`
-	Name            *string  `xml:",name"`
-	ConformanceType string   `xml:"conformance-type"`
-	Feature         []string `xml:"feature"`
-	Namespace       *string  `xml:"namespace"`
-	Revision        *string  `xml:"revision"`
-	Schema          *string  `xml:"schema"`
-	// Submodule       Module `xml:"submodule"`
-	// Deviation       map[ModuleKey]*ModuleKey `xml:"deviation"`
+	XMLName         xml.Name    `xml:"module"`
+	Name            *string     `xml:"name"`
+	Revision        *string     `xml:"revision"`
+	Schema          *string     `xml:"schema"`
+	Namespace       *string     `xml:"namespace"`
+	Feature         []string    `xml:"feature"`
+	Deviation       []ModuleRef `xml:"deviation"`
+	ConformanceType string      `xml:"conformance-type"`
+	Submodule       []ModuleRef `xml:"submodule"`
+}
+
+type ModuleRef struct {
+	Name     string `xml:"name"`
+	Revision string `xml:"revision"`
+}
+
+// YangLibrary is the RFC 8525 yang-library container
+type YangLibrary struct {
+	XMLName    xml.Name           `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-library yang-library"`
+	ModuleSets []YangLibModuleSet `xml:"module-set"`
+	Schemas    []YangLibSchema    `xml:"schema"`
+	Datastores []YangLibDatastore `xml:"datastore"`
+	ContentId  string             `xml:"content-id"`
+}
+
+type YangLibModuleSet struct {
+	Name              string          `xml:"name"`
+	Modules           []YangLibModule `xml:"module"`
+	ImportOnlyModules []YangLibModule `xml:"import-only-module"`
+}
+
+type YangLibModule struct {
+	Name       string          `xml:"name"`
+	Revision   string          `xml:"revision,omitempty"`
+	Namespace  string          `xml:"namespace,omitempty"`
+	Submodules []YangLibModule `xml:"submodule"`
+	Features   []string        `xml:"feature"`
+	Deviations []string        `xml:"deviation"`
+}
+
+type YangLibSchema struct {
+	Name       string   `xml:"name"`
+	ModuleSets []string `xml:"module-set"`
+}
+
+type YangLibDatastore struct {
+	Name   DatastoreName `xml:"name"`
+	Schema string        `xml:"schema"`
+}
+
+// DatastoreName is a datastore identity, e.g. ds:running
+type DatastoreName struct {
+	Ns    string `xml:"xmlns:ds,attr"`
+	Value string `xml:",chardata"`
 }
 
 type State struct {
-	XMLName xml.Name `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring netconf-state"`
-	Schemas []Schema `xml:"schemas>schema"`
+	XMLName    xml.Name    `xml:"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring netconf-state"`
+	Schemas    []Schema    `xml:"schemas>schema"`
+	Statistics *Statistics `xml:"statistics,omitempty"`
+}
+
+type Statistics struct {
+	NetconfStartTime string           `xml:"netconf-start-time"`
+	InBadHellos      uint64           `xml:"in-bad-hellos"`
+	InSessions       uint64           `xml:"in-sessions"`
+	DroppedSessions  uint64           `xml:"dropped-sessions"`
+	InRpcs           uint64           `xml:"in-rpcs"`
+	InBadRpcs        uint64           `xml:"in-bad-rpcs"`
+	OutRpcErrors     uint64           `xml:"out-rpc-errors"`
+	OutNotifications uint64           `xml:"out-notifications"`
+	Login            *LoginStatistics `xml:"login,omitempty"`
+}
+
+// Brute force protection counters, sonic-netconf-login augmentation
+type LoginStatistics struct {
+	XMLName             xml.Name `xml:"urn:orange:params:xml:ns:yang:sonic-netconf-login login"`
+	SuccessfulLogins    uint64   `xml:"successful-logins"`
+	FailedLogins        uint64   `xml:"failed-logins"`
+	Lockouts            uint64   `xml:"lockouts"`
+	LockedOutLogins     uint64   `xml:"locked-out-logins"`
+	RejectedConnections uint64   `xml:"rejected-connections"`
+	RejectedSessions    uint64   `xml:"rejected-sessions"`
+	LockedSources       uint32   `xml:"locked-sources"`
+	LockedUsers         uint32   `xml:"locked-users"`
+	Unauthenticated     uint32   `xml:"unauthenticated-connections"`
 }
 
 type GetSchema struct {
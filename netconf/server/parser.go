@@ -42,7 +42,7 @@ func ParseGetRequest(node *xmlquery.Node) ([]GetRequest, error) {
 	// TODO: request path creation assumes parent -> one child structure in filter tag, validation required
 
 	// Start with filter node
-	filterNode := xmlquery.FindOne(node, "//filter")
+	filterNode := findFilter(node)
 
 	if filterNode == nil {
 		return []GetRequest{}, errors.New("[Missing data] Need filter element. Complete configuration retrival currently not supported")
@@ -152,6 +152,11 @@ func ParseGetRequest(node *xmlquery.Node) ([]GetRequest, error) {
 	return queryPaths, nil
 } 
 
+// findFilter returns the subtree filter of a get, get-config or get-data request
+func findFilter(node *xmlquery.Node) *xmlquery.Node {
+	return xmlquery.FindOne(node, "//*[local-name() = 'filter' or local-name() = 'subtree-filter']")
+}
+
 func ParseGetSchemaRequest(node *xmlquery.Node) (GetSchema, error) {
 
 	identifier := xmlquery.FindOne(node, "//identifier/text()")
@@ -0,0 +1,270 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+// SchemaNode is a data node of the YANG schema tree, choices and cases are
+// transparent and their children are part of the enclosing node
+type SchemaNode struct {
+	Name      string
+	Module    string // Module the node is defined in
+	Namespace string
+	Kind      string // container, list, leaf, leaf-list, anydata or anyxml
+	Config    bool
+	Keys      []string
+	Type      string // Built-in type of leafs, leafref path is kept in Path
+	Path      string
+	Parent    *SchemaNode
+	Children  map[string]*SchemaNode
+}
+
+// Child returns the child node with the given name, nil if none
+func (n *SchemaNode) Child(name string) *SchemaNode {
+	if n == nil {
+		return nil
+	}
+	return n.Children[name]
+}
+
+// IsKey returns true if the node is a key leaf of its parent list
+func (n *SchemaNode) IsKey() bool {
+	return n.Parent != nil && n.Parent.Kind == "list" && contains(n.Parent.Keys, n.Name)
+}
+
+// LeafType returns the built-in type of a leaf, following leafrefs
+func (n *SchemaNode) LeafType() string {
+	node := n
+	for i := 0; node != nil && node.Type == "leafref" && i < 16; i++ {
+		node = node.leafrefTarget()
+	}
+	if node == nil || node.Type == "leafref" {
+		return "string"
+	}
+	return node.Type
+}
+
+func (n *SchemaNode) leafrefTarget() *SchemaNode {
+
+	path := n.Path
+	node := n
+
+	if strings.HasPrefix(path, "/") {
+		for node != nil && node.Parent != nil {
+			node = node.Parent
+		}
+	}
+
+	for _, step := range strings.Split(path, "/") {
+		if i := strings.Index(step, "["); i >= 0 {
+			step = step[:i]
+		}
+		if i := strings.Index(step, ":"); i >= 0 {
+			step = step[i+1:]
+		}
+		step = strings.TrimSpace(step)
+
+		switch step {
+		case "", ".":
+		case "..":
+			if node != nil {
+				node = node.Parent
+			}
+		default:
+			node = node.Child(step)
+		}
+
+		if node == nil {
+			return nil
+		}
+	}
+
+	return node
+}
+
+// schemaNodeKinds maps the goyang entry kinds of data nodes to their statement
+var schemaNodeKinds = map[yang.EntryKind]string{
+	yang.AnyDataEntry: "anydata",
+	yang.AnyXMLEntry:  "anyxml",
+}
+
+// schemaChildren adds the data nodes under entry to parent, choices and cases
+// are flattened into their enclosing node
+func schemaChildren(ms *yang.Modules, parent *SchemaNode, entry *yang.Entry) {
+
+	for _, child := range entry.Dir {
+
+		if child.RPC != nil || child.Kind == yang.NotificationEntry {
+			continue
+		}
+
+		if child.IsChoice() || child.IsCase() {
+			schemaChildren(ms, parent, child)
+			continue
+		}
+
+		node := &SchemaNode{
+			Name:      child.Name,
+			Module:    parent.Module,
+			Namespace: child.Namespace().Name,
+			Config:    !child.ReadOnly(),
+			Parent:    parent,
+			Children:  map[string]*SchemaNode{},
+		}
+
+		// Nodes added by an augment or a grouping belong to the module
+		// whose namespace they are instantiated in
+		if module, err := ms.FindModuleByNamespace(node.Namespace); err == nil {
+			node.Module = module.Name
+		}
+
+		switch {
+		case child.IsList():
+			node.Kind = "list"
+			node.Keys = strings.Fields(child.Key)
+		case child.IsLeafList():
+			node.Kind = "leaf-list"
+		case child.IsLeaf():
+			node.Kind = "leaf"
+		case schemaNodeKinds[child.Kind] != "":
+			node.Kind = schemaNodeKinds[child.Kind]
+		default:
+			node.Kind = "container"
+		}
+
+		if node.Kind == "leaf" || node.Kind == "leaf-list" {
+			node.Type = "string"
+			if child.Type != nil {
+				node.Type = child.Type.Kind.String()
+				if child.Type.Kind == yang.Yleafref {
+					node.Path = child.Type.Path
+				}
+			}
+		}
+
+		parent.Children[node.Name] = node
+
+		schemaChildren(ms, node, child)
+	}
+}
+
+// buildSchemaTree creates the schema tree of the given modules from the
+// processed goyang modules, augments and groupings are already applied
+func buildSchemaTree(ms *yang.Modules, names []string) map[string]*SchemaNode {
+
+	for _, err := range ms.Process() {
+		glog.V(2).Infof("YANG processing: %v", err)
+	}
+
+	roots := map[string]*SchemaNode{}
+
+	for _, name := range names {
+		module, ok := ms.Modules[name]
+		if !ok {
+			continue
+		}
+
+		entry := yang.ToEntry(module)
+		for _, err := range entry.Errors {
+			glog.Warningf("Schema of %s: %v", name, err)
+		}
+
+		root := &SchemaNode{Name: name, Module: name, Kind: "module", Config: true, Children: map[string]*SchemaNode{}}
+		if module.Namespace != nil {
+			root.Namespace = module.Namespace.Name
+		}
+
+		schemaChildren(ms, root, entry)
+
+		roots[name] = root
+	}
+
+	return roots
+}
+
+// readSchemaModules parses the YANG files of all the modules known to the
+// server, imports and includes not listed are searched in YangModelDir
+func readSchemaModules() *yang.Modules {
+
+	yang.AddPath(YangModelDir)
+	ms := yang.NewModules()
+
+	for _, mod := range YangModules.Modules {
+		path := yangFilePath(*mod.Name, "")
+		for _, schema := range YangSchemas[strings.ToLower(*mod.Name)] {
+			if schema.Format == "yang" {
+				path = schema.ModelPath
+				break
+			}
+		}
+		if err := ms.Read(path); err != nil {
+			glog.V(2).Infof("YANG module %s not loaded: %v", *mod.Name, err)
+		}
+	}
+
+	return ms
+}
+
+var (
+	schemaTree     map[string]*SchemaNode
+	schemaTreeLock sync.Mutex
+)
+
+// SchemaTree returns the schema tree of the implemented modules keyed by module name
+func SchemaTree() map[string]*SchemaNode {
+
+	schemaTreeLock.Lock()
+	defer schemaTreeLock.Unlock()
+
+	if schemaTree != nil {
+		return schemaTree
+	}
+
+	if !yangModulesInit {
+		readYangModules()
+	}
+
+	names := []string{}
+	for _, mod := range YangModules.Modules {
+		if mod.ConformanceType != "import" {
+			names = append(names, *mod.Name)
+		}
+	}
+
+	schemaTree = buildSchemaTree(readSchemaModules(), names)
+
+	return schemaTree
+}
+
+// schemaRootNode returns the schema node of a top level data element from its
+// namespace, or from its name when the namespace is not given
+func schemaRootNode(tree map[string]*SchemaNode, namespace string, name string) *SchemaNode {
+
+	for _, root := range tree {
+		if namespace != "" && root.Namespace != namespace {
+			continue
+		}
+		if node := root.Child(name); node != nil {
+			return node
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func init() {
+	fmt.Println("+++++ init schema_test +++++")
+}
+
+var testSchemaModules = map[string]string{
+	"test-types": `module test-types {
+		namespace "urn:test:types";
+		prefix tt;
+		typedef port-number { type uint16; }
+		identity speed;
+		identity speed-10g { base speed; }
+		grouping counters {
+			container counters {
+				config false;
+				leaf in-octets { type uint64; }
+			}
+		}
+	}`,
+	"test-interfaces": `module test-interfaces {
+		namespace "urn:test:interfaces";
+		prefix ti;
+		import test-types { prefix tt; }
+		typedef mtu-type { type tt:port-number; }
+		container interfaces {
+			list interface {
+				key "name";
+				leaf name { type string; }
+				leaf mtu { type mtu-type; }
+				leaf enabled { type boolean; }
+				leaf speed { type identityref { base tt:speed; } }
+				leaf-list alias { type string; }
+				choice mode {
+					case routed { leaf address { type string; } }
+					case switched { leaf vlan { type uint16; } }
+				}
+				uses tt:counters;
+			}
+			leaf parent-ref { type leafref { path "../interface/mtu"; } }
+		}
+	}`,
+	"test-augment": `module test-augment {
+		namespace "urn:test:augment";
+		prefix ta;
+		import test-interfaces { prefix ti; }
+		augment /ti:interfaces/ti:interface {
+			leaf description { type string; }
+		}
+	}`,
+}
+
+// testBuildSchema builds the schema tree of the named modules from YANG sources
+func testBuildSchema(sources map[string]string, names ...string) map[string]*SchemaNode {
+	ms := yang.NewModules()
+	for name, source := range sources {
+		if err := ms.Parse(source, name+".yang"); err != nil {
+			panic(err)
+		}
+	}
+	return buildSchemaTree(ms, names)
+}
+
+func testSchemaTree() map[string]*SchemaNode {
+	return testBuildSchema(testSchemaModules, "test-interfaces", "test-augment")
+}
+
+func TestSchemaTree(t *testing.T) {
+
+	tree := testSchemaTree()
+
+	list := schemaRootNode(tree, "urn:test:interfaces", "interfaces").Child("interface")
+
+	if list == nil || list.Kind != "list" || !list.Child("name").IsKey() {
+		t.Fatalf("Result was incorrect, interface list not found")
+	}
+
+	for _, name := range []string{"address", "vlan", "alias"} {
+		if list.Child(name) == nil {
+			t.Errorf("Result was incorrect, %s not found in interface list", name)
+		}
+	}
+
+	if list.Child("mtu").LeafType() != "uint16" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", list.Child("mtu").LeafType(), "uint16")
+	}
+
+	if list.Child("speed").LeafType() != "identityref" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", list.Child("speed").LeafType(), "identityref")
+	}
+
+	ref := list.Parent.Child("parent-ref")
+	if ref.LeafType() != "uint16" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", ref.LeafType(), "uint16")
+	}
+
+	counters := list.Child("counters")
+	if counters == nil || counters.Config || counters.Namespace != "urn:test:interfaces" {
+		t.Errorf("Result was incorrect, got: %+v, want: config false counters in urn:test:interfaces", counters)
+	}
+
+	description := list.Child("description")
+	if description == nil || description.Module != "test-augment" || description.Namespace != "urn:test:augment" {
+		t.Errorf("Result was incorrect, got: %+v, want: augmented description", description)
+	}
+}
@@ -0,0 +1,818 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"orange/sonic-netconf-server/lib"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/go-redis/redis/v7"
+	"github.com/golang/glog"
+)
+
+const configDbSeparator = "|"
+
+var StartupConfigPath = "/etc/sonic/config_db.json"
+
+// ConfigDb is the content of CONFIG_DB as saved in config_db.json, table -> key -> field -> value.
+// Values are strings, or lists of strings for leaf-lists
+type ConfigDb map[string]map[string]map[string]interface{}
+
+func readStartupConfig() (ConfigDb, error) {
+
+	data, err := ioutil.ReadFile(StartupConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := ConfigDb{}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// writeStartupConfig saves the configuration, the file is replaced atomically
+func writeStartupConfig(config ConfigDb) error {
+
+	data, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(StartupConfigPath, append(data, '\n'))
+}
+
+func writeFileAtomic(path string, data []byte) error {
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// dumpConfigDb reads the running configuration from CONFIG_DB, as config save does.
+// Keys are listed with SCAN and all the entries are then read in a single
+// transaction so that the snapshot is consistent
+func dumpConfigDb() (ConfigDb, error) {
+
+	keys := []string{}
+
+	iter := redisClient.Scan(0, "*"+configDbSeparator+"*", 1000).Iterator()
+	for iter.Next() {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(keys))
+
+	_, err := redisClient.TxPipelined(func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.HGetAll(key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	config := ConfigDb{}
+
+	for i, key := range keys {
+
+		fields := cmds[i].Val()
+
+		// Deleted since the scan
+		if len(fields) == 0 {
+			continue
+		}
+
+		entry := map[string]interface{}{}
+		for field, value := range fields {
+			if field == "NULL" {
+				continue
+			}
+			if strings.HasSuffix(field, "@") {
+				values := []interface{}{}
+				for _, v := range strings.Split(value, ",") {
+					values = append(values, v)
+				}
+				entry[strings.TrimSuffix(field, "@")] = values
+				continue
+			}
+			entry[field] = value
+		}
+
+		j := strings.Index(key, configDbSeparator)
+		table := key[:j]
+		if config[table] == nil {
+			config[table] = map[string]map[string]interface{}{}
+		}
+		config[table][key[j+1:]] = entry
+	}
+
+	return config, nil
+}
+
+// ConfigReloadCommand replaces the running configuration with the file given
+// as last argument, stopping and restarting the services as needed
+var ConfigReloadCommand = []string{"config", "reload", "-y"}
+
+// loadConfigDb replaces the content of CONFIG_DB with config reload, the
+// configuration is written to a private temporary file for the command
+func loadConfigDb(config ConfigDb) error {
+
+	data, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile("", "config_db*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	args := append(append([]string{}, ConfigReloadCommand[1:]...), file.Name())
+
+	glog.Infof("Reloading the running configuration with %s", strings.Join(ConfigReloadCommand, " "))
+
+	if output, err := exec.Command(ConfigReloadCommand[0], args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// configDbXml encodes the configuration according to the SONiC YANG models,
+// only the modules and tables selected by the subtree filter are returned
+func configDbXml(config ConfigDb, tree map[string]*SchemaNode, filterNode *xmlquery.Node) string {
+
+	// Module container name -> selected tables, nil for all tables
+	var selection map[string][]string
+
+	if filterNode != nil {
+		selection = map[string][]string{}
+		for _, container := range elementChildren(filterNode) {
+			tables := []string{}
+			for _, table := range elementChildren(container) {
+				tables = append(tables, table.Data)
+			}
+			selection[container.Data] = tables
+		}
+	}
+
+	modules := map[string]*dataNode{}
+
+	tables := []string{}
+	for table := range config {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+
+		schema := configDbTable(tree, table)
+		if schema == nil {
+			glog.V(1).Infof("No schema for table %s, skipped", table)
+			continue
+		}
+
+		container := schema.Parent
+		if selected, ok := selection[container.Name]; selection != nil && (!ok || (len(selected) > 0 && !contains(selected, table))) {
+			continue
+		}
+
+		module := modules[container.Name]
+		if module == nil {
+			module = &dataNode{name: container.Name, namespace: container.Namespace}
+			modules[container.Name] = module
+		}
+
+		tableNode := &dataNode{name: table, namespace: schema.Namespace}
+
+		keys := []string{}
+		for key := range config[table] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if entry := configDbEntry(schema, key, config[table][key]); entry != nil {
+				tableNode.children = append(tableNode.children, entry)
+			} else {
+				glog.V(1).Infof("No schema for %s|%s, skipped", table, key)
+			}
+		}
+
+		module.children = append(module.children, tableNode)
+	}
+
+	names := []string{}
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		modules[name].write(&b, "", "", false)
+	}
+
+	return b.String()
+}
+
+// configDbTable returns the schema node of a CONFIG_DB table, SONiC models
+// define each table as a container of a module top level container
+func configDbTable(tree map[string]*SchemaNode, table string) *SchemaNode {
+
+	names := []string{}
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, container := range tree[name].Children {
+			if node := container.Child(table); node != nil && node.Kind == "container" {
+				return node
+			}
+		}
+	}
+
+	return nil
+}
+
+// configDbEntry encodes a table entry either as a list entry, keys being
+// separated by '|', or as a container named after the key
+func configDbEntry(table *SchemaNode, key string, fields map[string]interface{}) *dataNode {
+
+	schema, parts := configDbEntrySchema(table, key)
+
+	var entry *dataNode
+
+	switch {
+	case schema == nil:
+		return nil
+	case schema.Kind == "list":
+		entry = &dataNode{name: schema.Name}
+		for i, name := range schema.Keys {
+			entry.children = append(entry.children, &dataNode{name: name, text: parts[i]})
+		}
+	default:
+		entry = &dataNode{name: schema.Name}
+	}
+
+	names := []string{}
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		field := strings.TrimSuffix(name, "@")
+		leaf := schema.Child(field)
+		if leaf == nil || leaf.IsKey() {
+			continue
+		}
+
+		values := []interface{}{fields[name]}
+		if list, ok := fields[name].([]interface{}); ok {
+			values = list
+		} else if s, ok := fields[name].(string); ok && leaf.Kind == "leaf-list" {
+			values = []interface{}{}
+			for _, v := range strings.Split(s, ",") {
+				values = append(values, v)
+			}
+		}
+
+		for _, value := range values {
+			entry.children = append(entry.children, &dataNode{name: field, namespace: leaf.Namespace, text: fmt.Sprintf("%v", value)})
+		}
+	}
+
+	return entry
+}
+
+// configDbEntrySchema returns the list of a table entry with the values of its
+// keys, or the container named after the key, nil if none
+func configDbEntrySchema(table *SchemaNode, key string) (*SchemaNode, []string) {
+
+	parts := strings.Split(key, configDbSeparator)
+
+	for _, name := range sortedChildren(table) {
+		child := table.Children[name]
+		if child.Kind == "list" && len(child.Keys) == len(parts) {
+			return child, parts
+		}
+	}
+
+	if schema := table.Child(key); schema != nil && schema.Kind == "container" {
+		return schema, nil
+	}
+
+	return nil, nil
+}
+
+func sortedChildren(node *SchemaNode) []string {
+	names := []string{}
+	for name := range node.Children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configDbFromXml decodes a configuration encoded according to the SONiC YANG
+// models, the inverse of configDbXml
+func configDbFromXml(config *xmlquery.Node, tree map[string]*SchemaNode) (ConfigDb, error) {
+
+	result := ConfigDb{}
+
+	for _, container := range elementChildren(config) {
+
+		schema := schemaRootNode(tree, container.NamespaceURI, container.Data)
+		if schema == nil {
+			return nil, unknownElementError(container)
+		}
+
+		for _, table := range elementChildren(container) {
+
+			tableSchema := schema.Child(table.Data)
+			if tableSchema == nil || tableSchema.Kind != "container" {
+				return nil, unknownElementError(table)
+			}
+
+			if result[table.Data] == nil {
+				result[table.Data] = map[string]map[string]interface{}{}
+			}
+
+			for _, entry := range elementChildren(table) {
+
+				entrySchema := tableSchema.Child(entry.Data)
+				if entrySchema == nil {
+					return nil, unknownElementError(entry)
+				}
+
+				key := entry.Data
+				if entrySchema.Kind == "list" {
+					parts := []string{}
+					for _, name := range entrySchema.Keys {
+						keyNode := xmlquery.FindOne(entry, "./*[local-name() = '"+name+"']")
+						if keyNode == nil {
+							rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagMissingElement, "Missing key "+name+" of "+entry.Data)
+							rpcErr.ErrorInfo.BadElement = name
+							return nil, rpcErr
+						}
+						parts = append(parts, strings.TrimSpace(keyNode.InnerText()))
+					}
+					key = strings.Join(parts, configDbSeparator)
+				}
+
+				fields := map[string]interface{}{}
+				for _, field := range elementChildren(entry) {
+					leaf := entrySchema.Child(field.Data)
+					if leaf == nil {
+						return nil, unknownElementError(field)
+					}
+					if leaf.IsKey() {
+						continue
+					}
+					if leaf.Kind == "leaf-list" {
+						values, _ := fields[field.Data].([]interface{})
+						fields[field.Data] = append(values, field.InnerText())
+						continue
+					}
+					fields[field.Data] = field.InnerText()
+				}
+
+				result[table.Data][key] = fields
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// configDbChanges compares the fields of two configurations, returning the
+// entries and fields created, updated and deleted by replacing current with
+// config. Leaf-lists are compared as the comma separated values of CONFIG_DB
+func configDbChanges(current ConfigDb, config ConfigDb) (ConfigDb, ConfigDb, ConfigDb) {
+
+	created, updated, deleted := ConfigDb{}, ConfigDb{}, ConfigDb{}
+
+	compare := func(from ConfigDb, to ConfigDb, missing ConfigDb, changed ConfigDb) {
+		for table, entries := range from {
+			for key, fields := range entries {
+				toFields, ok := to[table][key]
+				if !ok {
+					addConfigDbChange(missing, table, key, "", nil)
+				}
+				toValues := configDbValues(toFields)
+				for name, value := range configDbValues(fields) {
+					if toValue, ok := toValues[name]; !ok {
+						addConfigDbChange(missing, table, key, name, value)
+					} else if changed != nil && toValue != value {
+						addConfigDbChange(changed, table, key, name, value)
+					}
+				}
+			}
+		}
+	}
+
+	compare(config, current, created, updated)
+	compare(current, config, deleted, nil)
+
+	return created, updated, deleted
+}
+
+func addConfigDbChange(changes ConfigDb, table string, key string, name string, value interface{}) {
+
+	if changes[table] == nil {
+		changes[table] = map[string]map[string]interface{}{}
+	}
+	if changes[table][key] == nil {
+		changes[table][key] = map[string]interface{}{}
+	}
+	if name != "" {
+		changes[table][key][name] = value
+	}
+}
+
+// configDbValues returns the fields of an entry as config_db.json and redis
+// write them, leaf-list names lose their '@' suffix and values are joined
+func configDbValues(fields map[string]interface{}) map[string]string {
+
+	values := map[string]string{}
+
+	for name, value := range fields {
+		if list, ok := value.([]interface{}); ok {
+			texts := []string{}
+			for _, v := range list {
+				texts = append(texts, fmt.Sprint(v))
+			}
+			values[strings.TrimSuffix(name, "@")] = strings.Join(texts, ",")
+			continue
+		}
+		values[strings.TrimSuffix(name, "@")] = fmt.Sprint(value)
+	}
+
+	return values
+}
+
+// configDbPayload encodes a configuration as an RFC 7951 payload for access
+// control, tables without schema are top level nodes of no module
+func configDbPayload(config ConfigDb, tree map[string]*SchemaNode) map[string]interface{} {
+
+	payload := map[string]interface{}{}
+
+	for table, entries := range config {
+
+		schema := configDbTable(tree, table)
+		if schema == nil {
+			object := map[string]interface{}{}
+			for key, fields := range entries {
+				object[key] = fields
+			}
+			payload[table] = object
+			continue
+		}
+
+		name := schema.Parent.Module + ":" + schema.Parent.Name
+		module, ok := payload[name].(map[string]interface{})
+		if !ok {
+			module = map[string]interface{}{}
+			payload[name] = module
+		}
+
+		tableObject := map[string]interface{}{}
+		module[table] = tableObject
+
+		for key, fields := range entries {
+
+			entry := map[string]interface{}{}
+			for field, value := range fields {
+				entry[field] = value
+			}
+
+			entrySchema, parts := configDbEntrySchema(schema, key)
+			if entrySchema != nil && entrySchema.Kind == "list" {
+				for i, keyName := range entrySchema.Keys {
+					entry[keyName] = parts[i]
+				}
+				list, _ := tableObject[entrySchema.Name].([]interface{})
+				tableObject[entrySchema.Name] = append(list, entry)
+				continue
+			}
+
+			tableObject[key] = entry
+		}
+	}
+
+	return payload
+}
+
+// checkConfigWrite checks the write access to the nodes changed by replacing
+// the configuration of a target, a target that cannot be read is checked as a whole
+func checkConfigWrite(session *nacmSession, access string, target string, node *xmlquery.Node, config ConfigDb) error {
+
+	if session == nil {
+		return nil
+	}
+
+	current, err := readConfig(target, node)
+	if os.IsNotExist(err) {
+		current, err = ConfigDb{}, nil
+	}
+
+	if err != nil {
+		glog.Warningf("[NACM] Unable to read %s, checked as a whole: %v", datastoreArg(target, node), err)
+		return session.checkDatastore(access, datastoreArg(target, node))
+	}
+
+	return session.checkConfig(datastoreArg(target, node), current, config)
+}
+
+// readableConfig removes the nodes of a configuration the user may not read
+func readableConfig(session *nacmSession, config ConfigDb) (ConfigDb, error) {
+
+	if session == nil {
+		return config, nil
+	}
+
+	data := configDbXml(config, SchemaTree(), nil)
+
+	pruned := session.pruneRead(data)
+	if pruned == data {
+		return config, nil
+	}
+
+	doc, err := xmlquery.Parse(strings.NewReader("<config>" + pruned + "</config>"))
+	if err != nil {
+		return nil, err
+	}
+
+	return configDbFromXml(xmlquery.FindOne(doc, "config"), SchemaTree())
+}
+
+// startupData returns the startup configuration encoded in XML
+func startupData(authenticator lib.Authenticator, cmd string, rootNode *xmlquery.Node) (string, error) {
+
+	if !authenticator.Authorize(cmd, "startup") {
+		return "", errors.New("[AUTH] Unauthorized access startup")
+	}
+
+	if !authenticator.Account(cmd, "startup") {
+		return "", errors.New(fmt.Sprintf("[AUTH] Accounting failed %s - args:startup", cmd))
+	}
+
+	config, err := readStartupConfig()
+	if err != nil {
+		glog.Errorf("Unable to read startup config %v", err)
+		return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to read startup configuration")
+	}
+
+	return nacmFor(authenticator).pruneRead(configDbXml(config, SchemaTree(), findFilter(rootNode))), nil
+}
+
+// configDatastore returns the datastore named in the source or target parameter
+// of an operation, along with its element
+func configDatastore(node *xmlquery.Node, param string) (string, *xmlquery.Node, error) {
+
+	paramNode := xmlquery.FindOne(node, "./*[local-name() = '"+param+"']")
+
+	var datastore *xmlquery.Node
+	if paramNode != nil {
+		if children := elementChildren(paramNode); len(children) > 0 {
+			datastore = children[0]
+		}
+	}
+
+	if datastore == nil {
+		err := NewRPCError(ErrorTypeProtocol, ErrorTagMissingElement, "Missing "+param+" datastore")
+		err.ErrorInfo.BadElement = param
+		return "", nil, err
+	}
+
+	return datastore.Data, datastore, nil
+}
+
+// GetConfigHandler handles get-config on the running and startup datastores
+func GetConfigHandler(authenticator lib.Authenticator, rootNode *xmlquery.Node) (string, error) {
+
+	getConfig := xmlquery.FindOne(rootNode, "//*[local-name() = 'get-config']")
+
+	source, _, err := configDatastore(getConfig, "source")
+	if err != nil {
+		return "", err
+	}
+
+	var result string
+
+	switch source {
+	case "running":
+		requests, err := ParseGetRequest(rootNode)
+		if err != nil {
+			return "", err
+		}
+		result, err = collectData(authenticator, "get-config", rootNode, requests)
+		if err != nil {
+			return "", err
+		}
+	case "startup":
+		result, err = startupData(authenticator, "get-config", rootNode)
+		if err != nil {
+			return "", err
+		}
+	default:
+		rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Datastore "+source+" is not supported")
+		rpcErr.ErrorInfo.BadElement = source
+		return "", rpcErr
+	}
+
+	doc, err := xmlquery.Parse(strings.NewReader("<data>" + result + "</data>"))
+	if err != nil {
+		glog.Errorf("Unable to parse collected data %v", err)
+		return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to read data")
+	}
+
+	filter := dataFilter{datastore: DsRunning}
+
+	return "<data>" + filter.content(xmlquery.FindOne(doc, "data"), SchemaTree()) + "</data>", nil
+}
+
+// readConfig returns the configuration of the source of copy-config
+func readConfig(source string, node *xmlquery.Node) (ConfigDb, error) {
+
+	switch source {
+	case "running":
+		return dumpConfigDb()
+	case "startup":
+		return readStartupConfig()
+	case "url":
+		return readURLConfig(node.InnerText())
+	case "config":
+		return configDbFromXml(node, SchemaTree())
+	}
+
+	return nil, NewRPCError(ErrorTypeProtocol, ErrorTagOperationNotSupported, "Copy from "+source+" is not supported")
+}
+
+// writeConfig replaces the configuration of the target of copy-config
+func writeConfig(target string, node *xmlquery.Node, config ConfigDb) error {
+
+	switch target {
+	case "running":
+		return loadConfigDb(config)
+	case "startup":
+		return writeStartupConfig(config)
+	case "url":
+		return writeURLConfig(node.InnerText(), config)
+	}
+
+	return NewRPCError(ErrorTypeProtocol, ErrorTagOperationNotSupported, "Copy to "+target+" is not supported")
+}
+
+// datastoreArg names a datastore in authorization and accounting records
+func datastoreArg(datastore string, node *xmlquery.Node) string {
+	if datastore == "url" {
+		return strings.TrimSpace(node.InnerText())
+	}
+	return datastore
+}
+
+// CopyConfigHandler copies a configuration between the running and startup
+// datastores, URLs and inline configurations. Copying running to startup is
+// what config save does and startup to running what config reload does.
+func CopyConfigHandler(authenticator lib.Authenticator, rootNode *xmlquery.Node) (string, error) {
+
+	copyConfig := xmlquery.FindOne(rootNode, "//*[local-name() = 'copy-config']")
+
+	target, targetNode, err := configDatastore(copyConfig, "target")
+	if err != nil {
+		return "", err
+	}
+
+	source, sourceNode, err := configDatastore(copyConfig, "source")
+	if err != nil {
+		return "", err
+	}
+
+	targetArg, sourceArg := datastoreArg(target, targetNode), datastoreArg(source, sourceNode)
+
+	if sourceArg == targetArg {
+		rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Source and target are the same datastore")
+		rpcErr.ErrorInfo.BadElement = "target"
+		return "", rpcErr
+	}
+
+	// URLs are checked before any access
+	for _, arg := range []struct{ datastore, url string }{{source, sourceArg}, {target, targetArg}} {
+		if arg.datastore == "url" {
+			if _, err := urlPath(arg.url); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	args := sourceArg + " " + targetArg
+
+	// Authorize
+	if !authenticator.Authorize("copy-config", args) {
+		return "", errors.New(fmt.Sprintf("[AUTH] Unauthorized access %+s", args))
+	}
+
+	glog.Infof("[AUTH] authorization passed %+s", args)
+
+	// Access control, the source is read as a whole and each node the copy
+	// changes in the target is checked as edit-config checks it
+	session := nacmFor(authenticator)
+
+	if source != "config" {
+		if err := session.checkDatastore(NacmRead, sourceArg); err != nil {
+			return "", err
+		}
+	}
+
+	config, err := readConfig(source, sourceNode)
+
+	// A URL file is not protected by access control, only readable nodes are copied to it
+	if err == nil && target == "url" {
+		config, err = readableConfig(session, config)
+	}
+
+	if err == nil {
+		if err = checkConfigWrite(session, NacmUpdate, target, targetNode, config); err != nil {
+			return "", err
+		}
+
+		// Account, before the target is written
+		if !authenticator.Account("copy-config", args) {
+			return "", errors.New(fmt.Sprintf("[AUTH] Accounting failed copy-config - args:%s", args))
+		}
+
+		glog.Infof("[AUTH] Accounting passed - copy-config: %s", args)
+
+		err = writeConfig(target, targetNode, config)
+	}
+
+	if err != nil {
+		if rpcErr, ok := err.(*RPCError); ok {
+			return "", rpcErr
+		}
+		glog.Errorf("Unable to copy %s to %s: %v", sourceArg, targetArg, err)
+		return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to copy "+sourceArg+" to "+targetArg+": "+err.Error())
+	}
+
+	return "ok", nil
+}
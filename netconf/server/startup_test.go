@@ -0,0 +1,169 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+)
+
+func init() {
+	fmt.Println("+++++ init startup_test +++++")
+}
+
+const testSonicVlan = `module sonic-vlan {
+	namespace "http://github.com/Azure/sonic-vlan";
+	prefix svlan;
+	container sonic-vlan {
+		container VLAN {
+			list VLAN_LIST {
+				key "name";
+				leaf name { type string; }
+				leaf vlanid { type uint16; }
+				leaf-list dhcp_servers { type string; }
+			}
+		}
+		container VLAN_MEMBER {
+			list VLAN_MEMBER_LIST {
+				key "name port";
+				leaf name { type string; }
+				leaf port { type string; }
+				leaf tagging_mode { type string; }
+			}
+		}
+	}
+}`
+
+const testConfigDb = `{
+    "VLAN": {
+        "Vlan100": {"vlanid": "100", "dhcp_servers": ["10.0.0.1", "10.0.0.2"]}
+    },
+    "VLAN_MEMBER": {
+        "Vlan100|Ethernet0": {"tagging_mode": "untagged"}
+    },
+    "UNKNOWN_TABLE": {
+        "key": {"a": "b"}
+    }
+}`
+
+func testSonicTree() map[string]*SchemaNode {
+	return testBuildSchema(map[string]string{"sonic-vlan": testSonicVlan}, "sonic-vlan")
+}
+
+func TestStartupConfig(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "startup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedPath := StartupConfigPath
+	defer func() { StartupConfigPath = savedPath }()
+
+	StartupConfigPath = filepath.Join(dir, "config_db.json")
+	ioutil.WriteFile(StartupConfigPath, []byte(testConfigDb), 0644)
+
+	config, err := readStartupConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	if err := writeStartupConfig(config); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	saved, err := readStartupConfig()
+	if err != nil || saved["VLAN_MEMBER"]["Vlan100|Ethernet0"]["tagging_mode"] != "untagged" {
+		t.Errorf("Result was incorrect, got: %+v %v", saved, err)
+	}
+
+	result := configDbXml(config, testSonicTree(), nil)
+	correct := `<sonic-vlan xmlns="http://github.com/Azure/sonic-vlan"><VLAN><VLAN_LIST><name>Vlan100</name><dhcp_servers>10.0.0.1</dhcp_servers><dhcp_servers>10.0.0.2</dhcp_servers><vlanid>100</vlanid></VLAN_LIST></VLAN><VLAN_MEMBER><VLAN_MEMBER_LIST><name>Vlan100</name><port>Ethernet0</port><tagging_mode>untagged</tagging_mode></VLAN_MEMBER_LIST></VLAN_MEMBER></sonic-vlan>`
+
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+
+	filter, _ := xmlquery.Parse(strings.NewReader("<filter><sonic-vlan><VLAN_MEMBER/></sonic-vlan></filter>"))
+
+	result = configDbXml(config, testSonicTree(), xmlquery.FindOne(filter, "filter"))
+	if strings.Contains(result, "<VLAN>") || !strings.Contains(result, "<VLAN_MEMBER>") {
+		t.Errorf("Result was incorrect, got: %s", result)
+	}
+}
+
+func TestCopyConfigHandler(t *testing.T) {
+
+	request := func(source string, target string) *xmlquery.Node {
+		node, _ := xmlquery.Parse(strings.NewReader("<rpc message-id=\"1\"><copy-config><target><" + target + "/></target><source><" + source + "/></source></copy-config></rpc>"))
+		return node
+	}
+
+	errorTags := map[[2]string]string{
+		{"running", "running"}:   ErrorTagInvalidValue,
+		{"candidate", "startup"}: ErrorTagOperationNotSupported,
+	}
+
+	for datastores, tag := range errorTags {
+		_, err := CopyConfigHandler(NewTestAuthenticator(true), request(datastores[0], datastores[1]))
+		if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != tag {
+			t.Errorf("Result was incorrect for %v, got: %v, want: %s.", datastores, err, tag)
+		}
+	}
+
+	_, err := CopyConfigHandler(NewTestAuthenticator(false), request("running", "startup"))
+	if err == nil || !strings.Contains(err.Error(), "[AUTH]") {
+		t.Errorf("Result was incorrect, got: %v, want: unauthorized error", err)
+	}
+}
+
+func TestLoadConfigDb(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedCommand := ConfigReloadCommand
+	defer func() { ConfigReloadCommand = savedCommand }()
+
+	// The file given to config reload is copied for inspection
+	reloaded := filepath.Join(dir, "reloaded.json")
+	ConfigReloadCommand = []string{"sh", "-c", "cp \"$1\" " + reloaded, "reload"}
+
+	config := ConfigDb{"VLAN": {"Vlan100": {"vlanid": "100"}}}
+	if err := loadConfigDb(config); err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	data, _ := ioutil.ReadFile(reloaded)
+	result := ConfigDb{}
+	if err := json.Unmarshal(data, &result); err != nil || result["VLAN"]["Vlan100"]["vlanid"] != "100" {
+		t.Errorf("Result was incorrect, got: %s %v", data, err)
+	}
+
+	ConfigReloadCommand = []string{"sh", "-c", "echo invalid configuration; exit 1", "reload"}
+	if err := loadConfigDb(config); err == nil || !strings.Contains(err.Error(), "invalid configuration") {
+		t.Errorf("Result was incorrect, got: %v, want: reload error", err)
+	}
+}
@@ -0,0 +1,74 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"sync/atomic"
+	"time"
+
+	"orange/sonic-netconf-server/lib"
+)
+
+// Counters of the ietf-netconf-monitoring statistics
+var (
+	netconfStartTime = time.Now()
+
+	inBadHellos     uint64
+	inSessions      uint64
+	droppedSessions uint64
+	inRpcs          uint64
+	inBadRpcs       uint64
+	outRPCErrors    uint64
+)
+
+func countStatistic(counter *uint64) {
+	atomic.AddUint64(counter, 1)
+}
+
+// countBadRPC counts a malformed rpc, it is answered with an rpc-error
+func countBadRPC() {
+	countStatistic(&inBadRpcs)
+	countStatistic(&outRPCErrors)
+}
+
+// getStatistics returns the netconf-state statistics with the login protection counters
+func getStatistics() string {
+
+	login := lib.GetLoginStats()
+
+	state := State{
+		Statistics: &Statistics{
+			NetconfStartTime: netconfStartTime.UTC().Format(time.RFC3339),
+			InBadHellos:      atomic.LoadUint64(&inBadHellos),
+			InSessions:       atomic.LoadUint64(&inSessions),
+			DroppedSessions:  atomic.LoadUint64(&droppedSessions),
+			InRpcs:           atomic.LoadUint64(&inRpcs),
+			InBadRpcs:        atomic.LoadUint64(&inBadRpcs),
+			OutRpcErrors:     atomic.LoadUint64(&outRPCErrors),
+			Login: &LoginStatistics{
+				SuccessfulLogins:    login.SuccessfulLogins,
+				FailedLogins:        login.FailedLogins,
+				Lockouts:            login.Lockouts,
+				LockedOutLogins:     login.LockedOutLogins,
+				RejectedConnections: login.RejectedConnections,
+				RejectedSessions:    login.RejectedSessions,
+				LockedSources:       uint32(login.LockedSources),
+				LockedUsers:         uint32(login.LockedUsers),
+				Unauthenticated:     uint32(login.Unauthenticated),
+			},
+		},
+	}
+
+	return prepareSchemasReply(state)
+}
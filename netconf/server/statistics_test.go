@@ -0,0 +1,117 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+)
+
+func init() {
+	fmt.Println("+++++ init statistics_test +++++")
+}
+
+func TestStatisticsCounters(t *testing.T) {
+
+	rpcs := atomic.LoadUint64(&inRpcs)
+	badRpcs := atomic.LoadUint64(&inBadRpcs)
+
+	process(SessionRequest{xml: "<rpc xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><get/></rpc>", authenticator: NewTestAuthenticator(true)})
+
+	if atomic.LoadUint64(&inRpcs) != rpcs+1 {
+		t.Errorf("in-rpcs not counted")
+	}
+
+	if atomic.LoadUint64(&inBadRpcs) != badRpcs+1 {
+		t.Errorf("rpc without message-id not counted in in-bad-rpcs")
+	}
+}
+
+func TestGetStatistics(t *testing.T) {
+
+	doc, err := xmlquery.Parse(strings.NewReader(getStatistics()))
+	if err != nil {
+		t.Fatalf("Invalid statistics: %v", err)
+	}
+
+	for _, leaf := range []string{"netconf-start-time", "in-sessions", "in-rpcs", "out-rpc-errors", "login/failed-logins", "login/lockouts"} {
+		if xmlquery.FindOne(doc, "/netconf-state/statistics/"+leaf) == nil {
+			t.Errorf("Missing %s in %s", leaf, getStatistics())
+		}
+	}
+}
+
+// statisticsTransport replays client messages, the session ends with them
+type statisticsTransport struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (s *statisticsTransport) Write(p []byte) (int, error) { return s.Buffer.Write(p) }
+func (s *statisticsTransport) Read(p []byte) (int, error)  { return s.Reader.Read(p) }
+func (s *statisticsTransport) Close() error                { return nil }
+
+func TestDroppedSessions(t *testing.T) {
+
+	hello := "<hello xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><capabilities><capability>urn:ietf:params:netconf:base:1.0</capability></capabilities></hello>" + delimeter
+	closeSession := "<rpc message-id=\"1\" xmlns=\"urn:ietf:params:xml:ns:netconf:base:1.0\"><close-session/></rpc>" + delimeter
+
+	tests := []struct {
+		name    string
+		input   string
+		dropped uint64
+	}{
+		{"closed by close-session", hello + closeSession, 0},
+		{"transport closed", hello, 1},
+	}
+
+	for _, test := range tests {
+		dropped := atomic.LoadUint64(&droppedSessions)
+
+		ServeSession(&statisticsTransport{Reader: strings.NewReader(test.input)}, NewTestAuthenticator(true))
+
+		if got := atomic.LoadUint64(&droppedSessions) - dropped; got != test.dropped {
+			t.Errorf("%s: %d dropped sessions counted, want %d", test.name, got, test.dropped)
+		}
+	}
+}
+
+func TestLoginModule(t *testing.T) {
+
+	savedModules, savedSchemas := YangModules.Modules, YangSchemas
+	defer func() { YangModules.Modules, YangSchemas = savedModules, savedSchemas }()
+
+	YangModules.Modules, YangSchemas = nil, map[string][]Schema{}
+
+	addServerModule("sonic-netconf-login", NetconfLoginRevision, NsNetconfLogin)
+	addServerModule("sonic-netconf-login", NetconfLoginRevision, NsNetconfLogin)
+
+	if len(YangModules.Modules) != 1 || *YangModules.Modules[0].Namespace != NsNetconfLogin || YangModules.Modules[0].ConformanceType != "implement" {
+		t.Errorf("Unexpected yang library modules %+v", YangModules.Modules)
+	}
+
+	if len(YangSchemas["sonic-netconf-login"]) != 2 {
+		t.Errorf("sonic-netconf-login schemas %+v, yang and yin expected", YangSchemas["sonic-netconf-login"])
+	}
+
+	if capability := moduleCapability(YangModules.Modules[0]); !strings.HasPrefix(capability, NsNetconfLogin+"?module=sonic-netconf-login") {
+		t.Errorf("Unexpected capability %s", capability)
+	}
+}
@@ -18,37 +18,29 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"html"
 	"io/ioutil"
-	"os"
 	"regexp"
 	"strings"
 
 	"orange/sonic-netconf-server/build/netconf_codegen"
+	"orange/sonic-netconf-server/configdb"
+	"orange/sonic-netconf-server/lib"
 
 	"github.com/Azure/sonic-mgmt-common/translib"
 	"github.com/antchfx/xmlquery"
 	"github.com/clbanning/mxj/v2"
-	"github.com/go-redis/redis/v7"
 	"github.com/golang/glog"
+	"github.com/openconfig/goyang/pkg/yang"
 )
 
 var (
+	YangModelDir = "/usr/models/yang/"
 	YangSchemas map[string][]Schema
 	YangModules ModulesState
 	yangModulesInit	= false
-	redisClient	*redis.Client
+	redisClient	= configdb.Client
 )
 
-func init() {
-	redisClient = redis.NewClient(&redis.Options{
-		Network:  "unix",
-		Addr:     "/var/run/redis/redis.sock",
-		Password: "",
-		DB:       4,
-	})
-}
-
 func readYangModules() {
 
 	// return all schemas in module form
@@ -65,6 +57,9 @@ func readYangModules() {
 	}
 
 	YangModules.ModuleSetId = yangMod.ModuleSetId
+	YangModules.Modules = nil
+
+	parsed := map[string]*yang.Statement{}
 
 	for module_key, module := range yangMod.Module {
 
@@ -73,12 +68,12 @@ func readYangModules() {
 		schema.Version = module_key.Revision
 		schema.Format = "yang"
 		schema.NameSpace = *module.Namespace
-		schema.ModelPath = "/usr/models/yang/" + *module.Name + ".yang"
+		schema.ModelPath = yangFilePath(*module.Name, module_key.Revision)
 		schema.Location = "NETCONF"
 
 		schemas = append(schemas, schema)
 
-		YangSchemas[schema.Identifier] = append(YangSchemas[schema.Identifier], schema)
+		addSchema(schema)
 
 		mod := Module{}
 
@@ -86,7 +81,7 @@ func readYangModules() {
 		mod.Namespace = module.Namespace
 		mod.Revision = module.Revision
 		if module.Schema == nil {
-			s := ("http://localhost/usr/models/yang/" + *module.Name)
+			s := ("http://localhost" + yangFilePath(*module.Name, module_key.Revision))
 			mod.Schema = &s
 		} else {
 			mod.Schema = module.Schema
@@ -99,13 +94,122 @@ func readYangModules() {
 			mod.ConformanceType = "import"
 		}
 
+		if stmt, err := loadYangFile(schema.ModelPath); err == nil {
+			parsed[*module.Name] = stmt
+
+			submodules := readYangSubmodules(stmt, schema)
+			for _, submodule := range submodules {
+				mod.Submodule = append(mod.Submodule, ModuleRef{Name: submodule.Argument, Revision: yangRevision(submodule)})
+			}
+			mod.Feature = yangFeatures(stmt, submodules)
+		} else {
+			glog.Warningf("Unable to parse yang file %s: %v", schema.ModelPath, err)
+		}
+
 		YangModules.Modules = append(YangModules.Modules, mod)
 	}
 
+	addServerModule("ietf-netconf-nmda", NetconfNmdaRevision, NsNetconfNmda)
+	addServerModule("ietf-origin", OriginRevision, NsOrigin)
+	addServerModule("sonic-netconf-login", NetconfLoginRevision, NsNetconfLogin)
+
+	addYangDeviations(YangModules.Modules, parsed)
+
+	YangLib = buildYangLibrary(YangModules.Modules)
+
 	yangModulesInit = true
 }
 
-func GetRequestHandler(authenticator Authenticator, rootNode *xmlquery.Node) (string, error) {
+// addServerModule registers a module implemented by the server itself
+// when it is not part of the translib yang library
+func addServerModule(name string, revision string, namespace string) {
+
+	for _, module := range YangModules.Modules {
+		if *module.Name == name {
+			return
+		}
+	}
+
+	path := yangFilePath(name, revision)
+	addSchema(Schema{
+		Identifier: name,
+		Version:    revision,
+		Format:     "yang",
+		NameSpace:  namespace,
+		ModelPath:  path,
+		Location:   "NETCONF",
+	})
+
+	schema := "http://localhost" + path
+	module := Module{
+		Name:            &name,
+		Revision:        &revision,
+		Schema:          &schema,
+		Namespace:       &namespace,
+		ConformanceType: "implement",
+	}
+
+	if stmt, err := loadYangFile(path); err == nil {
+		module.Feature = yangFeatures(stmt, nil)
+	} else {
+		glog.Warningf("Unable to parse yang file %s: %v", path, err)
+	}
+
+	YangModules.Modules = append(YangModules.Modules, module)
+}
+
+// addSchema registers a YANG schema, each schema is also available in YIN format
+func addSchema(schema Schema) {
+
+	for _, existing := range YangSchemas[schema.Identifier] {
+		if existing.Version == schema.Version {
+			return
+		}
+	}
+
+	yin := schema
+	yin.Format = "yin"
+
+	YangSchemas[schema.Identifier] = append(YangSchemas[schema.Identifier], schema, yin)
+}
+
+// readYangSubmodules registers and returns the submodules included by a module,
+// they are not part of the yang library module list
+func readYangSubmodules(module *yang.Statement, schema Schema) []*yang.Statement {
+
+	submodules := []*yang.Statement{}
+
+	for _, include := range yangSubstatements(module, "include") {
+
+		revision := ""
+		if revisionDate := yangSubstatement(include, "revision-date"); revisionDate != nil {
+			revision = revisionDate.Argument
+		}
+
+		path := yangFilePath(include.Argument, revision)
+
+		submodule, err := loadYangFile(path)
+		if err != nil {
+			glog.Warningf("Unable to parse yang submodule %s: %v", path, err)
+			continue
+		}
+
+		addSchema(Schema{
+			Identifier: strings.ToLower(include.Argument),
+			Version:    yangRevision(submodule),
+			Format:     "yang",
+			NameSpace:  schema.NameSpace,
+			Location:   "NETCONF",
+			ModelPath:  path,
+		})
+
+		submodules = append(submodules, submodule)
+	}
+
+	return submodules
+}
+
+func GetRequestHandler(authenticator lib.Authenticator, rootNode *xmlquery.Node) (string, error) {
 
 	requests, err := ParseGetRequest(rootNode)
 
@@ -115,19 +219,43 @@ func GetRequestHandler(authenticator Authenticator, rootNode *xmlquery.Node) (st
 		return "", err
 	}
 
-	// authenticator := context.Value("auth").(Authenticator)
+	// authenticator := context.Value("auth").(lib.Authenticator)
+
+	result, err := collectData(authenticator, "get", rootNode, requests)
+
+	if err != nil {
+		return "", err
+	}
+
+	return "<data>" + result + "</data>", nil
+}
+
+// collectData authorizes, accounts and reads the requested paths, returning
+// the concatenated XML of all paths
+func collectData(authenticator lib.Authenticator, cmd string, rootNode *xmlquery.Node, requests []GetRequest) (string, error) {
 
 	for _, request := range requests {
 		// Authorize
-		if !authenticator.Authorize("get", request.path) {
+		if !authenticator.Authorize(cmd, request.path) {
 			return "", errors.New(fmt.Sprintf("[AUTH] Unauthorized access %+s", request.path))
 		}
 		glog.Infof("[AUTH] authorization passed %+s", request.path)
 	}
 
-	resultStr := "<data>"
-
 	args := ""
+	for _, request := range requests {
+		args += request.path + ", "
+	}
+
+	// Account, before the paths are read
+	if !authenticator.Account(cmd, args) {
+		return "", errors.New(fmt.Sprintf("[AUTH] Accounting failed %s - args:%s", cmd, args))
+	}
+
+	glog.Infof("[AUTH] Accounting passed - %s: %s", cmd, args)
+
+	resultStr := ""
+
 	for _, request := range requests {
 
 		pathResult, err := innerGetHandler(rootNode, request)
@@ -137,23 +265,19 @@ func GetRequestHandler(authenticator Authenticator, rootNode *xmlquery.Node) (st
 		}
 
 		resultStr += pathResult
-		args += request.path + ", "
-	}
-
-	// Account
-	if !authenticator.Account("get", args) {
-		return "", errors.New(fmt.Sprintf("[AUTH] Accounting failed get - args:%s", args))
 	}
 
-	glog.Infof("[AUTH] Accounting passed - get: %s", args)
-
-	resultStr += "</data>"
-
-	return resultStr, nil
+	// Nodes the user may not read are silently omitted
+	return nacmFor(authenticator).pruneRead(resultStr), nil
 }
 
 func innerGetHandler(rootNode *xmlquery.Node, request GetRequest) (string, error) {
 
+	// The nacm container is kept by the server, not translib
+	if request.path == "/nacm:nacm" || strings.HasPrefix(request.path, "/nacm:nacm/") {
+		return nacmXML(), nil
+	}
+
 	switch request.path {
 	case "/modules-state:modules-state":
 		response, err := xml.MarshalIndent(YangModules, "", "   ")
@@ -161,9 +285,17 @@ func innerGetHandler(rootNode *xmlquery.Node, request GetRequest) (string, error
 			return "", errors.New("Unable to read yang modules")
 		}
 		return string(response), nil
+	case "/yang-library:yang-library":
+		response, err := xml.MarshalIndent(YangLib, "", "   ")
+		if err != nil {
+			return "", errors.New("Unable to read yang library")
+		}
+		return string(response), nil
 	case "/netconf-state:netconf-state/schemas":
 		requests, _ := ParseGetRequest(rootNode)
 		return getSchemas(requests[0].path), nil
+	case RPCGetStatistics:
+		return getStatistics(), nil
 	case "/operation:operation":
 		return "", nil
 	default:
@@ -237,7 +369,7 @@ func innerGetHandler(rootNode *xmlquery.Node, request GetRequest) (string, error
 
 func postChecks(rootNode *xmlquery.Node, jsonConv mxj.Map) mxj.Map {
 
-	filterNode := xmlquery.FindOne(rootNode, "//filter")
+	filterNode := findFilter(rootNode)
 	containers := xmlquery.Find(filterNode, "./*")
 
 	for _, modelContainer := range containers {
@@ -459,41 +591,87 @@ func GetSchemaHandler(rootNode *xmlquery.Node) (string, error) {
 	req, err := ParseGetSchemaRequest(rootNode)
 
 	if err != nil {
-		return "", err
+		rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagMissingElement, err.Error())
+		rpcErr.ErrorInfo.BadElement = "identifier"
+		return "", rpcErr
 	}
 
-	schema := Schema{}
-
 	identifier := strings.ToLower(req.Identifier)
 	format := strings.ToLower(req.Format)
 
+	// Format is an identity, it may be prefixed (e.g. ncm:yang)
+	if i := strings.LastIndex(format, ":"); i >= 0 {
+		format = format[i+1:]
+	}
+
+	if format == "" {
+		format = "yang"
+	}
+
+	if format != "yang" && format != "yin" {
+		rpcErr := NewRPCError(ErrorTypeApplication, ErrorTagInvalidValue, "Unsupported schema format "+req.Format)
+		rpcErr.ErrorInfo.BadElement = "format"
+		return "", rpcErr
+	}
+
+	matches := []Schema{}
+
 	for _, model := range YangSchemas[identifier] {
-		if (req.Format == "" || model.Format == format) &&
-			(req.Version == "" || model.Version == req.Version) {
-			schema = model
-			break
+		if model.Format == format && (req.Version == "" || model.Version == req.Version) {
+			matches = append(matches, model)
+		}
+	}
+
+	if len(matches) == 0 {
+		if len(YangSchemas[identifier]) == 0 {
+			rpcErr := NewRPCError(ErrorTypeApplication, ErrorTagInvalidValue, "Unknown schema identifier "+req.Identifier)
+			rpcErr.ErrorInfo.BadElement = "identifier"
+			return "", rpcErr
 		}
+		rpcErr := NewRPCError(ErrorTypeApplication, ErrorTagInvalidValue, "No version "+req.Version+" found for schema "+req.Identifier)
+		rpcErr.ErrorInfo.BadElement = "version"
+		return "", rpcErr
+	}
+
+	if len(matches) > 1 {
+		// RFC 6022 3.1
+		rpcErr := NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Multiple versions found for schema "+req.Identifier+", version is required")
+		rpcErr.ErrorAppTag = ErrorAppTagDataNotUnique
+		return "", rpcErr
+	}
+
+	schema := matches[0]
+
+	yangData, err := readYangFile(schema.ModelPath)
+
+	if err != nil {
+		glog.Errorf("Unable to read yang file %s: %v", schema.ModelPath, err)
+		return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to read schema "+req.Identifier)
 	}
 
-	yangData := readYangFile(schema.ModelPath)
-	yangData = html.EscapeString(yangData)
+	if format == "yin" {
+		module, err := ParseYang(yangData)
+		if err != nil {
+			glog.Errorf("Unable to parse yang file %s: %v", schema.ModelPath, err)
+			return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to convert schema "+req.Identifier+" to yin")
+		}
+		yangData = ToYin(module, lookupYangModule)
+	} else {
+		yangData = xmlEscape(yangData)
+	}
 
 	yangData = "<data xmlns=\"urn:ietf:params:xml:ns:yang:ietf-netconf-monitoring\">" + yangData + "</data>"
 
 	return yangData, nil
 }
 
-func readYangFile(path string) string {
-	yangFile, err := os.Open(path)
+func readYangFile(path string) (string, error) {
+	byteValue, err := ioutil.ReadFile(path)
 	if err != nil {
-		fmt.Println(err)
+		return "", err
 	}
 
-	defer yangFile.Close()
-
-	byteValue, _ := ioutil.ReadAll(yangFile)
-
-	return html.EscapeString(string(byteValue))
+	return string(byteValue), nil
 }
 
 func prepareSchemasReply(st State) string {
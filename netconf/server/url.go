@@ -0,0 +1,263 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"orange/sonic-netconf-server/lib"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/golang/glog"
+)
+
+var (
+	// Directory holding the files reachable through file:// URLs, it must
+	// not hold any security material
+	URLSandboxDir = "/etc/sonic/netconf/url"
+
+	// Additional files never reachable through URLs, such as the TLS keys
+	URLProtectedPaths []string
+
+	URLSchemes = []string{"file"}
+)
+
+// urlCapability returns the :url capability with the supported schemes (RFC 6241 section 8.8.3)
+func urlCapability() string {
+	return CapURL + "?scheme=" + strings.Join(URLSchemes, ",")
+}
+
+func invalidURLError(rawURL string, reason string) error {
+	err := NewRPCError(ErrorTypeProtocol, ErrorTagInvalidValue, "Invalid URL "+rawURL+": "+reason)
+	err.ErrorInfo.BadElement = "url"
+	return err
+}
+
+// urlPath returns the local file of a file:// URL, the file must be inside the sandbox directory
+func urlPath(rawURL string) (string, error) {
+
+	rawURL = strings.TrimSpace(rawURL)
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", invalidURLError(rawURL, err.Error())
+	}
+
+	if !contains(URLSchemes, u.Scheme) {
+		err := NewRPCError(ErrorTypeProtocol, ErrorTagOperationNotSupported, "URL scheme "+u.Scheme+" is not supported")
+		err.ErrorInfo.BadElement = "url"
+		return "", err
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		return "", invalidURLError(rawURL, "remote hosts are not supported")
+	}
+
+	sandbox, err := filepath.Abs(URLSandboxDir)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Clean(u.Path)
+	if !strings.HasPrefix(path, sandbox+string(filepath.Separator)) {
+		return "", invalidURLError(rawURL, "outside of "+sandbox)
+	}
+
+	// Symbolic links must not lead out of the sandbox, including the file itself
+	resolvedSandbox, err := resolveExisting(sandbox)
+	if err != nil {
+		return "", err
+	}
+
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return "", invalidURLError(rawURL, err.Error())
+	}
+
+	if !strings.HasPrefix(resolved, resolvedSandbox+string(filepath.Separator)) {
+		return "", invalidURLError(rawURL, "outside of "+sandbox)
+	}
+
+	// The security files stay out of reach even if the sandbox holds them
+	for _, protected := range protectedPaths() {
+		if urlProtected(resolved, protected) {
+			return "", invalidURLError(rawURL, "protected file")
+		}
+	}
+
+	return resolved, nil
+}
+
+// protectedPaths returns the keys, certificates and authorization files of
+// the server, entries may be glob patterns
+func protectedPaths() []string {
+	return append([]string{
+		NACMConfigPath,
+		lib.RolePolicyPath,
+		lib.AuthorizedKeysDir,
+		lib.TrustedUserCAKeysPath,
+		lib.AuthorizedPrincipalsPath,
+		lib.RevokedKeysPath,
+		filepath.Join(lib.HostKeyDir, "ssh_host_*"),
+	}, URLProtectedPaths...)
+}
+
+// urlProtected reports whether the resolved path is, or is inside, the protected path
+func urlProtected(resolved string, protected string) bool {
+
+	if protected == "" {
+		return false
+	}
+
+	protected, err := filepath.Abs(protected)
+	if err != nil {
+		return false
+	}
+
+	dir, err := resolveExisting(filepath.Dir(protected))
+	if err != nil {
+		return false
+	}
+	pattern := filepath.Join(dir, filepath.Base(protected))
+
+	for path := resolved; ; path = filepath.Dir(path) {
+		if matched, _ := filepath.Match(pattern, path); matched {
+			return true
+		}
+		if path == filepath.Dir(path) {
+			return false
+		}
+	}
+}
+
+// resolveExisting resolves the symbolic links of the longest existing prefix
+// of path, the components that do not exist yet are appended unchanged
+func resolveExisting(path string) (string, error) {
+
+	rest := ""
+
+	for dir := path; ; dir = filepath.Dir(dir) {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !os.IsNotExist(err) || dir == filepath.Dir(dir) {
+			return "", err
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// readURLConfig reads a configuration file, it holds a config element as in RFC 6241 section 8.8
+func readURLConfig(rawURL string) (ConfigDb, error) {
+
+	path, err := urlPath(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := xmlquery.Parse(strings.NewReader(string(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	config := xmlquery.FindOne(doc, "./*[local-name() = 'config']")
+	if config == nil {
+		return nil, errors.New("config element not found")
+	}
+
+	return configDbFromXml(config, SchemaTree())
+}
+
+func writeURLConfig(rawURL string, config ConfigDb) error {
+
+	path, err := urlPath(rawURL)
+	if err != nil {
+		return err
+	}
+
+	data := "<config xmlns=\"" + NsNetconfBase + "\">" + configDbXml(config, SchemaTree(), nil) + "</config>\n"
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, []byte(data))
+}
+
+// DeleteConfigHandler deletes the startup datastore or a URL file, running cannot be deleted
+func DeleteConfigHandler(authenticator lib.Authenticator, rootNode *xmlquery.Node) (string, error) {
+
+	deleteConfig := xmlquery.FindOne(rootNode, "//*[local-name() = 'delete-config']")
+
+	target, targetNode, err := configDatastore(deleteConfig, "target")
+	if err != nil {
+		return "", err
+	}
+
+	var path string
+
+	switch target {
+	case "startup":
+		path = StartupConfigPath
+	case "url":
+		path, err = urlPath(targetNode.InnerText())
+		if err != nil {
+			return "", err
+		}
+	default:
+		rpcErr := NewRPCError(ErrorTypeProtocol, ErrorTagOperationNotSupported, "Datastore "+target+" cannot be deleted")
+		rpcErr.ErrorInfo.BadElement = target
+		return "", rpcErr
+	}
+
+	arg := datastoreArg(target, targetNode)
+
+	// Authorize
+	if !authenticator.Authorize("delete-config", arg) {
+		return "", errors.New(fmt.Sprintf("[AUTH] Unauthorized access %+s", arg))
+	}
+
+	glog.Infof("[AUTH] authorization passed %+s", arg)
+
+	// Every node of the target is deleted
+	if err := checkConfigWrite(nacmFor(authenticator), NacmDelete, target, targetNode, ConfigDb{}); err != nil {
+		return "", err
+	}
+
+	// Account, before the file is deleted
+	if !authenticator.Account("delete-config", arg) {
+		return "", errors.New(fmt.Sprintf("[AUTH] Accounting failed delete-config - args:%s", arg))
+	}
+
+	glog.Infof("[AUTH] Accounting passed - delete-config: %s", arg)
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		glog.Errorf("Unable to delete %s: %v", path, err)
+		return "", NewRPCError(ErrorTypeApplication, ErrorTagOperationFailed, "Unable to delete "+arg)
+	}
+
+	return "ok", nil
+}
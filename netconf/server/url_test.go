@@ -0,0 +1,362 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"orange/sonic-netconf-server/lib"
+
+	"github.com/antchfx/xmlquery"
+)
+
+func init() {
+	fmt.Println("+++++ init url_test +++++")
+}
+
+// unaccountedAuthenticator authorizes everything but refuses the accounting start records
+type unaccountedAuthenticator struct {
+	TestAuthenticator
+}
+
+func (unaccountedAuthenticator) Account(cmd string, cmdArgs string) bool {
+	return false
+}
+
+func TestURLPath(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outside, err := ioutil.TempDir("", "outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+
+	savedDir := URLSandboxDir
+	defer func() { URLSandboxDir = savedDir }()
+
+	URLSandboxDir = filepath.Join(dir, "netconf")
+	os.MkdirAll(URLSandboxDir, 0755)
+
+	ioutil.WriteFile(filepath.Join(outside, "passwd"), []byte("secret"), 0644)
+	ioutil.WriteFile(filepath.Join(URLSandboxDir, "backup.xml"), []byte("<config/>"), 0644)
+	os.Symlink(filepath.Join(outside, "passwd"), filepath.Join(URLSandboxDir, "passwd.xml"))
+	os.Symlink(outside, filepath.Join(URLSandboxDir, "out"))
+	os.Symlink(filepath.Join(URLSandboxDir, "backup.xml"), filepath.Join(URLSandboxDir, "latest.xml"))
+
+	sandbox, _ := filepath.EvalSymlinks(URLSandboxDir)
+	root := "file://" + URLSandboxDir
+
+	valid := map[string]string{
+		root + "/backup.xml":                            sandbox + "/backup.xml",
+		"file://localhost" + URLSandboxDir + "/a/b.xml": sandbox + "/a/b.xml",
+		" " + root + "/./daily/c.xml ":                  sandbox + "/daily/c.xml",
+		root + "/latest.xml":                            sandbox + "/backup.xml",
+	}
+
+	for rawURL, correct := range valid {
+		if result, err := urlPath(rawURL); err != nil || result != correct {
+			t.Errorf("Result was incorrect, got: %s %v, want: %s.", result, err, correct)
+		}
+	}
+
+	errorTags := map[string]string{
+		root + "/../passwd":                        ErrorTagInvalidValue,
+		"file:///etc/sonic/config_db.json":         ErrorTagInvalidValue,
+		"file://server" + URLSandboxDir + "/a.xml": ErrorTagInvalidValue,
+		root:                    ErrorTagInvalidValue,
+		root + "/passwd.xml":    ErrorTagInvalidValue,
+		root + "/out/passwd":    ErrorTagInvalidValue,
+		root + "/out/new/a.xml": ErrorTagInvalidValue,
+		"ftp://server" + URLSandboxDir + "/a.xml": ErrorTagOperationNotSupported,
+	}
+
+	for rawURL, tag := range errorTags {
+		_, err := urlPath(rawURL)
+		if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != tag {
+			t.Errorf("Result was incorrect for %s, got: %v, want: %s.", rawURL, err, tag)
+		}
+	}
+}
+
+func TestURLProtected(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedDir, savedProtected, savedNacm := URLSandboxDir, URLProtectedPaths, NACMConfigPath
+	savedHostKeys, savedRoles, savedKeys := lib.HostKeyDir, lib.RolePolicyPath, lib.AuthorizedKeysDir
+	savedCA, savedPrincipals, savedRevoked := lib.TrustedUserCAKeysPath, lib.AuthorizedPrincipalsPath, lib.RevokedKeysPath
+	defer func() {
+		URLSandboxDir, URLProtectedPaths, NACMConfigPath = savedDir, savedProtected, savedNacm
+		lib.HostKeyDir, lib.RolePolicyPath, lib.AuthorizedKeysDir = savedHostKeys, savedRoles, savedKeys
+		lib.TrustedUserCAKeysPath, lib.AuthorizedPrincipalsPath, lib.RevokedKeysPath = savedCA, savedPrincipals, savedRevoked
+	}()
+
+	// Worst case, the sandbox is the directory of the security files
+	netconfDir := filepath.Join(dir, "netconf")
+	os.MkdirAll(filepath.Join(netconfDir, "url"), 0755)
+
+	URLSandboxDir = netconfDir
+	NACMConfigPath = filepath.Join(netconfDir, "nacm.xml")
+	lib.HostKeyDir = netconfDir
+	lib.RolePolicyPath = filepath.Join(netconfDir, "roles.json")
+	lib.AuthorizedKeysDir = filepath.Join(netconfDir, "authorized_keys")
+	lib.TrustedUserCAKeysPath = filepath.Join(netconfDir, "trusted_user_ca_keys")
+	lib.AuthorizedPrincipalsPath = filepath.Join(netconfDir, "authorized_principals")
+	lib.RevokedKeysPath = filepath.Join(netconfDir, "revoked_keys")
+	URLProtectedPaths = []string{filepath.Join(netconfDir, "server.key")}
+
+	for _, name := range []string{"nacm.xml", "roles.json", "ssh_host_ed25519_key", "trusted_user_ca_keys", "revoked_keys", "server.key"} {
+		ioutil.WriteFile(filepath.Join(netconfDir, name), []byte("secret"), 0600)
+	}
+	os.Symlink(filepath.Join(netconfDir, "roles.json"), filepath.Join(netconfDir, "url", "roles.xml"))
+	os.Symlink(netconfDir, filepath.Join(netconfDir, "url", "parent"))
+
+	root := "file://" + netconfDir
+
+	protected := []string{
+		root + "/nacm.xml",
+		root + "/roles.json",
+		root + "/ssh_host_ed25519_key",
+		root + "/ssh_host_rsa_key",
+		root + "/trusted_user_ca_keys",
+		root + "/authorized_principals",
+		root + "/revoked_keys",
+		root + "/server.key",
+		root + "/authorized_keys/admin",
+		root + "/url/../nacm.xml",
+		root + "/url/roles.xml",
+		root + "/url/parent/revoked_keys",
+	}
+
+	for _, rawURL := range protected {
+		_, err := urlPath(rawURL)
+		if rpcErr, ok := err.(*RPCError); !ok || !strings.HasSuffix(rpcErr.ErrorMessage, "protected file") {
+			t.Errorf("Result was incorrect for %s, got: %v, want: protected file.", rawURL, err)
+		}
+	}
+
+	// delete-config and copy-config refuse the security files
+	request := func(operation string, body string) *xmlquery.Node {
+		node, _ := xmlquery.Parse(strings.NewReader("<rpc message-id=\"1\"><" + operation + ">" + body + "</" + operation + "></rpc>"))
+		return node
+	}
+
+	_, err = DeleteConfigHandler(NewTestAuthenticator(true), request("delete-config", "<target><url>"+root+"/nacm.xml</url></target>"))
+	if rpcErr, ok := err.(*RPCError); !ok || !strings.HasSuffix(rpcErr.ErrorMessage, "protected file") {
+		t.Errorf("Result was incorrect, got: %v, want: protected file.", err)
+	}
+
+	_, err = CopyConfigHandler(NewTestAuthenticator(true), request("copy-config", "<target><url>"+root+"/url/parent/trusted_user_ca_keys</url></target><source><config/></source>"))
+	if rpcErr, ok := err.(*RPCError); !ok || !strings.HasSuffix(rpcErr.ErrorMessage, "protected file") {
+		t.Errorf("Result was incorrect, got: %v, want: protected file.", err)
+	}
+
+	for _, name := range []string{"nacm.xml", "trusted_user_ca_keys"} {
+		if data, err := ioutil.ReadFile(filepath.Join(netconfDir, name)); err != nil || string(data) != "secret" {
+			t.Errorf("Result was incorrect, %s was modified: %s %v.", name, data, err)
+		}
+	}
+
+	// Other files of the sandbox stay reachable
+	if _, err := urlPath(root + "/url/backup.xml"); err != nil {
+		t.Errorf("Result was incorrect, got: %v, want: nil.", err)
+	}
+}
+
+func TestCopyConfigURL(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedDir, savedPath := URLSandboxDir, StartupConfigPath
+	defer func() { URLSandboxDir, StartupConfigPath = savedDir, savedPath }()
+
+	URLSandboxDir = dir
+	StartupConfigPath = filepath.Join(dir, "config_db.json")
+
+	schemaTreeLock.Lock()
+	savedTree := schemaTree
+	schemaTree = testSonicTree()
+	schemaTreeLock.Unlock()
+	defer func() { schemaTree = savedTree }()
+
+	request := func(operation string, body string) *xmlquery.Node {
+		node, _ := xmlquery.Parse(strings.NewReader("<rpc message-id=\"1\"><" + operation + ">" + body + "</" + operation + "></rpc>"))
+		return node
+	}
+
+	backup := "file://" + filepath.Join(dir, "backups", "vlan.xml")
+
+	config := `<config><sonic-vlan xmlns="http://github.com/Azure/sonic-vlan"><VLAN_MEMBER><VLAN_MEMBER_LIST><name>Vlan100</name><port>Ethernet0</port><tagging_mode>tagged</tagging_mode></VLAN_MEMBER_LIST></VLAN_MEMBER></sonic-vlan></config>`
+
+	_, err = CopyConfigHandler(NewTestAuthenticator(true), request("copy-config", "<target><url>"+backup+"</url></target><source>"+config+"</source>"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	_, err = CopyConfigHandler(NewTestAuthenticator(true), request("copy-config", "<target><startup/></target><source><url>"+backup+"</url></source>"))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	startup, err := readStartupConfig()
+	if err != nil || startup["VLAN_MEMBER"]["Vlan100|Ethernet0"]["tagging_mode"] != "tagged" {
+		t.Errorf("Result was incorrect, got: %+v %v", startup, err)
+	}
+
+	// Nothing is written or deleted when the accounting start record is refused
+	unaccounted := unaccountedAuthenticator{NewTestAuthenticator(true)}
+
+	_, err = CopyConfigHandler(unaccounted, request("copy-config", "<target><url>file://"+filepath.Join(dir, "copy.xml")+"</url></target><source>"+config+"</source>"))
+	if _, statErr := os.Stat(filepath.Join(dir, "copy.xml")); err == nil || !os.IsNotExist(statErr) {
+		t.Errorf("Result was incorrect, copy.xml written without accounting %v", err)
+	}
+
+	_, err = DeleteConfigHandler(unaccounted, request("delete-config", "<target><url>"+backup+"</url></target>"))
+	if _, statErr := os.Stat(filepath.Join(dir, "backups", "vlan.xml")); err == nil || statErr != nil {
+		t.Errorf("Result was incorrect, %s deleted without accounting %v", backup, err)
+	}
+
+	_, err = DeleteConfigHandler(NewTestAuthenticator(true), request("delete-config", "<target><url>"+backup+"</url></target>"))
+	if _, statErr := os.Stat(filepath.Join(dir, "backups", "vlan.xml")); err != nil || !os.IsNotExist(statErr) {
+		t.Errorf("Result was incorrect, expected %s to be deleted %v", backup, err)
+	}
+
+	_, err = DeleteConfigHandler(NewTestAuthenticator(true), request("delete-config", "<target><running/></target>"))
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != ErrorTagOperationNotSupported {
+		t.Errorf("Result was incorrect, got: %v, want: %s.", err, ErrorTagOperationNotSupported)
+	}
+}
+
+func TestCopyConfigNacm(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "url")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedDir, savedPath := URLSandboxDir, StartupConfigPath
+	defer func() { URLSandboxDir, StartupConfigPath = savedDir, savedPath }()
+
+	URLSandboxDir = dir
+	StartupConfigPath = filepath.Join(dir, "config_db.json")
+
+	schemaTreeLock.Lock()
+	savedTree := schemaTree
+	schemaTree = testSonicTree()
+	schemaTreeLock.Unlock()
+	defer func() { schemaTree = savedTree }()
+
+	setTestNacm(t, `<nacm xmlns="urn:ietf:params:xml:ns:yang:ietf-netconf-acm">
+  <enable-nacm>true</enable-nacm>
+  <write-default>deny</write-default>
+  <exec-default>permit</exec-default>
+  <groups>
+    <group><name>vlan-admins</name><user-name>carol</user-name></group>
+  </groups>
+  <rule-list>
+    <name>vlan-admins</name>
+    <group>vlan-admins</group>
+    <rule><name>no-vlan200</name><module-name>sonic-vlan</module-name><path>/sonic-vlan:sonic-vlan/VLAN/VLAN_LIST[name='Vlan200']</path><access-operations>read</access-operations><action>deny</action></rule>
+    <rule><name>vlan-write</name><module-name>sonic-vlan</module-name><access-operations>create update delete</access-operations><action>permit</action></rule>
+  </rule-list>
+</nacm>`)
+	defer resetTestNacm()
+
+	alice := nacmTestUser{TestAuthenticator: NewTestAuthenticator(true), name: "alice"}
+	carol := nacmTestUser{TestAuthenticator: NewTestAuthenticator(true), name: "carol"}
+
+	request := func(operation string, body string) *xmlquery.Node {
+		node, _ := xmlquery.Parse(strings.NewReader("<rpc message-id=\"1\"><" + operation + ">" + body + "</" + operation + "></rpc>"))
+		return node
+	}
+
+	denied := func(name string, err error) {
+		if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != ErrorTagAccessDenied {
+			t.Errorf("%s: got %v, want: %s", name, err, ErrorTagAccessDenied)
+		}
+	}
+
+	vlans := func(vlanid string) string {
+		return `<source><config><sonic-vlan xmlns="http://github.com/Azure/sonic-vlan"><VLAN>` +
+			`<VLAN_LIST><name>Vlan100</name><vlanid>` + vlanid + `</vlanid></VLAN_LIST>` +
+			`<VLAN_LIST><name>Vlan200</name><vlanid>200</vlanid></VLAN_LIST>` +
+			`</VLAN></sonic-vlan></config></source>`
+	}
+
+	ioutil.WriteFile(StartupConfigPath, []byte(`{"VLAN": {"Vlan100": {"vlanid": "100"}, "Vlan200": {"vlanid": "200"}}}`), 0644)
+
+	// Copying the same content changes no node
+	if _, err := CopyConfigHandler(alice, request("copy-config", "<target><startup/></target>"+vlans("100"))); err != nil {
+		t.Errorf("Unchanged copy: unexpected error %v", err)
+	}
+
+	_, err = CopyConfigHandler(alice, request("copy-config", "<target><startup/></target>"+vlans("101")))
+	denied("update without write access", err)
+
+	if _, err := CopyConfigHandler(carol, request("copy-config", "<target><startup/></target>"+vlans("101"))); err != nil {
+		t.Errorf("Permitted update: unexpected error %v", err)
+	}
+
+	// Only the readable nodes are written to a URL
+	backup := "file://" + filepath.Join(dir, "vlan.xml")
+
+	if _, err := CopyConfigHandler(carol, request("copy-config", "<target><url>"+backup+"</url></target><source><startup/></source>")); err != nil {
+		t.Fatalf("Copy to URL: unexpected error %v", err)
+	}
+
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "vlan.xml"))
+	if !strings.Contains(string(data), "<vlanid>101</vlanid>") || strings.Contains(string(data), "Vlan200") {
+		t.Errorf("Copy to URL: got %s, want: Vlan100 only", data)
+	}
+
+	_, err = DeleteConfigHandler(alice, request("delete-config", "<target><url>"+backup+"</url></target>"))
+	denied("delete without write access", err)
+
+	if _, err := DeleteConfigHandler(carol, request("delete-config", "<target><url>"+backup+"</url></target>")); err != nil {
+		t.Errorf("Permitted delete: unexpected error %v", err)
+	}
+
+	// Tables outside the permitted module are protected by write-default
+	ioutil.WriteFile(StartupConfigPath, []byte(testConfigDb), 0644)
+
+	_, err = DeleteConfigHandler(carol, request("delete-config", "<target><startup/></target>"))
+	denied("delete of another module", err)
+
+	_, err = CopyConfigHandler(carol, request("copy-config", "<target><startup/></target>"+vlans("100")))
+	denied("copy deleting another module", err)
+
+	if _, statErr := os.Stat(StartupConfigPath); statErr != nil {
+		t.Errorf("Expected the startup configuration to be kept, got: %v", statErr)
+	}
+}
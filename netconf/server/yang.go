@@ -0,0 +1,333 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+const NsYin = "urn:ietf:params:xml:ns:yang:yin:1"
+
+// yangSubstatement returns the first substatement with the given keyword, nil if none
+func yangSubstatement(s *yang.Statement, keyword string) *yang.Statement {
+	for _, sub := range s.SubStatements() {
+		if sub.Keyword == keyword {
+			return sub
+		}
+	}
+	return nil
+}
+
+// yangSubstatements returns all substatements with the given keyword
+func yangSubstatements(s *yang.Statement, keyword string) []*yang.Statement {
+	found := []*yang.Statement{}
+	for _, sub := range s.SubStatements() {
+		if sub.Keyword == keyword {
+			found = append(found, sub)
+		}
+	}
+	return found
+}
+
+// yangRevision returns the most recent revision date of a module or submodule
+func yangRevision(s *yang.Statement) string {
+	latest := ""
+	for _, rev := range yangSubstatements(s, "revision") {
+		if rev.Argument > latest {
+			latest = rev.Argument
+		}
+	}
+	return latest
+}
+
+// ParseYang parses a YANG module or submodule source into its top level
+// statement, with the goyang parser used for the schema tree
+func ParseYang(src string) (*yang.Statement, error) {
+
+	stmts, err := yang.Parse(src, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stmts) != 1 || (stmts[0].Keyword != "module" && stmts[0].Keyword != "submodule") {
+		return nil, errors.New("yang: expected a single module or submodule statement")
+	}
+
+	return stmts[0], nil
+}
+
+// yinArgument describes how the argument of a YANG keyword is mapped to YIN
+type yinArgument struct {
+	name    string
+	element bool
+}
+
+// RFC 7950 section 13.1, keywords without an entry take no argument
+var yinArguments = map[string]yinArgument{
+	"action":           {"name", false},
+	"anydata":          {"name", false},
+	"anyxml":           {"name", false},
+	"argument":         {"name", false},
+	"augment":          {"target-node", false},
+	"base":             {"name", false},
+	"belongs-to":       {"module", false},
+	"bit":              {"name", false},
+	"case":             {"name", false},
+	"choice":           {"name", false},
+	"config":           {"value", false},
+	"contact":          {"text", true},
+	"container":        {"name", false},
+	"default":          {"value", false},
+	"description":      {"text", true},
+	"deviate":          {"value", false},
+	"deviation":        {"target-node", false},
+	"enum":             {"name", false},
+	"error-app-tag":    {"value", false},
+	"error-message":    {"value", true},
+	"extension":        {"name", false},
+	"feature":          {"name", false},
+	"fraction-digits":  {"value", false},
+	"grouping":         {"name", false},
+	"identity":         {"name", false},
+	"if-feature":       {"name", false},
+	"import":           {"module", false},
+	"include":          {"module", false},
+	"key":              {"value", false},
+	"leaf":             {"name", false},
+	"leaf-list":        {"name", false},
+	"length":           {"value", false},
+	"list":             {"name", false},
+	"mandatory":        {"value", false},
+	"max-elements":     {"value", false},
+	"min-elements":     {"value", false},
+	"modifier":         {"value", false},
+	"module":           {"name", false},
+	"must":             {"condition", false},
+	"namespace":        {"uri", false},
+	"notification":     {"name", false},
+	"ordered-by":       {"value", false},
+	"organization":     {"text", true},
+	"path":             {"value", false},
+	"pattern":          {"value", false},
+	"position":         {"value", false},
+	"prefix":           {"value", false},
+	"presence":         {"value", false},
+	"range":            {"value", false},
+	"reference":        {"text", true},
+	"refine":           {"target-node", false},
+	"require-instance": {"value", false},
+	"revision":         {"date", false},
+	"revision-date":    {"date", false},
+	"rpc":              {"name", false},
+	"status":           {"value", false},
+	"submodule":        {"name", false},
+	"type":             {"name", false},
+	"typedef":          {"name", false},
+	"unique":           {"tag", false},
+	"units":            {"name", false},
+	"uses":             {"name", false},
+	"value":            {"value", false},
+	"when":             {"condition", false},
+	"yang-version":     {"value", false},
+	"yin-element":      {"value", false},
+}
+
+// xmlEscaper escapes text for the reply payload. Ampersands use a character
+// reference since CreateResponse unescapes "&amp;" in translib output.
+var (
+	xmlEscaper     = strings.NewReplacer("&", "&#38;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&#38;", "<", "&lt;", ">", "&gt;", "\"", "&#34;")
+)
+
+func xmlEscape(s string) string {
+	return xmlEscaper.Replace(s)
+}
+
+func xmlEscapeAttr(s string) string {
+	return xmlAttrEscaper.Replace(s)
+}
+
+// yinConverter converts a parsed YANG module to its YIN representation (RFC 7950 section 13)
+type yinConverter struct {
+	namespaces map[string]string                 // prefix -> namespace
+	extensions map[string]map[string]yinArgument // prefix -> extension -> argument
+	builder    strings.Builder
+}
+
+// ToYin returns the YIN document equivalent of the given module or submodule.
+// lookup is used to load imported modules, for namespaces and extension definitions.
+func ToYin(module *yang.Statement, lookup func(name string) *yang.Statement) string {
+
+	c := yinConverter{
+		namespaces: map[string]string{},
+		extensions: map[string]map[string]yinArgument{},
+	}
+
+	own := ""
+	if module.Keyword == "module" {
+		if prefix := yangSubstatement(module, "prefix"); prefix != nil {
+			own = prefix.Argument
+		}
+		if ns := yangSubstatement(module, "namespace"); ns != nil {
+			c.namespaces[own] = ns.Argument
+		}
+	} else if belongsTo := yangSubstatement(module, "belongs-to"); belongsTo != nil {
+		if prefix := yangSubstatement(belongsTo, "prefix"); prefix != nil {
+			own = prefix.Argument
+		}
+		if parent := lookup(belongsTo.Argument); parent != nil {
+			if ns := yangSubstatement(parent, "namespace"); ns != nil {
+				c.namespaces[own] = ns.Argument
+			}
+		}
+	}
+	c.extensions[own] = yinExtensions(module)
+
+	for _, imp := range yangSubstatements(module, "import") {
+		prefix := yangSubstatement(imp, "prefix")
+		if prefix == nil {
+			continue
+		}
+		imported := lookup(imp.Argument)
+		if imported == nil {
+			continue
+		}
+		if ns := yangSubstatement(imported, "namespace"); ns != nil {
+			c.namespaces[prefix.Argument] = ns.Argument
+		}
+		c.extensions[prefix.Argument] = yinExtensions(imported)
+	}
+
+	c.statement(module, 0, true)
+
+	return c.builder.String()
+}
+
+func yinExtensions(module *yang.Statement) map[string]yinArgument {
+	extensions := map[string]yinArgument{}
+	for _, ext := range yangSubstatements(module, "extension") {
+		arg := yangSubstatement(ext, "argument")
+		if arg == nil {
+			extensions[ext.Argument] = yinArgument{}
+			continue
+		}
+		yinElement := yangSubstatement(arg, "yin-element")
+		extensions[ext.Argument] = yinArgument{arg.Argument, yinElement != nil && yinElement.Argument == "true"}
+	}
+	return extensions
+}
+
+func (c *yinConverter) argument(keyword string) (yinArgument, bool) {
+	if i := strings.Index(keyword, ":"); i >= 0 {
+		if exts, ok := c.extensions[keyword[:i]]; ok {
+			if arg, ok := exts[keyword[i+1:]]; ok {
+				return arg, arg.name != ""
+			}
+		}
+		// Unknown extension, keep the argument as an attribute
+		return yinArgument{"value", false}, true
+	}
+	arg, ok := yinArguments[keyword]
+	return arg, ok
+}
+
+func (c *yinConverter) statement(stmt *yang.Statement, depth int, root bool) {
+
+	indent := strings.Repeat("  ", depth)
+	arg, hasArg := c.argument(stmt.Keyword)
+
+	c.builder.WriteString(indent + "<" + stmt.Keyword)
+
+	if hasArg && !arg.element && stmt.HasArgument {
+		c.builder.WriteString(" " + arg.name + "=\"" + xmlEscapeAttr(stmt.Argument) + "\"")
+	}
+
+	if root {
+		c.builder.WriteString(" xmlns=\"" + NsYin + "\"")
+		prefixes := make([]string, 0, len(c.namespaces))
+		for prefix := range c.namespaces {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+		for _, prefix := range prefixes {
+			if prefix != "" {
+				c.builder.WriteString(" xmlns:" + prefix + "=\"" + xmlEscapeAttr(c.namespaces[prefix]) + "\"")
+			}
+		}
+	}
+
+	argElement := hasArg && arg.element && stmt.HasArgument
+
+	if !argElement && len(stmt.SubStatements()) == 0 {
+		c.builder.WriteString("/>\n")
+		return
+	}
+
+	c.builder.WriteString(">\n")
+
+	if argElement {
+		c.builder.WriteString(indent + "  <" + arg.name + ">" + xmlEscape(stmt.Argument) + "</" + arg.name + ">\n")
+	}
+
+	for _, sub := range stmt.SubStatements() {
+		c.statement(sub, depth+1, false)
+	}
+
+	c.builder.WriteString(indent + "</" + stmt.Keyword + ">\n")
+}
+
+// yangFilePath returns the path of a YANG file in the model directory, files
+// named <name>@<revision>.yang take precedence over <name>.yang
+func yangFilePath(name string, revision string) string {
+	if revision != "" {
+		revPath := filepath.Join(YangModelDir, name+"@"+revision+".yang")
+		if _, err := os.Stat(revPath); err == nil {
+			return revPath
+		}
+	}
+	return filepath.Join(YangModelDir, name+".yang")
+}
+
+// loadYangFile reads and parses a YANG file
+func loadYangFile(path string) (*yang.Statement, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseYang(string(data))
+}
+
+// lookupYangModule loads a module known to the server by name, nil if it cannot be read
+func lookupYangModule(name string) *yang.Statement {
+	path := yangFilePath(name, "")
+	for _, schema := range YangSchemas[strings.ToLower(name)] {
+		if schema.Format == "yang" {
+			path = schema.ModelPath
+			break
+		}
+	}
+
+	module, err := loadYangFile(path)
+	if err != nil {
+		return nil
+	}
+	return module
+}
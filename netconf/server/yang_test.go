@@ -0,0 +1,192 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func init() {
+	fmt.Println("+++++ init yang_test +++++")
+}
+
+const testYangModule = `module test-module {
+  yang-version 1.1;
+  namespace "urn:test:module";
+  prefix tm;
+
+  import ietf-inet-types { prefix inet; }
+  include test-submodule;
+
+  organization "Test & co";
+  description
+    "First line
+     second line";
+
+  revision 2024-01-01 { description "Initial"; }
+  revision 2024-06-01;
+
+  /* block comment */
+  container top {
+    leaf address {
+      type inet:ip-address; // line comment
+      must "../a < 5" + ' and ../b > 2';
+    }
+  }
+}
+`
+
+const testYangSubmodule = `submodule test-submodule {
+  belongs-to test-module { prefix tm; }
+  revision 2024-02-01;
+}
+`
+
+func TestParseYang(t *testing.T) {
+
+	module, err := ParseYang(testYangModule)
+
+	if err != nil {
+		t.Fatalf("Unexpected parse error %v", err)
+	}
+
+	if module.Keyword != "module" || module.Argument != "test-module" {
+		t.Errorf("Result was incorrect, got: %s %s, want: module test-module", module.Keyword, module.Argument)
+	}
+
+	if yangRevision(module) != "2024-06-01" {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", yangRevision(module), "2024-06-01")
+	}
+
+	description := yangSubstatement(module, "description").Argument
+	if description != "First line\nsecond line" {
+		t.Errorf("Result was incorrect, got: %q, want: %q.", description, "First line\nsecond line")
+	}
+
+	must := yangSubstatement(yangSubstatement(yangSubstatement(module, "container"), "leaf"), "must").Argument
+	if must != "../a < 5 and ../b > 2" {
+		t.Errorf("Result was incorrect, got: %q, want: %q.", must, "../a < 5 and ../b > 2")
+	}
+
+	if _, err := ParseYang("module broken { leaf a { type string; }"); err == nil {
+		t.Errorf("Expected an error for unterminated module")
+	}
+}
+
+func TestToYin(t *testing.T) {
+
+	module, _ := ParseYang(testYangModule)
+
+	lookup := func(name string) *yang.Statement {
+		if name == "ietf-inet-types" {
+			m, _ := ParseYang(`module ietf-inet-types { namespace "urn:ietf:params:xml:ns:yang:ietf-inet-types"; prefix inet; }`)
+			return m
+		}
+		return nil
+	}
+
+	yin := ToYin(module, lookup)
+
+	expected := []string{
+		`<module name="test-module" xmlns="urn:ietf:params:xml:ns:yang:yin:1" xmlns:inet="urn:ietf:params:xml:ns:yang:ietf-inet-types" xmlns:tm="urn:test:module">`,
+		`<namespace uri="urn:test:module"/>`,
+		"<organization>\n    <text>Test &#38; co</text>\n  </organization>",
+		`<revision date="2024-01-01">`,
+		`<must condition="../a &lt; 5 and ../b &gt; 2"/>`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(yin, e) {
+			t.Errorf("Result was incorrect, %s not found in:\n%s", e, yin)
+		}
+	}
+
+	if _, err := xmlquery.Parse(strings.NewReader(yin)); err != nil {
+		t.Errorf("Result is not valid XML %v", err)
+	}
+}
+
+func TestGetSchemaHandler(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "yang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "test-module.yang"), []byte(testYangModule), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "test-submodule.yang"), []byte(testYangSubmodule), 0644)
+
+	savedDir, savedSchemas := YangModelDir, YangSchemas
+	defer func() { YangModelDir, YangSchemas = savedDir, savedSchemas }()
+
+	YangModelDir = dir
+	YangSchemas = make(map[string][]Schema)
+
+	schema := Schema{Identifier: "test-module", Version: "2024-06-01", Format: "yang", NameSpace: "urn:test:module", ModelPath: yangFilePath("test-module", "2024-06-01")}
+	addSchema(schema)
+	addSchema(Schema{Identifier: "test-module", Version: "2023-01-01", Format: "yang", NameSpace: "urn:test:module", ModelPath: yangFilePath("test-module", "2023-01-01")})
+
+	module, _ := loadYangFile(schema.ModelPath)
+	submodules := readYangSubmodules(module, schema)
+
+	if len(submodules) != 1 || submodules[0].Argument != "test-submodule" {
+		t.Errorf("Result was incorrect, expected test-submodule to be included")
+	}
+
+	request := func(body string) *xmlquery.Node {
+		node, _ := xmlquery.Parse(strings.NewReader("<rpc message-id=\"1\"><get-schema xmlns=\"" + NsNetconfMonitoring + "\">" + body + "</get-schema></rpc>"))
+		return node
+	}
+
+	errorTag := func(err error) string {
+		if rpcErr, ok := err.(*RPCError); ok {
+			return rpcErr.ErrorTag
+		}
+		return ""
+	}
+
+	_, err = GetSchemaHandler(request("<identifier>unknown</identifier>"))
+	if errorTag(err) != ErrorTagInvalidValue {
+		t.Errorf("Result was incorrect, got: %v, want: %s.", err, ErrorTagInvalidValue)
+	}
+
+	_, err = GetSchemaHandler(request("<identifier>test-module</identifier><version>2000-01-01</version>"))
+	if errorTag(err) != ErrorTagInvalidValue {
+		t.Errorf("Result was incorrect, got: %v, want: %s.", err, ErrorTagInvalidValue)
+	}
+
+	_, err = GetSchemaHandler(request("<identifier>test-module</identifier>"))
+	if rpcErr, ok := err.(*RPCError); !ok || rpcErr.ErrorTag != ErrorTagOperationFailed || rpcErr.ErrorAppTag != ErrorAppTagDataNotUnique {
+		t.Errorf("Result was incorrect, got: %v, want: %s with app tag %s.", err, ErrorTagOperationFailed, ErrorAppTagDataNotUnique)
+	}
+
+	result, err := GetSchemaHandler(request("<identifier>test-module</identifier><version>2024-06-01</version>"))
+	if err != nil || !strings.Contains(result, "organization \"Test &#38; co\";") || !strings.Contains(result, "\"../a &lt; 5\"") {
+		t.Errorf("Result was incorrect, got: %s %v", result, err)
+	}
+
+	result, err = GetSchemaHandler(request("<identifier>test-submodule</identifier><format>ncm:yin</format>"))
+	if err != nil || !strings.Contains(result, "<submodule name=\"test-submodule\"") || !strings.Contains(result, "xmlns:tm=\"urn:test:module\"") {
+		t.Errorf("Result was incorrect, got: %s %v", result, err)
+	}
+}
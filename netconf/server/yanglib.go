@@ -0,0 +1,210 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"sort"
+	"strings"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+const yangLibModuleSet = "complete"
+
+var (
+	YangLib YangLibrary
+
+	// Datastores reported in the yang library, all sharing the complete schema
+	yangLibDatastores = nmdaReadable
+)
+
+// Features implemented by the server, by module name. Defining a feature in
+// a module does not mean it is supported
+var SupportedFeatures = map[string][]string{
+	"ietf-netconf-nmda": {"origin"},
+}
+
+// yangFeatures returns the supported features defined by a module and its submodules
+func yangFeatures(module *yang.Statement, submodules []*yang.Statement) []string {
+	features := []string{}
+	for _, stmt := range append([]*yang.Statement{module}, submodules...) {
+		for _, feature := range yangSubstatements(stmt, "feature") {
+			if contains(SupportedFeatures[module.Argument], feature.Argument) {
+				features = append(features, feature.Argument)
+			}
+		}
+	}
+	return features
+}
+
+// yangDeviations returns the modules targeted by the deviation statements of a module
+func yangDeviations(module *yang.Statement) []string {
+
+	prefixes := map[string]string{}
+	for _, imp := range yangSubstatements(module, "import") {
+		if prefix := yangSubstatement(imp, "prefix"); prefix != nil {
+			prefixes[prefix.Argument] = imp.Argument
+		}
+	}
+
+	targets := []string{}
+	for _, deviation := range yangSubstatements(module, "deviation") {
+
+		// Target node is an absolute schema node id, /prefix:node/...
+		node := strings.TrimPrefix(deviation.Argument, "/")
+		i := strings.Index(node, ":")
+		if i < 0 {
+			continue
+		}
+
+		target, ok := prefixes[node[:i]]
+		if ok && !contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets
+}
+
+// addYangDeviations fills the deviation list of each module from the parsed module files
+func addYangDeviations(modules []Module, parsed map[string]*yang.Statement) {
+
+	for name, stmt := range parsed {
+
+		revision := yangRevision(stmt)
+
+		for _, target := range yangDeviations(stmt) {
+			for i := range modules {
+				if *modules[i].Name == target {
+					modules[i].Deviation = append(modules[i].Deviation, ModuleRef{Name: name, Revision: revision})
+				}
+			}
+		}
+	}
+}
+
+// buildYangLibrary creates the RFC 8525 yang library from the RFC 7895 module list
+func buildYangLibrary(modules []Module) YangLibrary {
+
+	sorted := make([]Module, len(modules))
+	copy(sorted, modules)
+	sort.Slice(sorted, func(i, j int) bool {
+		return *sorted[i].Name < *sorted[j].Name || (*sorted[i].Name == *sorted[j].Name && *sorted[i].Revision < *sorted[j].Revision)
+	})
+
+	moduleSet := YangLibModuleSet{Name: yangLibModuleSet}
+
+	for _, mod := range sorted {
+
+		libModule := YangLibModule{
+			Name:      *mod.Name,
+			Revision:  *mod.Revision,
+			Namespace: *mod.Namespace,
+		}
+
+		for _, submodule := range mod.Submodule {
+			libModule.Submodules = append(libModule.Submodules, YangLibModule{Name: submodule.Name, Revision: submodule.Revision})
+		}
+
+		if mod.ConformanceType == "import" {
+			moduleSet.ImportOnlyModules = append(moduleSet.ImportOnlyModules, libModule)
+			continue
+		}
+
+		libModule.Features = mod.Feature
+		for _, deviation := range mod.Deviation {
+			libModule.Deviations = append(libModule.Deviations, deviation.Name)
+		}
+
+		moduleSet.Modules = append(moduleSet.Modules, libModule)
+	}
+
+	library := YangLibrary{
+		ModuleSets: []YangLibModuleSet{moduleSet},
+		Schemas:    []YangLibSchema{{Name: yangLibModuleSet, ModuleSets: []string{yangLibModuleSet}}},
+	}
+
+	for _, datastore := range yangLibDatastores {
+		library.Datastores = append(library.Datastores, YangLibDatastore{
+			Name:   DatastoreName{Ns: NsDatastores, Value: datastore},
+			Schema: yangLibModuleSet,
+		})
+	}
+
+	library.ContentId = yangLibContentId(library)
+
+	return library
+}
+
+// yangLibContentId identifies the content of the yang library, it changes
+// whenever a module, feature, deviation or datastore changes
+func yangLibContentId(library YangLibrary) string {
+
+	h := sha256.New()
+
+	for _, set := range library.ModuleSets {
+		for _, mod := range set.Modules {
+			yangLibHashModule(h, mod)
+		}
+		for _, mod := range set.ImportOnlyModules {
+			yangLibHashModule(h, mod)
+		}
+	}
+
+	for _, datastore := range library.Datastores {
+		h.Write([]byte("ds:" + datastore.Name.Value + ";"))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func yangLibHashModule(h hash.Hash, mod YangLibModule) {
+	h.Write([]byte(mod.Name + "@" + mod.Revision + ";"))
+	for _, submodule := range mod.Submodules {
+		h.Write([]byte("s:" + submodule.Name + "@" + submodule.Revision + ";"))
+	}
+	for _, feature := range mod.Features {
+		h.Write([]byte("f:" + feature + ";"))
+	}
+	for _, deviation := range mod.Deviations {
+		h.Write([]byte("d:" + deviation + ";"))
+	}
+}
+
+// moduleCapability returns the hello capability of a module (RFC 7950 section 5.6.4)
+func moduleCapability(mod Module) string {
+
+	capability := *mod.Namespace + "?module=" + *mod.Name
+
+	if mod.Revision != nil && *mod.Revision != "" {
+		capability += "&revision=" + *mod.Revision
+	}
+
+	if len(mod.Feature) > 0 {
+		capability += "&features=" + strings.Join(mod.Feature, ",")
+	}
+
+	if len(mod.Deviation) > 0 {
+		deviations := []string{}
+		for _, deviation := range mod.Deviation {
+			deviations = append(deviations, deviation.Name)
+		}
+		capability += "&deviations=" + strings.Join(deviations, ",")
+	}
+
+	return capability
+}
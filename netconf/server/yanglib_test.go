@@ -0,0 +1,158 @@
+//
+// Software Name: sonic-netconf-server
+// SPDX-FileCopyrightText: Copyright (c) Orange SA
+// SPDX-License-Identifier: Apache 2.0
+//
+// This software is distributed under the Apache 2.0 licence,
+// the text of which is available at https://opensource.org/license/apache-2-0/
+// or see the "LICENSE" file for more details.
+//
+// Authors: hossam4.hassan@orange.com, abdelmuhaimen.seaudi@orange.com
+// Software description: RFC compliant NETCONF server implementation for SONiC
+//
+
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openconfig/goyang/pkg/yang"
+)
+
+func init() {
+	fmt.Println("+++++ init yanglib_test +++++")
+}
+
+func testModule(name string, revision string, conformance string) Module {
+	namespace := "urn:test:" + name
+	return Module{Name: &name, Revision: &revision, Namespace: &namespace, ConformanceType: conformance}
+}
+
+func TestBuildYangLibrary(t *testing.T) {
+
+	deviations, _ := ParseYang(`module test-deviations {
+		namespace "urn:test:test-deviations";
+		prefix td;
+		import test-module { prefix tm; }
+		revision 2024-03-01;
+		deviation /tm:top/tm:address { deviate not-supported; }
+	}`)
+
+	modules := []Module{
+		testModule("test-module", "2024-06-01", "implement"),
+		testModule("test-deviations", "2024-03-01", "implement"),
+		testModule("test-types", "2020-01-01", "import"),
+	}
+	modules[0].Feature = []string{"feat-a"}
+	modules[0].Submodule = []ModuleRef{{Name: "test-submodule", Revision: "2024-02-01"}}
+
+	addYangDeviations(modules, map[string]*yang.Statement{"test-deviations": deviations})
+
+	if len(modules[0].Deviation) != 1 || modules[0].Deviation[0].Name != "test-deviations" {
+		t.Errorf("Result was incorrect, got: %+v, want: test-deviations deviation", modules[0].Deviation)
+	}
+
+	library := buildYangLibrary(modules)
+
+	if len(library.ModuleSets) != 1 || len(library.ModuleSets[0].Modules) != 2 || len(library.ModuleSets[0].ImportOnlyModules) != 1 {
+		t.Fatalf("Result was incorrect, unexpected module set %+v", library.ModuleSets)
+	}
+
+	if library.ContentId == "" || library.ContentId != buildYangLibrary(modules).ContentId {
+		t.Errorf("Result was incorrect, content-id must be stable, got: %s", library.ContentId)
+	}
+
+	modules[0].Feature = nil
+	if library.ContentId == buildYangLibrary(modules).ContentId {
+		t.Errorf("Result was incorrect, content-id must change with the module set")
+	}
+
+	output, err := xml.Marshal(library)
+	if err != nil {
+		t.Fatalf("Unable to marshal yang library %v", err)
+	}
+
+	expected := []string{
+		`<yang-library xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-library">`,
+		`<module><name>test-module</name><revision>2024-06-01</revision><namespace>urn:test:test-module</namespace><submodule><name>test-submodule</name><revision>2024-02-01</revision></submodule><feature>feat-a</feature><deviation>test-deviations</deviation></module>`,
+		`<import-only-module><name>test-types</name>`,
+		`<datastore><name xmlns:ds="urn:ietf:params:xml:ns:yang:ietf-datastores">ds:running</name><schema>complete</schema></datastore>`,
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(string(output), e) {
+			t.Errorf("Result was incorrect, %s not found in %s", e, output)
+		}
+	}
+}
+
+func TestModuleCapability(t *testing.T) {
+
+	mod := testModule("test-module", "2024-06-01", "implement")
+	mod.Feature = []string{"feat-a", "feat-b"}
+	mod.Deviation = []ModuleRef{{Name: "test-deviations"}}
+
+	result := moduleCapability(mod)
+	correct := "urn:test:test-module?module=test-module&revision=2024-06-01&features=feat-a,feat-b&deviations=test-deviations"
+
+	if result != correct {
+		t.Errorf("Result was incorrect, got: %s, want: %s.", result, correct)
+	}
+}
+
+func TestYangFeatures(t *testing.T) {
+
+	module, _ := ParseYang(`module test-module {
+		namespace "urn:test:test-module";
+		prefix tm;
+		feature feat-a;
+		feature feat-b;
+	}`)
+
+	if features := yangFeatures(module, nil); len(features) != 0 {
+		t.Errorf("Defined features reported as supported: %v", features)
+	}
+
+	SupportedFeatures["test-module"] = []string{"feat-b", "feat-c"}
+	defer delete(SupportedFeatures, "test-module")
+
+	if features := yangFeatures(module, nil); strings.Join(features, ",") != "feat-b" {
+		t.Errorf("Result was incorrect, got: %v, want: feat-b", features)
+	}
+}
+
+func TestServerModules(t *testing.T) {
+
+	savedDir, savedModules, savedSchemas := YangModelDir, YangModules.Modules, YangSchemas
+	defer func() { YangModelDir, YangModules.Modules, YangSchemas = savedDir, savedModules, savedSchemas }()
+
+	// Modules shipped with the server
+	YangModelDir = "../../models/netconf"
+	YangModules.Modules, YangSchemas = nil, map[string][]Schema{}
+
+	addServerModule("ietf-netconf-nmda", NetconfNmdaRevision, NsNetconfNmda)
+	addServerModule("ietf-origin", OriginRevision, NsOrigin)
+	addServerModule("sonic-netconf-login", NetconfLoginRevision, NsNetconfLogin)
+
+	for _, module := range YangModules.Modules {
+		stmt, err := loadYangFile(yangFilePath(*module.Name, *module.Revision))
+		if err != nil || yangRevision(stmt) != *module.Revision || yangSubstatement(stmt, "namespace").Argument != *module.Namespace {
+			t.Errorf("Invalid yang file of %s: %v", *module.Name, err)
+		}
+	}
+
+	if nmda := YangModules.Modules[0]; strings.Join(nmda.Feature, ",") != "origin" {
+		t.Errorf("Result was incorrect, got: %v, want: origin.", nmda.Feature)
+	}
+
+	library := buildYangLibrary(YangModules.Modules)
+	output, _ := xml.Marshal(library)
+	for _, datastore := range []string{DsRunning, DsStartup, DsIntended, DsOperational} {
+		if !strings.Contains(string(output), datastore) {
+			t.Errorf("Datastore %s missing from the yang library %s", datastore, output)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package radius
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net"
+	"time"
+)
+
+var errTimeout = errors.New("RADIUS server did not answer")
+
+// Client sends requests to a RADIUS server over UDP
+type Client struct {
+	Addr    string
+	Secret  []byte
+	Timeout time.Duration // Wait for an answer before retransmitting
+	Retries int           // Retransmissions after the first attempt
+}
+
+// Exchange signs a copy of a request, sends it and returns the verified
+// response. The User-Password of the request is in clear, it is hidden
+// with the secret of the server, so requests may be sent to several servers
+func (c *Client) Exchange(ctx context.Context, template *Packet) (*Packet, error) {
+
+	random := make([]byte, 1+authenticatorLen)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+
+	request := &Packet{Code: template.Code, Identifier: random[0]}
+	request.Attributes = append([]Attribute{}, template.Attributes...)
+
+	switch request.Code {
+	case CodeAccessRequest:
+		copy(request.Authenticator[:], random[1:])
+		for i, attr := range request.Attributes {
+			if attr.Type == AttrUserPassword {
+				request.Attributes[i].Value = EncryptPassword(attr.Value, c.Secret, request.Authenticator[:])
+			}
+		}
+		if err := request.SignAccessRequest(c.Secret); err != nil {
+			return nil, err
+		}
+	case CodeAccountingRequest:
+		if err := request.SignAccountingRequest(c.Secret); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("Unsupported RADIUS request")
+	}
+
+	b, err := request.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", c.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	buf := make([]byte, maxPacketLen)
+
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+
+		if _, err := conn.Write(b); err != nil {
+			return nil, err
+		}
+
+		deadline := time.Now().Add(c.Timeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		conn.SetReadDeadline(deadline)
+
+		for {
+			n, err := conn.Read(buf)
+
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					break
+				}
+				return nil, err
+			}
+
+			// Stale or forged answers are dropped
+			response, err := Unmarshal(buf[:n])
+			if err == nil && response.VerifyResponse(c.Secret, request) {
+				return response, nil
+			}
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, errTimeout
+}
@@ -0,0 +1,149 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package radius
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"orange/sonic-netconf-server/configdb"
+
+	"github.com/golang/glog"
+)
+
+// Default ports of RFC 2865 and RFC 2866
+const (
+	AuthPort = 1812
+	AcctPort = 1813
+)
+
+type RadiusInfo struct {
+	IP         string
+	AuthPort   int
+	AcctPort   int
+	Priority   int
+	Secret     string
+	Timeout    int // Seconds before a retransmission
+	Retransmit int
+	AuthType   string // pap or chap
+	NASIP      string
+}
+
+// CONFIG_DB client for the RADIUS configuration
+var redisClient = configdb.Client
+
+func IsRadiusEnabled() bool {
+	radiusKeys, err := redisClient.Keys("RADIUS_SERVER|*").Result()
+	if err != nil {
+		glog.Infof("IsRadiusEnabled - Can't read radius info")
+		return false
+	}
+	return len(radiusKeys) != 0
+}
+
+// GetRadiusInfo returns the RADIUS servers, sorted in ascending order by priority
+// as the TACACS+ servers are
+func GetRadiusInfo() ([]RadiusInfo, error) {
+
+	global := RadiusInfo{AuthPort: AuthPort, AcctPort: AcctPort, Timeout: 5, Retransmit: 3, AuthType: "pap"}
+
+	radiusGlobal, err := redisClient.HGetAll("RADIUS|global").Result()
+
+	if err == nil {
+		readRadiusInfo(radiusGlobal, &global)
+	}
+
+	radiusKeys, err := redisClient.Keys("RADIUS_SERVER|*").Result()
+
+	if err != nil || len(radiusKeys) == 0 {
+		return nil, errors.New("Cannot find any valid radius server configuration")
+	}
+
+	infos := make([]RadiusInfo, 0, len(radiusKeys))
+
+	for _, key := range radiusKeys {
+
+		serverData, err := redisClient.HGetAll(key).Result()
+
+		if err != nil {
+			return nil, errors.New("Unable to read server information")
+		}
+
+		info := global
+		info.IP = strings.Split(key, "|")[1]
+
+		readRadiusInfo(serverData, &info)
+
+		infos = append(infos, info)
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Priority < infos[j].Priority
+	})
+
+	return infos, nil
+}
+
+// readRadiusInfo overrides info with the fields of a RADIUS or RADIUS_SERVER entry
+func readRadiusInfo(data map[string]string, info *RadiusInfo) {
+
+	if port, err := strconv.Atoi(data["auth_port"]); err == nil {
+		info.AuthPort = port
+	}
+
+	if port, err := strconv.Atoi(data["acct_port"]); err == nil {
+		info.AcctPort = port
+	}
+
+	if priority, err := strconv.Atoi(data["priority"]); err == nil {
+		info.Priority = priority
+	}
+
+	if pass, ok := data["passkey"]; ok {
+		info.Secret = pass
+	}
+
+	if timeout, err := strconv.Atoi(data["timeout"]); err == nil {
+		info.Timeout = timeout
+	}
+
+	if retransmit, err := strconv.Atoi(data["retransmit"]); err == nil {
+		info.Retransmit = retransmit
+	}
+
+	if authType, ok := data["auth_type"]; ok {
+		info.AuthType = authType
+	}
+
+	if nasIP, ok := data["nas_ip"]; ok {
+		info.NASIP = nasIP
+	}
+}
+
+func (info *RadiusInfo) authAddr() string {
+	return net.JoinHostPort(info.IP, strconv.Itoa(info.AuthPort))
+}
+
+func (info *RadiusInfo) acctAddr() string {
+	return net.JoinHostPort(info.IP, strconv.Itoa(info.AcctPort))
+}
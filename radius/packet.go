@@ -0,0 +1,459 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package radius is a RADIUS client for authentication (RFC 2865) and
+// accounting (RFC 2866)
+package radius
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+)
+
+// Code field values
+const (
+	CodeAccessRequest      = 1
+	CodeAccessAccept       = 2
+	CodeAccessReject       = 3
+	CodeAccountingRequest  = 4
+	CodeAccountingResponse = 5
+	CodeAccessChallenge    = 11
+)
+
+// Attribute types
+const (
+	AttrUserName             = 1
+	AttrUserPassword         = 2
+	AttrCHAPPassword         = 3
+	AttrNASIPAddress         = 4
+	AttrNASPort              = 5
+	AttrServiceType          = 6
+	AttrReplyMessage         = 18
+	AttrClass                = 25
+	AttrVendorSpecific       = 26
+	AttrCallingStationID     = 31
+	AttrNASIdentifier        = 32
+	AttrAcctStatusType       = 40
+	AttrAcctSessionID        = 44
+	AttrAcctAuthentic        = 45
+	AttrAcctSessionTime      = 46
+	AttrAcctTerminateCause   = 49
+	AttrCHAPChallenge        = 60
+	AttrNASPortType          = 61
+	AttrMessageAuthenticator = 80
+)
+
+// Service-Type values
+const (
+	ServiceTypeLogin          = 1
+	ServiceTypeAdministrative = 6
+	ServiceTypeNASPrompt      = 7
+)
+
+// Acct-Status-Type values
+const (
+	AcctStatusStart = 1
+	AcctStatusStop  = 2
+	AcctStatusAlive = 3
+)
+
+// Acct-Authentic value of the users authenticated by RADIUS
+const AcctAuthenticRADIUS = 1
+
+// Acct-Terminate-Cause value of a session closed by the user
+const AcctTerminateUserRequest = 1
+
+// NAS-Port-Type value of the sessions with no physical port
+const NASPortTypeVirtual = 5
+
+// Vendor of the Cisco-AVPair attribute, used for the shell attributes
+const (
+	VendorCisco     = 9
+	CiscoAttrAVPair = 1
+)
+
+const (
+	headerLen        = 20
+	maxPacketLen     = 4096
+	authenticatorLen = 16
+)
+
+var errBadPacket = errors.New("Bad RADIUS packet")
+
+type Attribute struct {
+	Type  uint8
+	Value []byte
+}
+
+// Packet is a RADIUS packet, the attributes are kept in order
+type Packet struct {
+	Code          uint8
+	Identifier    uint8
+	Authenticator [authenticatorLen]byte
+	Attributes    []Attribute
+}
+
+func NewPacket(code uint8) *Packet {
+	return &Packet{Code: code}
+}
+
+// Add appends an attribute, values over 253 bytes are truncated
+func (p *Packet) Add(attrType uint8, value []byte) {
+	if len(value) > 253 {
+		value = value[:253]
+	}
+	p.Attributes = append(p.Attributes, Attribute{Type: attrType, Value: value})
+}
+
+func (p *Packet) AddString(attrType uint8, value string) {
+	p.Add(attrType, []byte(value))
+}
+
+func (p *Packet) AddUint32(attrType uint8, value uint32) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, value)
+	p.Add(attrType, b)
+}
+
+// AddVendor appends a Vendor-Specific attribute holding one vendor attribute
+func (p *Packet) AddVendor(vendor uint32, vendorType uint8, value []byte) {
+	if len(value) > 247 {
+		value = value[:247]
+	}
+	b := make([]byte, 6, 6+len(value))
+	binary.BigEndian.PutUint32(b, vendor)
+	b[4] = vendorType
+	b[5] = uint8(2 + len(value))
+	p.Add(AttrVendorSpecific, append(b, value...))
+}
+
+// Get returns the first attribute of a type
+func (p *Packet) Get(attrType uint8) ([]byte, bool) {
+	for _, attr := range p.Attributes {
+		if attr.Type == attrType {
+			return attr.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Packet) GetString(attrType uint8) string {
+	value, _ := p.Get(attrType)
+	return string(value)
+}
+
+func (p *Packet) GetUint32(attrType uint8) (uint32, bool) {
+	value, ok := p.Get(attrType)
+	if !ok || len(value) != 4 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(value), true
+}
+
+// Vendor returns the values of a vendor attribute, in order
+func (p *Packet) Vendor(vendor uint32, vendorType uint8) [][]byte {
+
+	values := [][]byte{}
+
+	for _, attr := range p.Attributes {
+
+		if attr.Type != AttrVendorSpecific || len(attr.Value) < 4 || binary.BigEndian.Uint32(attr.Value) != vendor {
+			continue
+		}
+
+		for b := attr.Value[4:]; len(b) >= 2 && int(b[1]) >= 2 && int(b[1]) <= len(b); b = b[b[1]:] {
+			if b[0] == vendorType {
+				values = append(values, b[2:b[1]])
+			}
+		}
+	}
+
+	return values
+}
+
+func (p *Packet) Marshal() ([]byte, error) {
+
+	b := make([]byte, headerLen, maxPacketLen)
+	b[0] = p.Code
+	b[1] = p.Identifier
+	copy(b[4:headerLen], p.Authenticator[:])
+
+	for _, attr := range p.Attributes {
+		b = append(b, attr.Type, uint8(2+len(attr.Value)))
+		b = append(b, attr.Value...)
+	}
+
+	if len(b) > maxPacketLen {
+		return nil, errors.New("RADIUS packet too large")
+	}
+
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)))
+
+	return b, nil
+}
+
+func Unmarshal(b []byte) (*Packet, error) {
+
+	if len(b) < headerLen {
+		return nil, errBadPacket
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if length < headerLen || length > len(b) || length > maxPacketLen {
+		return nil, errBadPacket
+	}
+	b = b[:length]
+
+	p := &Packet{Code: b[0], Identifier: b[1]}
+	copy(p.Authenticator[:], b[4:headerLen])
+
+	for attrs := b[headerLen:]; len(attrs) > 0; {
+		if len(attrs) < 2 || attrs[1] < 2 || int(attrs[1]) > len(attrs) {
+			return nil, errBadPacket
+		}
+		p.Attributes = append(p.Attributes, Attribute{Type: attrs[0], Value: append([]byte{}, attrs[2:attrs[1]]...)})
+		attrs = attrs[attrs[1]:]
+	}
+
+	return p, nil
+}
+
+// EncryptPassword hides a User-Password with the secret and the request
+// authenticator (RFC 2865 5.2)
+func EncryptPassword(password []byte, secret []byte, authenticator []byte) []byte {
+
+	length := (len(password) + 15) / 16 * 16
+	if length == 0 {
+		length = 16
+	}
+	if length > 128 {
+		length = 128
+	}
+
+	encrypted := make([]byte, length)
+	copy(encrypted, password)
+
+	last := authenticator
+	for i := 0; i < length; i += 16 {
+		hash := md5.New()
+		hash.Write(secret)
+		hash.Write(last)
+		for j, c := range hash.Sum(nil) {
+			encrypted[i+j] ^= c
+		}
+		last = encrypted[i : i+16]
+	}
+
+	return encrypted
+}
+
+// DecryptPassword reveals a User-Password, for servers
+func DecryptPassword(encrypted []byte, secret []byte, authenticator []byte) ([]byte, error) {
+
+	if len(encrypted) == 0 || len(encrypted)%16 != 0 || len(encrypted) > 128 {
+		return nil, errBadPacket
+	}
+
+	password := make([]byte, len(encrypted))
+
+	last := authenticator
+	for i := 0; i < len(encrypted); i += 16 {
+		hash := md5.New()
+		hash.Write(secret)
+		hash.Write(last)
+		for j, c := range hash.Sum(nil) {
+			password[i+j] = encrypted[i+j] ^ c
+		}
+		last = encrypted[i : i+16]
+	}
+
+	for len(password) > 0 && password[len(password)-1] == 0 {
+		password = password[:len(password)-1]
+	}
+
+	return password, nil
+}
+
+// CHAPPassword returns the CHAP-Password value, the CHAP id and the MD5
+// response to the challenge (RFC 2865 5.3)
+func CHAPPassword(id byte, password []byte, challenge []byte) []byte {
+
+	hash := md5.New()
+	hash.Write([]byte{id})
+	hash.Write(password)
+	hash.Write(challenge)
+
+	return append([]byte{id}, hash.Sum(nil)...)
+}
+
+// SignAccessRequest sets the Message-Authenticator of an Access-Request
+// whose random authenticator is set (RFC 3579 3.2), it is added when missing
+func (p *Packet) SignAccessRequest(secret []byte) error {
+	return p.setMessageAuthenticator(secret, p.Authenticator[:])
+}
+
+// SignAccountingRequest sets the authenticator of an Accounting-Request (RFC 2866 3)
+func (p *Packet) SignAccountingRequest(secret []byte) error {
+	p.Authenticator = [authenticatorLen]byte{}
+	b, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	copy(p.Authenticator[:], md5Sum(b, secret))
+	return nil
+}
+
+// VerifyAccountingRequest checks the authenticator of an Accounting-Request, for servers
+func (p *Packet) VerifyAccountingRequest(secret []byte) bool {
+	received := p.Authenticator
+	defer func() { p.Authenticator = received }()
+	if p.SignAccountingRequest(secret) != nil {
+		return false
+	}
+	return hmac.Equal(received[:], p.Authenticator[:])
+}
+
+// SignResponse sets the Message-Authenticator when present and the response
+// authenticator of a reply to request, for servers
+func (p *Packet) SignResponse(secret []byte, request *Packet) error {
+
+	p.Identifier = request.Identifier
+
+	if _, ok := p.Get(AttrMessageAuthenticator); ok {
+		if err := p.setMessageAuthenticator(secret, request.Authenticator[:]); err != nil {
+			return err
+		}
+	}
+
+	p.Authenticator = request.Authenticator
+	b, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+	copy(p.Authenticator[:], md5Sum(b, secret))
+
+	return nil
+}
+
+// VerifyResponse checks the response authenticator and the
+// Message-Authenticator of a reply to request. The Message-Authenticator is
+// mandatory in Access responses against forged replies (CVE-2024-3596)
+func (p *Packet) VerifyResponse(secret []byte, request *Packet) bool {
+
+	if p.Identifier != request.Identifier {
+		return false
+	}
+
+	response := *p
+	response.Authenticator = request.Authenticator
+	b, err := response.Marshal()
+	if err != nil || !hmac.Equal(md5Sum(b, secret), p.Authenticator[:]) {
+		return false
+	}
+
+	if _, ok := p.Get(AttrMessageAuthenticator); ok {
+		return response.checkMessageAuthenticator(secret, request.Authenticator[:])
+	}
+
+	switch p.Code {
+	case CodeAccessAccept, CodeAccessReject, CodeAccessChallenge:
+		return false
+	}
+
+	return true
+}
+
+// VerifyAccessRequest checks the Message-Authenticator of an Access-Request
+// when present, for servers
+func (p *Packet) VerifyAccessRequest(secret []byte) bool {
+	if _, ok := p.Get(AttrMessageAuthenticator); !ok {
+		return true
+	}
+	return p.checkMessageAuthenticator(secret, p.Authenticator[:])
+}
+
+func (p *Packet) setMessageAuthenticator(secret []byte, authenticator []byte) error {
+
+	index := p.messageAuthenticatorIndex()
+
+	p.Attributes[index].Value = make([]byte, 16)
+
+	signature, err := p.messageAuthenticator(secret, authenticator)
+	if err != nil {
+		return err
+	}
+
+	p.Attributes[index].Value = signature
+
+	return nil
+}
+
+func (p *Packet) checkMessageAuthenticator(secret []byte, authenticator []byte) bool {
+
+	signed := *p
+	signed.Attributes = append([]Attribute{}, p.Attributes...)
+
+	index := signed.messageAuthenticatorIndex()
+	received := signed.Attributes[index].Value
+	signed.Attributes[index].Value = make([]byte, 16)
+
+	signature, err := signed.messageAuthenticator(secret, authenticator)
+
+	return err == nil && hmac.Equal(received, signature)
+}
+
+// messageAuthenticatorIndex returns the index of the Message-Authenticator, added when missing
+func (p *Packet) messageAuthenticatorIndex() int {
+
+	for i, attr := range p.Attributes {
+		if attr.Type == AttrMessageAuthenticator {
+			return i
+		}
+	}
+
+	p.Add(AttrMessageAuthenticator, make([]byte, 16))
+
+	return len(p.Attributes) - 1
+}
+
+// messageAuthenticator is the HMAC-MD5 of the packet with authenticator in its header
+func (p *Packet) messageAuthenticator(secret []byte, authenticator []byte) ([]byte, error) {
+
+	signed := *p
+	copy(signed.Authenticator[:], authenticator)
+
+	b, err := signed.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(md5.New, secret)
+	mac.Write(b)
+
+	return mac.Sum(nil), nil
+}
+
+func md5Sum(b []byte, secret []byte) []byte {
+	hash := md5.New()
+	hash.Write(b)
+	hash.Write(secret)
+	return hash.Sum(nil)
+}
@@ -0,0 +1,153 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package radius
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init packet_test +++++")
+}
+
+// Example of RFC 2865 7.1
+func TestEncryptPassword(t *testing.T) {
+
+	authenticator, _ := hex.DecodeString("0f403f9473978057bd83d5cb98f4227a")
+	expected, _ := hex.DecodeString("0dbe708d93d413ce3196e43f782a0aee")
+
+	encrypted := EncryptPassword([]byte("arctangent"), []byte("xyzzy5461"), authenticator)
+	if !bytes.Equal(encrypted, expected) {
+		t.Errorf("Encrypted password %x", encrypted)
+	}
+
+	long := []byte("a password longer than sixteen bytes")
+	decrypted, err := DecryptPassword(EncryptPassword(long, []byte("secret"), authenticator), []byte("secret"), authenticator)
+	if err != nil || !bytes.Equal(decrypted, long) {
+		t.Errorf("Decrypted password %q %v", decrypted, err)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+
+	p := NewPacket(CodeAccessAccept)
+	p.Identifier = 7
+	p.AddString(AttrUserName, "admin")
+	p.AddUint32(AttrServiceType, ServiceTypeAdministrative)
+	p.AddVendor(VendorCisco, CiscoAttrAVPair, []byte("shell:priv-lvl=15"))
+
+	b, err := p.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if serviceType, _ := q.GetUint32(AttrServiceType); q.Identifier != 7 || q.GetString(AttrUserName) != "admin" || serviceType != ServiceTypeAdministrative {
+		t.Errorf("Unexpected packet %+v", q)
+	}
+
+	if avPairs := q.Vendor(VendorCisco, CiscoAttrAVPair); len(avPairs) != 1 || string(avPairs[0]) != "shell:priv-lvl=15" {
+		t.Errorf("Unexpected vendor attributes %q", avPairs)
+	}
+
+	// Length of the last attribute past the end of the packet
+	b[len(b)-len("shell:priv-lvl=15")-7]++
+	if _, err := Unmarshal(b); err == nil {
+		t.Error("Bad attribute length accepted")
+	}
+}
+
+func TestSignatures(t *testing.T) {
+
+	secret := []byte("secret")
+
+	request := NewPacket(CodeAccessRequest)
+	copy(request.Authenticator[:], "0123456789abcdef")
+	request.AddString(AttrUserName, "admin")
+
+	if err := request.SignAccessRequest(secret); err != nil || !request.VerifyAccessRequest(secret) {
+		t.Fatalf("Access-Request signature not verified: %v", err)
+	}
+
+	if request.VerifyAccessRequest([]byte("wrong")) {
+		t.Error("Access-Request verified with a wrong secret")
+	}
+
+	response := NewPacket(CodeAccessAccept)
+	response.Add(AttrMessageAuthenticator, make([]byte, 16))
+	if err := response.SignResponse(secret, request); err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.VerifyResponse(secret, request) {
+		t.Error("Response not verified")
+	}
+
+	response.Code = CodeAccessReject
+	if response.VerifyResponse(secret, request) {
+		t.Error("Altered response verified")
+	}
+
+	unsigned := NewPacket(CodeAccessAccept)
+	if err := unsigned.SignResponse(secret, request); err != nil {
+		t.Fatal(err)
+	}
+
+	if unsigned.VerifyResponse(secret, request) {
+		t.Error("Access-Accept without Message-Authenticator verified")
+	}
+
+	accounting := NewPacket(CodeAccountingRequest)
+	accounting.AddUint32(AttrAcctStatusType, AcctStatusStart)
+	if err := accounting.SignAccountingRequest(secret); err != nil || !accounting.VerifyAccountingRequest(secret) {
+		t.Errorf("Accounting-Request signature not verified: %v", err)
+	}
+}
+
+func TestExchangeTimeout(t *testing.T) {
+
+	// Nothing answers on this socket
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := &Client{Addr: conn.LocalAddr().String(), Secret: []byte("secret"), Timeout: 50 * time.Millisecond, Retries: 1}
+
+	start := time.Now()
+	if _, err := client.Exchange(context.Background(), NewPacket(CodeAccessRequest)); err != errTimeout {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Request not retransmitted, gave up after %v", elapsed)
+	}
+}
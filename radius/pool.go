@@ -0,0 +1,173 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package radius
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Time a server that did not answer is skipped before it is tried again
+var ServerRetryInterval = 30 * time.Second
+
+var errNoServer = errors.New("Unable to reach any RADIUS servers")
+
+type poolServer struct {
+	info   RadiusInfo
+	dead   bool
+	failed time.Time
+}
+
+// Pool sends the requests to the configured servers in priority order, a
+// server that does not answer is skipped for ServerRetryInterval and the
+// next one is tried
+type Pool struct {
+	mu      sync.Mutex
+	servers []*poolServer
+}
+
+// Shared by all the sessions, rebuilt when the server configuration changes
+var serverPool = &Pool{}
+
+// Reads the radius configuration from the config db and refreshes the server pool.
+// Returns the pool and the configuration of the preferred reachable server
+func GetPool() (*Pool, RadiusInfo, error) {
+
+	infos, err := GetRadiusInfo()
+
+	if err != nil {
+		return nil, RadiusInfo{}, errors.New("No radius configuration found")
+	}
+
+	serverPool.update(infos)
+
+	return serverPool, serverPool.order()[0].info, nil
+}
+
+// NewPool returns a pool of the servers of infos, tried in the given order
+func NewPool(infos []RadiusInfo) *Pool {
+	pool := &Pool{}
+	pool.update(infos)
+	return pool
+}
+
+// update replaces the server list, the health of unchanged servers is kept
+func (p *Pool) update(infos []RadiusInfo) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := map[RadiusInfo]*poolServer{}
+	for _, server := range p.servers {
+		current[server.info] = server
+	}
+
+	p.servers = make([]*poolServer, 0, len(infos))
+	for _, info := range infos {
+		if server, ok := current[info]; ok {
+			p.servers = append(p.servers, server)
+		} else {
+			p.servers = append(p.servers, &poolServer{info: info})
+		}
+	}
+}
+
+// order returns the live servers by priority, then the dead ones
+func (p *Pool) order() []*poolServer {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	alive := []*poolServer{}
+	dead := []*poolServer{}
+	for _, server := range p.servers {
+		if server.dead && time.Since(server.failed) < ServerRetryInterval {
+			dead = append(dead, server)
+		} else {
+			alive = append(alive, server)
+		}
+	}
+
+	return append(alive, dead...)
+}
+
+func (p *Pool) setDead(server *poolServer, dead bool) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if dead {
+		if !server.dead {
+			glog.Warningf("Server (%s) unreachable, failing over to the next priority", server.info.IP)
+		}
+		server.failed = time.Now()
+	} else if server.dead {
+		glog.Infof("Server (%s) reachable again", server.info.IP)
+	}
+
+	server.dead = dead
+}
+
+// send runs a request on the servers in turn until one of them answers
+func (p *Pool) send(ctx context.Context, request *Packet, addr func(info *RadiusInfo) string) (*Packet, error) {
+
+	err := errNoServer
+
+	for _, server := range p.order() {
+
+		client := &Client{
+			Addr:    addr(&server.info),
+			Secret:  []byte(server.info.Secret),
+			Timeout: time.Duration(server.info.Timeout) * time.Second,
+			Retries: server.info.Retransmit,
+		}
+
+		var response *Packet
+		response, err = client.Exchange(ctx, request)
+
+		if err == nil {
+			p.setDead(server, false)
+			return response, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		glog.Warningf("Request to server (%s) failed: %v", client.Addr, err)
+		p.setDead(server, true)
+	}
+
+	return nil, err
+}
+
+// Authenticate sends an Access-Request to the first server answering
+func (p *Pool) Authenticate(ctx context.Context, request *Packet) (*Packet, error) {
+	return p.send(ctx, request, (*RadiusInfo).authAddr)
+}
+
+// Account sends an Accounting-Request to the first server answering
+func (p *Pool) Account(ctx context.Context, request *Packet) (*Packet, error) {
+	return p.send(ctx, request, (*RadiusInfo).acctAddr)
+}
@@ -0,0 +1,224 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package radiustest provides a RADIUS server for tests, answering PAP and
+// CHAP Access-Requests of configured users and recording accounting
+package radiustest
+
+import (
+	"bytes"
+	"net"
+	"sync"
+
+	"orange/sonic-netconf-server/radius"
+)
+
+// User of the test server
+type User struct {
+	Password string
+	// Service-Type of the Access-Accept, none when 0
+	ServiceType uint32
+	// Cisco-AVPair values of the Access-Accept, e.g. "shell:priv-lvl=15"
+	AVPairs []string
+}
+
+// Server is a RADIUS server listening on the loopback
+type Server struct {
+	Secret string
+	Users  map[string]*User
+	// Drop every request, as an unreachable server
+	Drop bool
+	// Leave the Message-Authenticator out of the Access responses
+	NoMessageAuthenticator bool
+
+	mutex      sync.Mutex
+	auth       net.PacketConn
+	acct       net.PacketConn
+	requests   int
+	accounting []*radius.Packet
+}
+
+func NewServer(secret string) *Server {
+	return &Server{
+		Secret: secret,
+		Users:  map[string]*User{},
+	}
+}
+
+// AddUser adds a user with the Cisco-AVPair values of its Access-Accept
+func (s *Server) AddUser(name string, password string, avPairs ...string) *User {
+	user := &User{Password: password, AVPairs: avPairs}
+	s.Users[name] = user
+	return user
+}
+
+// Start listens on random loopback ports for authentication and accounting
+func (s *Server) Start() error {
+
+	auth, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+
+	acct, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		auth.Close()
+		return err
+	}
+
+	s.auth = auth
+	s.acct = acct
+
+	go s.serve(auth, s.handleAccess)
+	go s.serve(acct, s.handleAccounting)
+
+	return nil
+}
+
+func (s *Server) Close() {
+	s.auth.Close()
+	s.acct.Close()
+}
+
+// Info returns the configuration of a client of the server
+func (s *Server) Info(authType string) radius.RadiusInfo {
+	return radius.RadiusInfo{
+		IP:         "127.0.0.1",
+		AuthPort:   s.auth.LocalAddr().(*net.UDPAddr).Port,
+		AcctPort:   s.acct.LocalAddr().(*net.UDPAddr).Port,
+		Secret:     s.Secret,
+		Timeout:    1,
+		Retransmit: 0,
+		AuthType:   authType,
+	}
+}
+
+// Requests returns the number of requests received, dropped ones included
+func (s *Server) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+// Accounting returns the accounting requests received
+func (s *Server) Accounting() []*radius.Packet {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*radius.Packet{}, s.accounting...)
+}
+
+func (s *Server) serve(conn net.PacketConn, handle func(request *radius.Packet) *radius.Packet) {
+
+	buf := make([]byte, 4096)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		s.requests++
+		drop := s.Drop
+		s.mutex.Unlock()
+
+		request, err := radius.Unmarshal(buf[:n])
+		if drop || err != nil {
+			continue
+		}
+
+		response := handle(request)
+		if response == nil {
+			continue
+		}
+
+		if err := response.SignResponse([]byte(s.Secret), request); err != nil {
+			continue
+		}
+
+		if b, err := response.Marshal(); err == nil {
+			conn.WriteTo(b, addr)
+		}
+	}
+}
+
+func (s *Server) handleAccess(request *radius.Packet) *radius.Packet {
+
+	if request.Code != radius.CodeAccessRequest || !request.VerifyAccessRequest([]byte(s.Secret)) {
+		return nil
+	}
+
+	s.mutex.Lock()
+	signed := !s.NoMessageAuthenticator
+	s.mutex.Unlock()
+
+	reject := radius.NewPacket(radius.CodeAccessReject)
+	if signed {
+		reject.Add(radius.AttrMessageAuthenticator, make([]byte, 16))
+	}
+
+	user, ok := s.Users[request.GetString(radius.AttrUserName)]
+	if !ok || !s.checkPassword(request, user.Password) {
+		return reject
+	}
+
+	accept := radius.NewPacket(radius.CodeAccessAccept)
+	if signed {
+		accept.Add(radius.AttrMessageAuthenticator, make([]byte, 16))
+	}
+	if user.ServiceType != 0 {
+		accept.AddUint32(radius.AttrServiceType, user.ServiceType)
+	}
+	for _, avPair := range user.AVPairs {
+		accept.AddVendor(radius.VendorCisco, radius.CiscoAttrAVPair, []byte(avPair))
+	}
+
+	return accept
+}
+
+func (s *Server) checkPassword(request *radius.Packet, password string) bool {
+
+	if encrypted, ok := request.Get(radius.AttrUserPassword); ok {
+		decrypted, err := radius.DecryptPassword(encrypted, []byte(s.Secret), request.Authenticator[:])
+		return err == nil && string(decrypted) == password
+	}
+
+	if chapPassword, ok := request.Get(radius.AttrCHAPPassword); ok && len(chapPassword) == 17 {
+		challenge, ok := request.Get(radius.AttrCHAPChallenge)
+		if !ok {
+			challenge = request.Authenticator[:]
+		}
+		return bytes.Equal(chapPassword, radius.CHAPPassword(chapPassword[0], []byte(password), challenge))
+	}
+
+	return false
+}
+
+func (s *Server) handleAccounting(request *radius.Packet) *radius.Packet {
+
+	if request.Code != radius.CodeAccountingRequest || !request.VerifyAccountingRequest([]byte(s.Secret)) {
+		return nil
+	}
+
+	s.mutex.Lock()
+	s.accounting = append(s.accounting, request)
+	s.mutex.Unlock()
+
+	return radius.NewPacket(radius.CodeAccountingResponse)
+}
@@ -0,0 +1,228 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"crypto/des"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"errors"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// Lengths of the challenges and responses carried in the AuthenStart data
+const (
+	chapResponseLen      = 16 // MD5 digest
+	chapChallengeLen     = 16
+	mschapChallengeLen   = 8
+	mschapv2ChallengeLen = 16
+	mschapResponseLen    = 49 // LM or peer challenge, NT response and flags
+)
+
+var errBadAuthenData = errors.New("Bad CHAP authentication data")
+
+// AuthenData returns the AuthenStart data of a login with a known password,
+// the NAS acts as the CHAP authenticator and picks the identifier and
+// challenge. PAP and ASCII send the password as is
+func AuthenData(authType uint8, user string, password string) ([]byte, error) {
+
+	switch authType {
+	case AuthenTypeCHAP:
+		challenge, err := randomBytes(1 + chapChallengeLen)
+		if err != nil {
+			return nil, err
+		}
+		return CHAPData(challenge[0], challenge[1:], password), nil
+
+	case AuthenTypeMSCHAP:
+		challenge, err := randomBytes(1 + mschapChallengeLen)
+		if err != nil {
+			return nil, err
+		}
+		return MSCHAPData(challenge[0], challenge[1:], password)
+
+	case AuthenTypeMSCHAPv2:
+		challenge, err := randomBytes(1 + mschapv2ChallengeLen + 16)
+		if err != nil {
+			return nil, err
+		}
+		return MSCHAPv2Data(challenge[0], challenge[1:1+mschapv2ChallengeLen], challenge[1+mschapv2ChallengeLen:], user, password)
+	}
+
+	return []byte(password), nil
+}
+
+// CHAPData returns the PPP id, the challenge and the MD5 response (RFC 1994)
+func CHAPData(id byte, challenge []byte, password string) []byte {
+
+	data := append([]byte{id}, challenge...)
+
+	return append(data, chapResponse(id, challenge, password)...)
+}
+
+// MSCHAPData returns the PPP id, the challenge and the MS-CHAPv1 response
+// (RFC 2433) with the NT response only
+func MSCHAPData(id byte, challenge []byte, password string) ([]byte, error) {
+
+	if len(challenge) != mschapChallengeLen {
+		return nil, errBadAuthenData
+	}
+
+	data := append([]byte{id}, challenge...)
+
+	// LM response unused, the flag selects the NT response
+	data = append(data, make([]byte, 24)...)
+	data = append(data, challengeResponse(challenge, ntPasswordHash(password))...)
+
+	return append(data, 1), nil
+}
+
+// MSCHAPv2Data returns the PPP id, the authenticator challenge and the
+// MS-CHAPv2 response (RFC 2759) made of the peer challenge, a reserved
+// field, the NT response and the flags
+func MSCHAPv2Data(id byte, authChallenge []byte, peerChallenge []byte, user string, password string) ([]byte, error) {
+
+	if len(authChallenge) != mschapv2ChallengeLen || len(peerChallenge) != 16 {
+		return nil, errBadAuthenData
+	}
+
+	data := append([]byte{id}, authChallenge...)
+	data = append(data, peerChallenge...)
+	data = append(data, make([]byte, 8)...)
+	data = append(data, MSCHAPv2Response(authChallenge, peerChallenge, user, password)...)
+
+	return append(data, 0), nil
+}
+
+// CheckAuthenData verifies the AuthenStart data of a login against the
+// password, for servers
+func CheckAuthenData(authType uint8, user string, password string, data []byte) bool {
+
+	switch authType {
+	case AuthenTypeCHAP:
+		if len(data) <= 1+chapResponseLen {
+			return false
+		}
+		expected := CHAPData(data[0], data[1:len(data)-chapResponseLen], password)
+		return constantTimeEqual(data, expected)
+
+	case AuthenTypeMSCHAP:
+		if len(data) != 1+mschapChallengeLen+mschapResponseLen {
+			return false
+		}
+		expected, err := MSCHAPData(data[0], data[1:1+mschapChallengeLen], password)
+		// Only the NT response is checked
+		return err == nil && constantTimeEqual(data[1+mschapChallengeLen+24:], expected[1+mschapChallengeLen+24:])
+
+	case AuthenTypeMSCHAPv2:
+		if len(data) != 1+mschapv2ChallengeLen+mschapResponseLen {
+			return false
+		}
+		peerChallenge := data[1+mschapv2ChallengeLen : 1+mschapv2ChallengeLen+16]
+		expected, err := MSCHAPv2Data(data[0], data[1:1+mschapv2ChallengeLen], peerChallenge, user, password)
+		return err == nil && constantTimeEqual(data[:len(data)-1], expected[:len(expected)-1])
+	}
+
+	return constantTimeEqual(data, []byte(password))
+}
+
+// MSCHAPv2Response returns the NT response of MS-CHAPv2
+func MSCHAPv2Response(authChallenge []byte, peerChallenge []byte, user string, password string) []byte {
+
+	hash := sha1.New()
+	hash.Write(peerChallenge)
+	hash.Write(authChallenge)
+	hash.Write([]byte(user))
+
+	return challengeResponse(hash.Sum(nil)[:8], ntPasswordHash(password))
+}
+
+func chapResponse(id byte, challenge []byte, password string) []byte {
+
+	hash := md5.New()
+	hash.Write([]byte{id})
+	hash.Write([]byte(password))
+	hash.Write(challenge)
+
+	return hash.Sum(nil)
+}
+
+// ntPasswordHash is the MD4 digest of the UTF-16LE password
+func ntPasswordHash(password string) []byte {
+
+	encoded := utf16.Encode([]rune(password))
+	unicode := make([]byte, 0, 2*len(encoded))
+	for _, c := range encoded {
+		unicode = append(unicode, byte(c), byte(c>>8))
+	}
+
+	hash := md4.New()
+	hash.Write(unicode)
+
+	return hash.Sum(nil)
+}
+
+// challengeResponse encrypts the 8 bytes challenge with the three DES keys
+// taken from the zero padded password hash
+func challengeResponse(challenge []byte, passwordHash []byte) []byte {
+
+	key := make([]byte, 21)
+	copy(key, passwordHash)
+
+	response := make([]byte, 24)
+	for i := 0; i < 3; i++ {
+		// The key is valid, des only checks its length
+		block, _ := des.NewCipher(desKey(key[7*i : 7*i+7]))
+		block.Encrypt(response[8*i:], challenge)
+	}
+
+	return response
+}
+
+// desKey spreads 56 key bits over 8 bytes, parity bits are ignored by des
+func desKey(key []byte) []byte {
+
+	expanded := make([]byte, 8)
+	expanded[0] = key[0]
+	for i := 1; i < 7; i++ {
+		expanded[i] = key[i-1]<<(8-uint(i)) | key[i]>>uint(i)
+	}
+	expanded[7] = key[6] << 1
+
+	return expanded
+}
+
+func randomBytes(n int) ([]byte, error) {
+
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func constantTimeEqual(a []byte, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
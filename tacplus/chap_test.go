@@ -0,0 +1,119 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func init() {
+	fmt.Println("+++++ init chap_test +++++")
+}
+
+func fromHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCHAPData(t *testing.T) {
+
+	challenge := fromHex(t, "0102030405060708090a0b0c0d0e0f10")
+
+	data := CHAPData(0x2a, challenge, "secret")
+
+	expected := md5.Sum(append(append([]byte{0x2a}, "secret"...), challenge...))
+
+	if data[0] != 0x2a || !bytes.Equal(data[1:17], challenge) || !bytes.Equal(data[17:], expected[:]) {
+		t.Errorf("Unexpected CHAP data %x", data)
+	}
+}
+
+// Test vectors of RFC 2433 appendix B
+func TestMSCHAPData(t *testing.T) {
+
+	challenge := fromHex(t, "102db5df085d3041")
+
+	if hash := ntPasswordHash("MyPw"); !bytes.Equal(hash, fromHex(t, "fc156af7edcd6c0edde3337d427f4eac")) {
+		t.Errorf("NT password hash %x", hash)
+	}
+
+	data, err := MSCHAPData(0x01, challenge, "MyPw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fromHex(t, "4e9d3c8f9cfd385d5bf4d3246791956ca4c351ab409a3d61")
+
+	if len(data) != 1+8+49 || !bytes.Equal(data[1+8+24:1+8+48], expected) || data[len(data)-1] != 1 {
+		t.Errorf("Unexpected MS-CHAP data %x", data)
+	}
+}
+
+// Test vectors of RFC 2759 section 9.2
+func TestMSCHAPv2Data(t *testing.T) {
+
+	authChallenge := fromHex(t, "5b5d7c7d7b3f2f3e3c2c602132262628")
+	peerChallenge := fromHex(t, "21402324255e262a28295f2b3a337c7e")
+
+	if hash := ntPasswordHash("clientPass"); !bytes.Equal(hash, fromHex(t, "44ebba8d5312b8d611474411f56989ae")) {
+		t.Errorf("NT password hash %x", hash)
+	}
+
+	data, err := MSCHAPv2Data(0x01, authChallenge, peerChallenge, "User", "clientPass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fromHex(t, "82309ecd8d708b5ea08faa3981cd83544233114a3d85d6df")
+
+	if len(data) != 1+16+49 || !bytes.Equal(data[1+16+24:1+16+48], expected) {
+		t.Errorf("Unexpected MS-CHAPv2 data %x", data)
+	}
+}
+
+func TestCheckAuthenData(t *testing.T) {
+
+	for _, authType := range []uint8{AuthenTypePAP, AuthenTypeCHAP, AuthenTypeMSCHAP, AuthenTypeMSCHAPv2} {
+
+		data, err := AuthenData(authType, "user", "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !CheckAuthenData(authType, "user", "password", data) {
+			t.Errorf("Type %d: data rejected with the right password", authType)
+		}
+
+		if CheckAuthenData(authType, "user", "wrong", data) {
+			t.Errorf("Type %d: data accepted with a wrong password", authType)
+		}
+
+		if authType != AuthenTypePAP && bytes.Contains(data, []byte("password")) {
+			t.Errorf("Type %d: password sent in clear", authType)
+		}
+	}
+}
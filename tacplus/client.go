@@ -2,10 +2,12 @@ package tacplus
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"net"
 	"sync"
+	"time"
 )
 
 // ClientSession is a TACACS+ client session.
@@ -82,6 +84,10 @@ type Client struct {
 	// Optional DialContext function used to create the network connection.
 	DialContext func(ctx context.Context, net, addr string) (net.Conn, error)
 
+	// Optional TLS configuration, when set the connection is wrapped in
+	// TLS 1.3 and packet bodies are no longer obfuscated with the secret.
+	TLSConfig *tls.Config
+
 	mu   sync.Mutex // protects access to conn
 	conn *conn      // current cached mux connection
 }
@@ -99,15 +105,44 @@ func (c *Client) Close() {
 var zeroDialer net.Dialer
 
 func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	var nc net.Conn
+	var err error
 	if c.DialContext != nil {
-		return c.DialContext(ctx, "tcp", c.Addr)
+		nc, err = c.DialContext(ctx, "tcp", c.Addr)
+	} else {
+		nc, err = zeroDialer.DialContext(ctx, "tcp", c.Addr)
+	}
+	if err != nil || c.TLSConfig == nil {
+		return nc, err
+	}
+
+	config := tls13Config(c.TLSConfig)
+	if config.ServerName == "" {
+		// verify the server certificate against the dialed host
+		if host, _, err := net.SplitHostPort(c.Addr); err == nil {
+			config.ServerName = host
+		}
+	}
+
+	tc := tls.Client(nc, config)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = tc.SetDeadline(deadline)
+	}
+	if err = tc.Handshake(); err != nil {
+		nc.Close()
+		return nil, err
 	}
-	return zeroDialer.DialContext(ctx, "tcp", c.Addr)
+	_ = tc.SetDeadline(time.Time{})
+	return tc, nil
 }
 
 func (c *Client) TestConnection(ctx context.Context) bool {
-	_, err := c.dial(ctx)
-	return err == nil
+	nc, err := c.dial(ctx)
+	if err != nil {
+		return false
+	}
+	nc.Close()
+	return true
 }
 
 func (c *Client) newSession(ctx context.Context) (*session, error) {
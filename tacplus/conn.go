@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/md5"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -32,6 +33,7 @@ const (
 	hdrBodyLen = 8
 
 	// Packet header flags
+	hdrFlagUnencrypted   = 0x01 // body not obfuscated, mandatory over TLS
 	hdrFlagSingleConnect = 0x04 // multiplex requests over a single connection
 )
 
@@ -43,6 +45,7 @@ var (
 	errSessionNotFound  = errors.New("session not found or timed out")
 	errUnexpectedEOF    = errors.New("unexpected EOF")
 	errPacketQueueFull  = errors.New("packet queue full")
+	errObfuscatedTLS    = errors.New("obfuscated packet received over TLS")
 )
 
 // doneContext allows a done channel to be used as a context.Context
@@ -180,6 +183,15 @@ func (s *session) readPacket(ctx context.Context) ([]byte, error) {
 		return p, errInvalidSeqNo
 	}
 
+	// RFC 9887: bodies are never obfuscated over TLS
+	if s.c.tls {
+		if p[hdrFlags]&hdrFlagUnencrypted == 0 {
+			s.c.close()
+			return p, errObfuscatedTLS
+		}
+		return p, nil
+	}
+
 	crypt(p, s.c.Secret)
 	return p, nil
 }
@@ -197,7 +209,11 @@ func (s *session) writePacket(ctx context.Context, p []byte) error {
 
 	// set body size
 	binary.BigEndian.PutUint32(p[hdrBodyLen:], uint32(len(p)-hdrLen))
-	crypt(p, s.c.Secret)
+	if s.c.tls {
+		p[hdrFlags] |= hdrFlagUnencrypted
+	} else {
+		crypt(p, s.c.Secret)
+	}
 
 	wr := writeRequest{p: p, ec: make(chan error, 1)}
 	if deadline, ok := ctx.Deadline(); ok {
@@ -290,6 +306,7 @@ type conn struct {
 	parity   uint8               // parity of sequence number for incoming packets
 	mux      bool                // connection multiplexing status
 	checkMux bool                // connection multiplexing to be negotatied
+	tls      bool                // connection secured with TLS, bodies are not obfuscated
 	idleT    *time.Timer         // idle timer
 
 	// channels used for communicating with connection serving goroutines
@@ -610,6 +627,7 @@ func newConn(nc net.Conn, h func(*session), cfg ConnConfig) *conn {
 		nc:         nc,
 		mux:        cfg.LegacyMux,             // For LegacyMux allow multiplexing regardless of header flags.
 		checkMux:   !cfg.LegacyMux && cfg.Mux, // For (draft) Mux check the first packet for the single-connection flag.
+		tls:        isTLS(nc),
 		handle:     h,
 		ConnConfig: cfg,
 	}
@@ -632,3 +650,16 @@ func newConn(nc net.Conn, h func(*session), cfg ConnConfig) *conn {
 
 	return c
 }
+
+func isTLS(nc net.Conn) bool {
+	_, ok := nc.(*tls.Conn)
+	return ok
+}
+
+// tls13Config returns a copy of config restricted to TLS 1.3, the only
+// version allowed for TACACS+ by RFC 9887
+func tls13Config(config *tls.Config) *tls.Config {
+	config = config.Clone()
+	config.MinVersion = tls.VersionTLS13
+	return config
+}
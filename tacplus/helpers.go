@@ -21,16 +21,18 @@ package tacplus
 
 import (
 	"container/heap"
-	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
+	"io/ioutil"
 	"math/rand"
 	"net"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/go-redis/redis/v7"
+	"orange/sonic-netconf-server/configdb"
+
 	"github.com/golang/glog"
 )
 
@@ -42,19 +44,20 @@ type TacacsInfo struct {
 	Timeout  int
 	AuthType string
 	index    int
+
+	// TACACS+ over TLS 1.3, the password is not used
+	TLS           bool
+	TLSServerName string // Name verified in the server certificate, the IP when empty
+	TLSCACert     string // CA certificates of the server, the system roots when empty
+	TLSCert       string // Client certificate, optional
+	TLSKey        string // Client private key
 }
 
+// IANA port of TACACS+ over TLS
+const TLSPort = 300
+
 //Single redis client for tacacs communication
-var redisClient *redis.Client
-
-func init() {
-	redisClient = redis.NewClient(&redis.Options{
-		Network:  "unix",
-		Addr:     "/var/run/redis/redis.sock",
-		Password: "",
-		DB:       4,
-	})
-}
+var redisClient = configdb.Client
 
 func IsTacacsAAAEnabled() bool {
 	aaaAuth, err := redisClient.HGet("AAA|authentication", "login").Result()
@@ -88,6 +91,7 @@ func GetTacacsInfo() (PriorityQueue, error) {
 	globalTacacsTimeout := 5
 	globalTacacsPass := ""
 	globalTacacsAuthType := "pap"
+	globalTLS := TacacsInfo{}
 
 	tacacsGlobal, err := redisClient.HGetAll("TACPLUS|global").Result()
 
@@ -97,18 +101,13 @@ func GetTacacsInfo() (PriorityQueue, error) {
 
 			if encrypted, ok := tacacsGlobal["is_key_encrypted"]; ok && encrypted == "True" {
 
-				glog.Info("Tacacs key is encrypted, decrypting ...")
-
-				decrypted, err := exec.Command("bash", "-c" , "echo " + pass + " | " + "openssl enc -d -aes-256-cbc -base64 -pbkdf2 -pass file:/etc/sonic/enc_master_key").Output()
+				decrypted, err := decryptEntryPasskey("TACPLUS|global", pass)
 
 				if err != nil {
-					glog.Infof("Unable execute decrypt openssl command")
 					return nil, errors.New("Cannot get tacacs server info")
 				}
 
-				glog.Infof("Decryption success")
-
-				globalTacacsPass = string(decrypted)
+				globalTacacsPass = decrypted
 
 			}else{
 				globalTacacsPass = pass
@@ -123,6 +122,8 @@ func GetTacacsInfo() (PriorityQueue, error) {
 		if timeout, ok := tacacsGlobal["timeout"]; ok {
 			globalTacacsTimeout, _ = strconv.Atoi(timeout)
 		}
+
+		readTLSInfo(tacacsGlobal, &globalTLS)
 	}
 
 	tacKeys, err := redisClient.Keys("TACPLUS_SERVER|*").Result()
@@ -155,18 +156,13 @@ func GetTacacsInfo() (PriorityQueue, error) {
 		if pass, ok := serverData["passkey"]; ok {
 			if encrypted, ok := serverData["is_key_encrypted"]; ok && encrypted == "True" {
 
-				glog.Info("Tacacs key is encrypted, decrypting ...")
-
-				decrypted, err := exec.Command("bash", "-c" , "echo " + pass + " | " + "openssl enc -d -aes-256-cbc -base64 -pbkdf2 -pass file:/etc/sonic/enc_master_key").Output()
+				decrypted, err := decryptEntryPasskey(key, pass)
 
 				if err != nil {
-					glog.Infof("Unable execute decrypt openssl command")
 					return nil, errors.New("Cannot get tacacs server info")
 				}
 
-				glog.Infof("Decryption success")
-
-				tacPassword = string(decrypted)
+				tacPassword = decrypted
 
 			}else{
 				tacPassword = pass
@@ -181,14 +177,21 @@ func GetTacacsInfo() (PriorityQueue, error) {
 			tacAuthType = "ascii"
 		}
 
-		pq[i] = &TacacsInfo{
-			IP:       tacIp,
-			Port:     tacPort,
-			Priority: tacPriority,
-			Timeout:  tacTimeout,
-			Password: tacPassword,
-			AuthType: tacAuthType,
+		info := globalTLS
+		info.IP = tacIp
+		info.Port = tacPort
+		info.Priority = tacPriority
+		info.Timeout = tacTimeout
+		info.Password = tacPassword
+		info.AuthType = tacAuthType
+
+		readTLSInfo(serverData, &info)
+
+		if info.TLS && info.Port == 0 {
+			info.Port = TLSPort
 		}
+
+		pq[i] = &info
 	}
 
 	// Sort by priority
@@ -218,46 +221,121 @@ func GetLocalIP() string {
 	return ""
 }
 
-// Creates a connection from a specific configuration
-func CreateClientFromInfo(info *TacacsInfo) *Client {
-	return &Client{
-		Addr: info.IP + ":" + strconv.Itoa(info.Port),
-		ConnConfig: ConnConfig{
-			Secret: []byte(info.Password),
-			Mux:    false,
-		},
+// readTLSInfo overrides the TLS settings of info with the fields of a
+// TACPLUS or TACPLUS_SERVER entry
+func readTLSInfo(data map[string]string, info *TacacsInfo) {
+
+	if enabled, ok := data["tls"]; ok {
+		info.TLS = strings.EqualFold(enabled, "true")
+	}
+
+	if serverName, ok := data["tls_server_name"]; ok {
+		info.TLSServerName = serverName
+	}
+
+	if caCert, ok := data["tls_ca_cert"]; ok {
+		info.TLSCACert = caCert
+	}
+
+	if cert, ok := data["tls_cert"]; ok {
+		info.TLSCert = cert
+	}
+
+	if key, ok := data["tls_key"]; ok {
+		info.TLSKey = key
 	}
 }
 
-/*Creates a client by reading the conifg db for tacacs configurations
-Returns a reference to a newly created client with the highest priority and an established connection
-*/
-func CreateClient(context context.Context) (*Client, TacacsInfo, error) {
+// tlsClientConfig returns the TLS configuration verifying the server of info
+func tlsClientConfig(info *TacacsInfo) (*tls.Config, error) {
 
-	queue, err := GetTacacsInfo()
+	config := &tls.Config{ServerName: info.TLSServerName}
 
-	if err != nil {
-		return nil, TacacsInfo{}, errors.New("No tacacs configuration found")
+	if info.TLSCACert != "" {
+		pem, err := ioutil.ReadFile(info.TLSCACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("No CA certificate found in " + info.TLSCACert)
+		}
 	}
 
-	for queue.Len() > 0 {
+	if info.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(info.TLSCert, info.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
 
-		info := heap.Pop(&queue).(*TacacsInfo)
+	return config, nil
+}
 
-		client := CreateClientFromInfo(info)
+// Creates a connection from a specific configuration, sessions are multiplexed
+// over a single connection when the server sets the single-connection flag
+func CreateClientFromInfo(info *TacacsInfo) (*Client, error) {
 
-		glog.Infof("Found server (%s) in db, testing connection", info.IP)
+	timeout := time.Duration(info.Timeout) * time.Second
 
-		connected := client.TestConnection(context)
+	client := &Client{
+		Addr: net.JoinHostPort(info.IP, strconv.Itoa(info.Port)),
+		ConnConfig: ConnConfig{
+			Secret:       []byte(info.Password),
+			Mux:          true,
+			IdleTimeout:  MuxIdleTimeout,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+			Log: func(v ...interface{}) {
+				glog.Warning(v...)
+			},
+		},
+	}
 
-		if connected {
-			glog.Infof("Successful connection to server (%s)", info.IP)
-			return client, *info, nil
+	if info.TLS {
+		config, err := tlsClientConfig(info)
+		if err != nil {
+			return nil, err
 		}
+		client.TLSConfig = config
+	}
+
+	return client, nil
+}
 
-		glog.Warningf("Unable to connect to server (%s) - skipping", info.IP)
+// AAA login configuration, as set with "config aaa authentication"
+type AAALogin struct {
+	Methods     []string // Ordered login methods, tacacs+ and local
+	Failthrough bool     // Try the next method when authentication is rejected
+	Fallback    bool     // Try the next method when no server is reachable
+}
+
+func GetAAALogin() AAALogin {
+
+	login := AAALogin{Methods: []string{"local"}, Failthrough: false, Fallback: true}
+
+	aaaAuth, err := redisClient.HGetAll("AAA|authentication").Result()
+
+	if err != nil || len(aaaAuth) == 0 {
+		glog.Info("[AAA] No AAA Authentication data found, using local login")
+		return login
+	}
+
+	if methods, ok := aaaAuth["login"]; ok && len(methods) != 0 {
+		login.Methods = nil
+		for _, method := range strings.Split(methods, ",") {
+			login.Methods = append(login.Methods, strings.TrimSpace(method))
+		}
+	}
+
+	if failthrough, ok := aaaAuth["failthrough"]; ok {
+		login.Failthrough = strings.EqualFold(failthrough, "true")
+	}
 
+	if fallback, ok := aaaAuth["fallback"]; ok {
+		login.Fallback = strings.EqualFold(fallback, "true")
 	}
 
-	return nil, TacacsInfo{}, errors.New("Unable to connect to any TACACS servers")
+	return login
 }
@@ -51,6 +51,8 @@ const (
 	AuthenTypeCHAP   = 0x3
 	AuthenTypeARAP   = 0x4
 	AuthenTypeMSCHAP = 0x5
+	// MS-CHAPv2, RFC 8907
+	AuthenTypeMSCHAPv2 = 0x6
 )
 
 // AuthenStart Action field values
@@ -157,12 +159,12 @@ func (a *AuthenStart) version() uint8 {
 	switch a.Action {
 	case AuthenActionLogin:
 		switch a.AuthenType {
-		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeARAP, AuthenTypeMSCHAP:
+		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeARAP, AuthenTypeMSCHAP, AuthenTypeMSCHAPv2:
 			return verDefaultMinorOne
 		}
 	case AuthenActionSendAuth:
 		switch a.AuthenType {
-		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeMSCHAP:
+		case AuthenTypePAP, AuthenTypeCHAP, AuthenTypeMSCHAP, AuthenTypeMSCHAPv2:
 			return verDefaultMinorOne
 		}
 	}
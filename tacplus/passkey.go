@@ -0,0 +1,174 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// Master key of the encrypted passkeys, the first line is the password
+// given to openssl with -pass file:
+var MasterKeyPath = "/etc/sonic/enc_master_key"
+
+// Parameters of "openssl enc -aes-256-cbc -base64 -pbkdf2"
+const (
+	opensslMagic      = "Salted__"
+	opensslSaltLen    = 8
+	opensslIterations = 10000
+	opensslKeyLen     = 32
+)
+
+// DecryptPasskey decrypts a passkey encrypted with
+// "openssl enc -aes-256-cbc -base64 -pbkdf2", the output is the same
+func DecryptPasskey(encrypted string, masterKey []byte) ([]byte, error) {
+
+	// openssl wraps the base64 output every 64 characters
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encrypted), ""))
+	if err != nil {
+		return nil, errors.New("Invalid base64 passkey")
+	}
+
+	if len(data) < len(opensslMagic)+opensslSaltLen || string(data[:len(opensslMagic)]) != opensslMagic {
+		return nil, errors.New("Passkey is not salted")
+	}
+
+	salt := data[len(opensslMagic) : len(opensslMagic)+opensslSaltLen]
+	ciphertext := data[len(opensslMagic)+opensslSaltLen:]
+
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("Invalid passkey length")
+	}
+
+	keyIV := pbkdf2.Key(masterKey, salt, opensslIterations, opensslKeyLen+aes.BlockSize, sha256.New)
+
+	block, err := aes.NewCipher(keyIV[:opensslKeyLen])
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, keyIV[opensslKeyLen:]).CryptBlocks(plaintext, ciphertext)
+
+	// PKCS#7 padding, a wrong master key almost always breaks it
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize ||
+		!bytes.Equal(plaintext[len(plaintext)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, errors.New("Bad decrypt")
+	}
+
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// ReadMasterKey returns the first line of the master key file
+func ReadMasterKey(path string) ([]byte, error) {
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+
+	return data, nil
+}
+
+// Decrypted passkeys by config db entry, an entry is dropped when its
+// keyspace notification is received and ignored when the stored value changed
+type cachedPasskey struct {
+	encrypted string
+	decrypted string
+}
+
+var passkeyCache = struct {
+	sync.Mutex
+	entries map[string]cachedPasskey
+}{entries: map[string]cachedPasskey{}}
+
+var passkeyWatchOnce sync.Once
+
+const keyspacePrefix = "__keyspace@4__:"
+
+// decryptEntryPasskey returns the decrypted passkey of a TACPLUS or
+// TACPLUS_SERVER entry, decrypting it only when it is not cached
+func decryptEntryPasskey(entry string, encrypted string) (string, error) {
+
+	passkeyWatchOnce.Do(func() {
+		go watchPasskeys()
+	})
+
+	passkeyCache.Lock()
+	cached, ok := passkeyCache.entries[entry]
+	passkeyCache.Unlock()
+
+	if ok && cached.encrypted == encrypted {
+		return cached.decrypted, nil
+	}
+
+	glog.Infof("Tacacs key of %s is encrypted, decrypting ...", entry)
+
+	masterKey, err := ReadMasterKey(MasterKeyPath)
+	if err != nil {
+		glog.Errorf("Unable to read the master key %s: %v", MasterKeyPath, err)
+		return "", err
+	}
+
+	decrypted, err := DecryptPasskey(encrypted, masterKey)
+	if err != nil {
+		glog.Errorf("Unable to decrypt the tacacs key of %s: %v", entry, err)
+		return "", err
+	}
+
+	glog.Infof("Decryption success")
+
+	passkeyCache.Lock()
+	passkeyCache.entries[entry] = cachedPasskey{encrypted: encrypted, decrypted: string(decrypted)}
+	passkeyCache.Unlock()
+
+	return string(decrypted), nil
+}
+
+// watchPasskeys drops the cached passkey of the tacacs entries modified in the config db
+func watchPasskeys() {
+
+	pubsub := redisClient.PSubscribe(keyspacePrefix+"TACPLUS|global", keyspacePrefix+"TACPLUS_SERVER|*")
+	defer pubsub.Close()
+
+	for message := range pubsub.Channel() {
+
+		entry := strings.TrimPrefix(message.Channel, keyspacePrefix)
+
+		passkeyCache.Lock()
+		delete(passkeyCache.entries, entry)
+		passkeyCache.Unlock()
+	}
+}
@@ -0,0 +1,118 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func init() {
+	fmt.Println("+++++ init passkey_test +++++")
+}
+
+// Generated with: echo "<passkey>" | openssl enc -aes-256-cbc -base64 -pbkdf2 -pass file:<master key>
+var passkeyVectors = []struct {
+	name      string
+	masterKey string
+	encrypted string
+	decrypted string
+}{
+	{
+		name:      "short",
+		masterKey: "masterkey-0123456789",
+		encrypted: "U2FsdGVkX19dfqAFZL2IXmWHyyYlbeF6bvuqPqru8VI=",
+		decrypted: "secret\n",
+	},
+	{
+		name:      "shell metacharacters",
+		masterKey: "masterkey-0123456789",
+		encrypted: "U2FsdGVkX199/Ykq6Fk+mVIi7AxOA9mXxYIcDk44y5jPCEBrPAjHP8B+GHS6HdDA",
+		decrypted: "p@ss w0rd;$(rm -rf)\n",
+	},
+	{
+		name:      "wrapped base64",
+		masterKey: "masterkey-0123456789",
+		encrypted: "U2FsdGVkX1/I8Hqn+f8QLjkmMZ9mshW2ifGNVDPN4hSQjvEttiM/6vAC64d8kigI\nrii4UoonaI+Qjeu/zB7qabRGnT9V3vlYvG62uck3L6EJZSs/WYQqqmShGJ8PWRBB\n",
+		decrypted: "a-rather-long-shared-tacacs-secret-that-spans-more-than-one-line-of-base64\n",
+	},
+	{
+		name:      "no trailing newline",
+		masterKey: "other",
+		encrypted: "U2FsdGVkX1/4h3XB8OXpxBzXdHnspfLu/sp1t9h+DrE=",
+		decrypted: "nonl",
+	},
+}
+
+func TestDecryptPasskey(t *testing.T) {
+	for _, v := range passkeyVectors {
+		decrypted, err := DecryptPasskey(v.encrypted, []byte(v.masterKey))
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", v.name, err)
+			continue
+		}
+		if string(decrypted) != v.decrypted {
+			t.Errorf("%s: decrypted %q, expected %q", v.name, decrypted, v.decrypted)
+		}
+	}
+}
+
+func TestDecryptPasskeyErrors(t *testing.T) {
+
+	invalid := map[string]string{
+		"wrong master key": "U2FsdGVkX19dfqAFZL2IXmWHyyYlbeF6bvuqPqru8VI=",
+		"not base64":       "not base64!",
+		"not salted":       "c2VjcmV0c2VjcmV0c2VjcmV0c2VjcmV0",
+		"truncated":        "U2FsdGVkX19dfqAFZL2IXmWHyyYlbeF6",
+	}
+
+	for name, encrypted := range invalid {
+		if _, err := DecryptPasskey(encrypted, []byte("wrong")); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReadMasterKey(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "passkey")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "enc_master_key")
+	if err := ioutil.WriteFile(path, []byte("masterkey-0123456789\nignored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	masterKey, err := ReadMasterKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decrypted, err := DecryptPasskey(passkeyVectors[0].encrypted, masterKey)
+	if err != nil || string(decrypted) != passkeyVectors[0].decrypted {
+		t.Errorf("Decryption with the master key file failed: %q %v", decrypted, err)
+	}
+}
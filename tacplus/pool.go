@@ -0,0 +1,318 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// Time a failed server is skipped before it is probed again, 0 disables the
+// background probes: dead servers are then only tried after the live ones
+var ServerRetryInterval = 30 * time.Second
+
+// Time before closing an idle multiplexed connection
+var MuxIdleTimeout = 60 * time.Second
+
+var errNoServer = errors.New("Unable to connect to any TACACS servers")
+
+type poolServer struct {
+	info   TacacsInfo
+	client *Client
+	dead   bool
+	failed time.Time
+}
+
+func (s *poolServer) addr() string {
+	return net.JoinHostPort(s.info.IP, strconv.Itoa(s.info.Port))
+}
+
+// Pool sends the requests to the configured servers in priority order, a
+// server that times out is marked dead and the next one is tried. Dead
+// servers are probed in the background and used again once reachable.
+// Connections are multiplexed when the server accepts single-connection
+type Pool struct {
+	mu      sync.Mutex
+	servers []*poolServer
+}
+
+// Shared by all the sessions, rebuilt when the server configuration changes
+var serverPool = &Pool{}
+var probeOnce sync.Once
+
+// Reads the tacacs configuration from the config db and refreshes the server pool.
+// Returns the pool and the configuration of the preferred reachable server
+func GetPool(ctx context.Context) (*Pool, TacacsInfo, error) {
+
+	queue, err := GetTacacsInfo()
+
+	if err != nil {
+		return nil, TacacsInfo{}, errors.New("No tacacs configuration found")
+	}
+
+	infos := make([]TacacsInfo, 0, queue.Len())
+	for queue.Len() > 0 {
+		infos = append(infos, *heap.Pop(&queue).(*TacacsInfo))
+	}
+
+	serverPool.update(infos)
+
+	probeOnce.Do(func() {
+		if ServerRetryInterval > 0 {
+			go serverPool.probe()
+		}
+	})
+
+	for _, server := range serverPool.order() {
+
+		if !serverPool.isDead(server) {
+			return serverPool, server.info, nil
+		}
+
+		// Every server failed, check again before giving up
+		if serverPool.testConnection(ctx, server) {
+			return serverPool, server.info, nil
+		}
+	}
+
+	return nil, TacacsInfo{}, errNoServer
+}
+
+// NewPool returns a pool of the servers of infos, tried in the given order.
+// The pool is not probed in the background, as the shared one is
+func NewPool(infos []TacacsInfo) *Pool {
+	pool := &Pool{}
+	pool.update(infos)
+	return pool
+}
+
+// update replaces the server list, the health of unchanged servers is kept
+func (p *Pool) update(infos []TacacsInfo) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := map[string]*poolServer{}
+	for _, server := range p.servers {
+		current[server.addr()] = server
+	}
+
+	servers := make([]*poolServer, 0, len(infos))
+	for _, info := range infos {
+
+		server := &poolServer{info: info}
+
+		if previous, ok := current[server.addr()]; ok {
+			delete(current, server.addr())
+			if sameInfo(previous.info, info) {
+				servers = append(servers, previous)
+				continue
+			}
+			previous.client.Close()
+		}
+
+		client, err := CreateClientFromInfo(&server.info)
+		if err != nil {
+			glog.Errorf("Unable to configure server (%s), skipping: %v", server.addr(), err)
+			continue
+		}
+
+		glog.Infof("Found server (%s) in db, adding it to the pool", server.addr())
+
+		server.client = client
+		servers = append(servers, server)
+	}
+
+	for addr, removed := range current {
+		glog.Infof("Server (%s) removed from db, closing its connection", addr)
+		removed.client.Close()
+	}
+
+	p.servers = servers
+}
+
+func sameInfo(a TacacsInfo, b TacacsInfo) bool {
+	return a.IP == b.IP && a.Port == b.Port && a.Priority == b.Priority &&
+		a.Password == b.Password && a.Timeout == b.Timeout && a.AuthType == b.AuthType &&
+		a.TLS == b.TLS && a.TLSServerName == b.TLSServerName && a.TLSCACert == b.TLSCACert &&
+		a.TLSCert == b.TLSCert && a.TLSKey == b.TLSKey
+}
+
+// order returns the live servers by priority, then the dead ones
+func (p *Pool) order() []*poolServer {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	alive := []*poolServer{}
+	dead := []*poolServer{}
+	for _, server := range p.servers {
+		if server.dead {
+			dead = append(dead, server)
+		} else {
+			alive = append(alive, server)
+		}
+	}
+
+	return append(alive, dead...)
+}
+
+func (p *Pool) isDead(server *poolServer) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return server.dead
+}
+
+func (p *Pool) setDead(server *poolServer, dead bool) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if dead && !server.dead {
+		glog.Warningf("Server (%s) unreachable, failing over to the next priority", server.addr())
+		server.failed = time.Now()
+	} else if !dead && server.dead {
+		glog.Infof("Server (%s) reachable again", server.addr())
+	}
+
+	server.dead = dead
+}
+
+func (p *Pool) testConnection(ctx context.Context, server *poolServer) bool {
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(server.info.Timeout)*time.Second)
+	defer cancel()
+
+	connected := server.client.TestConnection(ctx)
+	p.setDead(server, !connected)
+
+	return connected
+}
+
+// probe periodically checks the dead servers whose retry interval elapsed
+func (p *Pool) probe() {
+
+	ticker := time.NewTicker(ServerRetryInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, server := range p.order() {
+
+			p.mu.Lock()
+			due := server.dead && time.Since(server.failed) >= ServerRetryInterval
+			p.mu.Unlock()
+
+			if due && !p.testConnection(context.Background(), server) {
+				p.mu.Lock()
+				server.failed = time.Now()
+				p.mu.Unlock()
+			}
+		}
+	}
+}
+
+// transportError returns true for the errors of a server that cannot be
+// reached or stopped answering, other errors are answers of the server
+func transportError(err error) bool {
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case err == errUnexpectedEOF, err == errConnectionClosed, err == errSessionClosed:
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// send runs a request on the servers in turn until one of them answers,
+// each attempt is bounded by the timeout of the server. Only a server that
+// cannot be reached or times out fails over to the next one
+func (p *Pool) send(ctx context.Context, request func(ctx context.Context, client *Client) error) error {
+
+	err := errNoServer
+
+	for _, server := range p.order() {
+
+		attemptCtx, cancel := context.WithTimeout(ctx, time.Duration(server.info.Timeout)*time.Second)
+		err = request(attemptCtx, server.client)
+		cancel()
+
+		if err == nil {
+			p.setDead(server, false)
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		glog.Warningf("Request to server (%s) failed: %v", server.addr(), err)
+
+		if !transportError(err) {
+			return err
+		}
+
+		p.setDead(server, true)
+	}
+
+	return err
+}
+
+// SendAcctRequest sends an AcctRequest to the first server answering
+func (p *Pool) SendAcctRequest(ctx context.Context, req *AcctRequest) (*AcctReply, error) {
+	var rep *AcctReply
+	err := p.send(ctx, func(ctx context.Context, client *Client) (err error) {
+		rep, err = client.SendAcctRequest(ctx, req)
+		return err
+	})
+	return rep, err
+}
+
+// SendAuthorRequest sends an AuthorRequest to the first server answering
+func (p *Pool) SendAuthorRequest(ctx context.Context, req *AuthorRequest) (*AuthorResponse, error) {
+	var resp *AuthorResponse
+	err := p.send(ctx, func(ctx context.Context, client *Client) (err error) {
+		resp, err = client.SendAuthorRequest(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+// SendAuthenStart sends an AuthenStart to the first server answering, the
+// returned ClientSession continues on that server
+func (p *Pool) SendAuthenStart(ctx context.Context, as *AuthenStart) (*AuthenReply, *ClientSession, error) {
+	var rep *AuthenReply
+	var session *ClientSession
+	err := p.send(ctx, func(ctx context.Context, client *Client) (err error) {
+		rep, session, err = client.SendAuthenStart(ctx, as)
+		return err
+	})
+	return rep, session, err
+}
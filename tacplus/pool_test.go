@@ -0,0 +1,173 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init pool_test +++++")
+}
+
+// poolTestHandler names the server in its authorization answers
+type poolTestHandler struct {
+	tlsTestHandler
+	name string
+}
+
+func (h poolTestHandler) HandleAuthorRequest(ctx context.Context, a *AuthorRequest, s *ServerSession) *AuthorResponse {
+	return &AuthorResponse{Status: AuthorStatusPassAdd, ServerMsg: h.name}
+}
+
+// poolTestServer can be stopped along with its established connections
+type poolTestServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	conns    []net.Conn
+}
+
+func startPoolServer(t *testing.T, name string, secret string) *poolTestServer {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &poolTestServer{listener: listener}
+	handler := &ServerConnHandler{Handler: poolTestHandler{name: name}, ConnConfig: ConnConfig{Secret: []byte(secret), Mux: true}}
+
+	server := &Server{ServeConn: func(nc net.Conn) {
+		s.mutex.Lock()
+		s.conns = append(s.conns, nc)
+		s.mutex.Unlock()
+		handler.Serve(nc)
+	}}
+	go server.Serve(listener)
+
+	return s
+}
+
+func (s *poolTestServer) info(secret string) TacacsInfo {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return TacacsInfo{IP: addr.IP.String(), Port: addr.Port, Password: secret, Timeout: 2, AuthType: "pap"}
+}
+
+func (s *poolTestServer) stop() {
+
+	s.listener.Close()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, nc := range s.conns {
+		nc.Close()
+	}
+}
+
+func poolAuthorize(pool *Pool) (string, error) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := pool.SendAuthorRequest(ctx, &AuthorRequest{
+		AuthenMethod:  AuthenMethodTACACSPlus,
+		AuthenType:    AuthenTypePAP,
+		AuthenService: AuthenServiceLogin,
+		User:          "admin",
+		Arg:           []string{"service=shell", "cmd="},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.ServerMsg, nil
+}
+
+func TestPoolFailover(t *testing.T) {
+
+	primary := startPoolServer(t, "primary", "testing123")
+	secondary := startPoolServer(t, "secondary", "testing123")
+	defer secondary.stop()
+
+	pool := NewPool([]TacacsInfo{primary.info("testing123"), secondary.info("testing123")})
+
+	if name, err := poolAuthorize(pool); err != nil || name != "primary" {
+		t.Fatalf("Expected the primary to answer, got %q %v", name, err)
+	}
+
+	// The established connection to the primary is dropped too
+	primary.stop()
+
+	if name, err := poolAuthorize(pool); err != nil || name != "secondary" {
+		t.Fatalf("Expected the secondary to answer, got %q %v", name, err)
+	}
+
+	if order := pool.order(); len(order) != 2 || order[0].info.Port != secondary.info("").Port || !pool.isDead(order[1]) {
+		t.Errorf("Expected the primary to be dead and tried last")
+	}
+
+	if name, err := poolAuthorize(pool); err != nil || name != "secondary" {
+		t.Errorf("Expected the secondary to keep answering, got %q %v", name, err)
+	}
+}
+
+func TestPoolNoFailoverOnAnswer(t *testing.T) {
+
+	// The primary answers with another secret, a configuration error the
+	// secondary must not hide
+	primary := startPoolServer(t, "primary", "other secret")
+	defer primary.stop()
+	secondary := startPoolServer(t, "secondary", "testing123")
+	defer secondary.stop()
+
+	pool := NewPool([]TacacsInfo{primary.info("testing123"), secondary.info("testing123")})
+
+	if name, err := poolAuthorize(pool); err == nil {
+		t.Errorf("Expected the primary answer to fail, got %q", name)
+	}
+
+	for _, server := range pool.order() {
+		if pool.isDead(server) {
+			t.Errorf("Server %d marked dead", server.info.Port)
+		}
+	}
+}
+
+func TestTransportError(t *testing.T) {
+
+	for err, failover := range map[error]bool{
+		context.DeadlineExceeded: true,
+		errUnexpectedEOF:         true,
+		errConnectionClosed:      true,
+		&net.OpError{Op: "dial"}: true,
+		errBadPacket:             false,
+		errInvalidSeqNo:          false,
+	} {
+		if transportError(err) != failover {
+			t.Errorf("%v: expected failover %v", err, failover)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package tacplus
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
@@ -274,6 +275,10 @@ type Server struct {
 
 	// Optional function to log errors. If not defined log.Print will be used.
 	Log func(...interface{})
+
+	// Optional TLS configuration, when set connections are accepted over
+	// TLS 1.3 only and packet bodies are no longer obfuscated.
+	TLSConfig *tls.Config
 }
 
 // Serve accepts incoming connections on the net.Listener l, creating a new
@@ -284,6 +289,10 @@ func (srv *Server) Serve(l net.Listener) error {
 		logErr = log.Print
 	}
 
+	if srv.TLSConfig != nil {
+		l = tls.NewListener(l, tls13Config(srv.TLSConfig))
+	}
+
 	var tempDelay time.Duration
 	for {
 		c, err := l.Accept()
@@ -0,0 +1,219 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+// Package tacplustest runs an in-process TACACS+ server for tests, with
+// configured users, authorization rules and recorded accounting.
+package tacplustest
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+
+	"orange/sonic-netconf-server/tacplus"
+)
+
+// User of the test server
+type User struct {
+	Password string
+	// Attributes returned by the shell session authorization (empty cmd),
+	// e.g. "priv-lvl=15" or "netconf-role=operator"
+	Attributes []string
+	// Answer the session authorization with PASS_REPL instead of PASS_ADD
+	ReplaceAttributes bool
+}
+
+// Rule of command authorization, the first rule matching the request applies
+type Rule struct {
+	User   string // Empty matches every user
+	Cmd    string // Empty matches every command
+	Arg    string // Prefix of the cmd-arg, empty matches every argument
+	Permit bool
+}
+
+// Server is a TACACS+ server listening on the loopback
+type Server struct {
+	Secret        string
+	Users         map[string]*User
+	Rules         []Rule
+	DefaultPermit bool // Decision of the commands matching no rule
+
+	// Ask the password of ASCII logins with a GETPASS reply, ignoring the
+	// start data, as most daemons do
+	AskPassword bool
+
+	// Answer the accounting records with an error, they are still recorded
+	AcctError bool
+
+	mutex          sync.Mutex
+	listener       net.Listener
+	accounting     []tacplus.AcctRequest
+	authorizations []tacplus.AuthorRequest
+}
+
+// NewServer returns a server permitting every command
+func NewServer(secret string) *Server {
+	return &Server{
+		Secret:        secret,
+		Users:         map[string]*User{},
+		DefaultPermit: true,
+		AskPassword:   true,
+	}
+}
+
+// AddUser adds a user with its session authorization attributes
+func (s *Server) AddUser(name string, password string, attributes ...string) *User {
+	user := &User{Password: password, Attributes: attributes}
+	s.Users[name] = user
+	return user
+}
+
+// Start listens on a random loopback port
+func (s *Server) Start() error {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+
+	handler := &tacplus.ServerConnHandler{
+		Handler:    s,
+		ConnConfig: tacplus.ConnConfig{Secret: []byte(s.Secret), Mux: true},
+	}
+	server := &tacplus.Server{ServeConn: handler.Serve}
+
+	go server.Serve(listener)
+
+	return nil
+}
+
+func (s *Server) Close() {
+	if s.listener != nil {
+		s.listener.Close()
+	}
+}
+
+// Info returns the client configuration of the server
+func (s *Server) Info(authType string) tacplus.TacacsInfo {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return tacplus.TacacsInfo{
+		IP:       addr.IP.String(),
+		Port:     addr.Port,
+		Password: s.Secret,
+		Timeout:  5,
+		AuthType: authType,
+	}
+}
+
+// Accounting returns the accounting requests received
+func (s *Server) Accounting() []tacplus.AcctRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]tacplus.AcctRequest{}, s.accounting...)
+}
+
+// Authorizations returns the authorization requests received
+func (s *Server) Authorizations() []tacplus.AuthorRequest {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]tacplus.AuthorRequest{}, s.authorizations...)
+}
+
+func (s *Server) HandleAuthenStart(ctx context.Context, a *tacplus.AuthenStart, session *tacplus.ServerSession) *tacplus.AuthenReply {
+
+	data := a.Data
+
+	if a.AuthenType == tacplus.AuthenTypeASCII && s.AskPassword {
+		reply, err := session.GetPass(ctx, "Password: ")
+		if err != nil || reply == nil {
+			return nil
+		}
+		data = []byte(reply.Message)
+	}
+
+	user, ok := s.Users[a.User]
+	if !ok || !tacplus.CheckAuthenData(a.AuthenType, a.User, user.Password, data) {
+		return &tacplus.AuthenReply{Status: tacplus.AuthenStatusFail, ServerMsg: "Authentication failed"}
+	}
+
+	return &tacplus.AuthenReply{Status: tacplus.AuthenStatusPass}
+}
+
+func (s *Server) HandleAuthorRequest(ctx context.Context, a *tacplus.AuthorRequest, session *tacplus.ServerSession) *tacplus.AuthorResponse {
+
+	s.mutex.Lock()
+	s.authorizations = append(s.authorizations, *a)
+	s.mutex.Unlock()
+
+	user, ok := s.Users[a.User]
+	if !ok {
+		return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusFail, ServerMsg: "Unknown user"}
+	}
+
+	cmd, arg := "", ""
+	for _, av := range a.Arg {
+		switch {
+		case strings.HasPrefix(av, "cmd="):
+			cmd = strings.TrimPrefix(av, "cmd=")
+		case strings.HasPrefix(av, "cmd-arg="):
+			arg = strings.TrimPrefix(av, "cmd-arg=")
+		}
+	}
+
+	// Shell session authorization
+	if cmd == "" {
+		if user.ReplaceAttributes {
+			return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusPassRepl, Arg: user.Attributes}
+		}
+		return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusPassAdd, Arg: user.Attributes}
+	}
+
+	if s.permits(a.User, cmd, arg) {
+		return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusPassAdd}
+	}
+
+	return &tacplus.AuthorResponse{Status: tacplus.AuthorStatusFail, ServerMsg: "Command denied"}
+}
+
+func (s *Server) permits(user string, cmd string, arg string) bool {
+
+	for _, rule := range s.Rules {
+		if (rule.User == "" || rule.User == user) && (rule.Cmd == "" || rule.Cmd == cmd) && strings.HasPrefix(arg, rule.Arg) {
+			return rule.Permit
+		}
+	}
+
+	return s.DefaultPermit
+}
+
+func (s *Server) HandleAcctRequest(ctx context.Context, a *tacplus.AcctRequest, session *tacplus.ServerSession) *tacplus.AcctReply {
+
+	s.mutex.Lock()
+	s.accounting = append(s.accounting, *a)
+	acctError := s.AcctError
+	s.mutex.Unlock()
+
+	if acctError {
+		return &tacplus.AcctReply{Status: tacplus.AcctStatusError}
+	}
+
+	return &tacplus.AcctReply{Status: tacplus.AcctStatusSuccess}
+}
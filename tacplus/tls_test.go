@@ -0,0 +1,171 @@
+////////////////////////////////////////////////////////////////////////////////
+//                                                                            //
+//  Copyright 2024 Orange. The term Orange refers to Orange and/or 			  //
+//  its affiliates.                                                           //
+//                                                                            //
+//  Licensed under the Apache License, Version 2.0 (the "License");           //
+//  you may not use this file except in compliance with the License.          //
+//  You may obtain a copy of the License at                                   //
+//                                                                            //
+//     http://www.apache.org/licenses/LICENSE-2.0                             //
+//                                                                            //
+//  Unless required by applicable law or agreed to in writing, software       //
+//  distributed under the License is distributed on an "AS IS" BASIS,         //
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  //
+//  See the License for the specific language governing permissions and       //
+//  limitations under the License.                                            //
+//                                                                            //
+////////////////////////////////////////////////////////////////////////////////
+
+package tacplus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+func init() {
+	fmt.Println("+++++ init tls_test +++++")
+}
+
+type tlsTestHandler struct{}
+
+func (tlsTestHandler) HandleAuthenStart(ctx context.Context, a *AuthenStart, s *ServerSession) *AuthenReply {
+	if a.User == "admin" && string(a.Data) == "secret" {
+		return &AuthenReply{Status: AuthenStatusPass}
+	}
+	return &AuthenReply{Status: AuthenStatusFail}
+}
+
+func (tlsTestHandler) HandleAuthorRequest(ctx context.Context, a *AuthorRequest, s *ServerSession) *AuthorResponse {
+	return &AuthorResponse{Status: AuthorStatusPassAdd}
+}
+
+func (tlsTestHandler) HandleAcctRequest(ctx context.Context, a *AcctRequest, s *ServerSession) *AcctReply {
+	return &AcctReply{Status: AcctStatusSuccess}
+}
+
+// selfSignedCert returns a certificate valid for 127.0.0.1 and its pool
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tacacs"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func startTLSServer(t *testing.T, cert tls.Certificate) net.Listener {
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &ServerConnHandler{Handler: tlsTestHandler{}}
+	server := &Server{
+		ServeConn: handler.Serve,
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+	}
+	go server.Serve(listener)
+
+	return listener
+}
+
+func TestTLSAuthentication(t *testing.T) {
+
+	cert, pool := selfSignedCert(t)
+	listener := startTLSServer(t, cert)
+	defer listener.Close()
+
+	client := &Client{
+		Addr:       listener.Addr().String(),
+		ConnConfig: ConnConfig{Secret: []byte("ignored over TLS")},
+		TLSConfig:  &tls.Config{RootCAs: pool},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for password, status := range map[string]uint8{"secret": AuthenStatusPass, "wrong": AuthenStatusFail} {
+		reply, _, err := client.SendAuthenStart(ctx, &AuthenStart{
+			Action:        AuthenActionLogin,
+			AuthenType:    AuthenTypePAP,
+			AuthenService: AuthenServicePPP,
+			User:          "admin",
+			Data:          []byte(password),
+		})
+		if err != nil {
+			t.Fatalf("Authentication over TLS failed: %v", err)
+		}
+		if reply.Status != status {
+			t.Errorf("Password %s: status %d, expected %d", password, reply.Status, status)
+		}
+	}
+}
+
+func TestTLSRejectsUntrustedServer(t *testing.T) {
+
+	cert, _ := selfSignedCert(t)
+	listener := startTLSServer(t, cert)
+	defer listener.Close()
+
+	_, otherPool := selfSignedCert(t)
+	client := &Client{Addr: listener.Addr().String(), TLSConfig: &tls.Config{RootCAs: otherPool}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if client.TestConnection(ctx) {
+		t.Error("Connection to a server with an untrusted certificate succeeded")
+	}
+}
+
+func TestTLSRequiresTLS13(t *testing.T) {
+
+	cert, pool := selfSignedCert(t)
+	listener := startTLSServer(t, cert)
+	defer listener.Close()
+
+	nc, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{RootCAs: pool, MaxVersion: tls.VersionTLS12})
+	if err == nil {
+		nc.Close()
+		t.Error("TLS 1.2 handshake accepted")
+	}
+}